@@ -636,7 +636,7 @@ func (b *localBackend) apply(
 		StartTime:   start,
 		Message:     op.M.Message,
 		Environment: op.M.Environment,
-		Config:      update.GetTarget().Config,
+		Config:      update.GetTarget().Config.Map(),
 		Result:      backendUpdateResult,
 		EndTime:     end,
 		// IDEA: it would be nice to populate the *Deployment, so that addToHistory below doesn't need to
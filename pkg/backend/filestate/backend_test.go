@@ -71,7 +71,7 @@ func TestMassageBlobPath(t *testing.T) {
 func TestGetLogsForTargetWithNoSnapshot(t *testing.T) {
 	target := &deploy.Target{
 		Name:      "test",
-		Config:    config.Map{},
+		Config:    config.Map{}.Freeze(),
 		Decrypter: config.NopDecrypter,
 		Snapshot:  nil,
 	}
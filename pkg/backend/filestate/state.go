@@ -118,7 +118,7 @@ func (b *localBackend) getTarget(stackName tokens.QName, cfg config.Map, dec con
 	}
 	return &deploy.Target{
 		Name:      stackName,
-		Config:    cfg,
+		Config:    cfg.Freeze(),
 		Decrypter: dec,
 		Snapshot:  snapshot,
 	}, nil
@@ -372,6 +372,19 @@ func (pc *Client) DecryptValue(ctx context.Context, stack StackIdentifier, ciphe
 	return resp.Plaintext, nil
 }
 
+// BatchDecryptValue decrypts a set of ciphertext values in the context of the indicated stack. The result maps the
+// base64-encoded form of each ciphertext to its plaintext.
+func (pc *Client) BatchDecryptValue(ctx context.Context, stack StackIdentifier,
+	ciphertexts [][]byte) (map[string][]byte, error) {
+
+	req := apitype.BatchDecryptRequest{Ciphertexts: ciphertexts}
+	var resp apitype.BatchDecryptResponse
+	if err := pc.restCall(ctx, "POST", getStackPath(stack, "batch-decrypt"), nil, &req, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Plaintexts, nil
+}
+
 // GetStackUpdates returns all updates to the indicated stack.
 func (pc *Client) GetStackUpdates(
 	ctx context.Context,
@@ -297,7 +297,7 @@ func (b *cloudBackend) getTarget(ctx context.Context, stackRef backend.StackRefe
 
 	return &deploy.Target{
 		Name:      stackRef.Name(),
-		Config:    cfg,
+		Config:    cfg.Freeze(),
 		Decrypter: dec,
 		Snapshot:  snapshot,
 	}, nil
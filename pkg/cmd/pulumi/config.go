@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
@@ -31,9 +32,11 @@ import (
 	"github.com/pulumi/pulumi/pkg/v2/backend"
 	"github.com/pulumi/pulumi/pkg/v2/backend/display"
 	"github.com/pulumi/pulumi/pkg/v2/secrets"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/diag"
 	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
 	"github.com/pulumi/pulumi/sdk/v2/go/common/tokens"
 	"github.com/pulumi/pulumi/sdk/v2/go/common/util/cmdutil"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
 	"github.com/pulumi/pulumi/sdk/v2/go/common/workspace"
 )
 
@@ -41,6 +44,7 @@ func newConfigCmd() *cobra.Command {
 	var stack string
 	var showSecrets bool
 	var jsonOut bool
+	var labelArgs []string
 
 	cmd := &cobra.Command{
 		Use:   "config",
@@ -59,7 +63,16 @@ func newConfigCmd() *cobra.Command {
 				return err
 			}
 
-			return listConfig(stack, showSecrets, jsonOut)
+			selector := make(config.Labels)
+			for _, arg := range labelArgs {
+				name, value, err := config.ParseLabel(arg)
+				if err != nil {
+					return err
+				}
+				selector[name] = value
+			}
+
+			return listConfig(stack, showSecrets, jsonOut, selector)
 		}),
 	}
 
@@ -69,6 +82,9 @@ func newConfigCmd() *cobra.Command {
 	cmd.Flags().BoolVarP(
 		&jsonOut, "json", "j", false,
 		"Emit output as JSON")
+	cmd.Flags().StringArrayVar(
+		&labelArgs, "label", []string{},
+		"Only list keys with the given label, of the form `name=value`. May be repeated")
 	cmd.PersistentFlags().StringVarP(
 		&stack, "stack", "s", "",
 		"The name of the stack to operate on. Defaults to the current stack")
@@ -82,7 +98,11 @@ func newConfigCmd() *cobra.Command {
 	cmd.AddCommand(newConfigSetCmd(&stack))
 	cmd.AddCommand(newConfigSetAllCmd(&stack))
 	cmd.AddCommand(newConfigRefreshCmd(&stack))
+	cmd.AddCommand(newConfigDiffCmd(&stack))
 	cmd.AddCommand(newConfigCopyCmd(&stack))
+	cmd.AddCommand(newConfigMigrateCmd(&stack))
+	cmd.AddCommand(newConfigLabelCmd(&stack))
+	cmd.AddCommand(newConfigSecretCacheCmd())
 
 	return cmd
 }
@@ -155,7 +175,13 @@ func copySingleConfigKey(configKey string, path bool, currentStack backend.Stack
 		return errors.Wrap(err, "invalid configuration key")
 	}
 
-	v, ok, err := currentProjectStack.Config.Get(key, path)
+	// Externally stored values are copied inline, as the destination stack has its own store.
+	currentConfig, err := resolveConfigBlobs(currentStack, currentProjectStack.Config)
+	if err != nil {
+		return err
+	}
+
+	v, ok, err := currentConfig.Get(key, path)
 	if err != nil {
 		return err
 	}
@@ -196,7 +222,11 @@ func copyEntireConfigMap(currentStack backend.Stack,
 	destinationProjectStack *workspace.ProjectStack) error {
 
 	var decrypter config.Decrypter
-	currentConfig := currentProjectStack.Config
+	// Externally stored values are copied inline, as the destination stack has its own store.
+	currentConfig, err := resolveConfigBlobs(currentStack, currentProjectStack.Config)
+	if err != nil {
+		return err
+	}
 	if currentConfig.HasSecureValue() {
 		dec, decerr := getStackDecrypter(currentStack)
 		if decerr != nil {
@@ -241,6 +271,7 @@ func copyEntireConfigMap(currentStack backend.Stack,
 func newConfigGetCmd(stack *string) *cobra.Command {
 	var jsonOut bool
 	var path bool
+	var explain bool
 
 	getCmd := &cobra.Command{
 		Use:   "get <key>",
@@ -250,7 +281,9 @@ func newConfigGetCmd(stack *string) *cobra.Command {
 			"  - `pulumi config get --path outer.inner` will get the value of the `inner` key, " +
 			"if the value of `outer` is a map `inner: value`.\n" +
 			"  - `pulumi config get --path names[0]` will get the value of the first item, " +
-			"if the value of `names` is a list.",
+			"if the value of `names` is a list.\n\n" +
+			"The `--explain` flag shows every configuration layer (such as project defaults and the stack's\n" +
+			"configuration file) that sets the key, and which of them provides the value that is used.",
 		Args: cmdutil.SpecificArgs([]string{"key"}),
 		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
 			opts := display.Options{
@@ -267,6 +300,13 @@ func newConfigGetCmd(stack *string) *cobra.Command {
 				return errors.Wrap(err, "invalid configuration key")
 			}
 
+			if explain {
+				if path || jsonOut {
+					return errors.New("--explain may not be combined with --path or --json")
+				}
+				return explainConfig(s, key)
+			}
+
 			return getConfig(s, key, path, jsonOut)
 		}),
 	}
@@ -276,6 +316,9 @@ func newConfigGetCmd(stack *string) *cobra.Command {
 	getCmd.PersistentFlags().BoolVar(
 		&path, "path", false,
 		"The key contains a path to a property in a map or list to get")
+	getCmd.PersistentFlags().BoolVar(
+		&explain, "explain", false,
+		"Show where the value comes from, including any values it overrides")
 
 	return getCmd
 }
@@ -317,6 +360,10 @@ func newConfigRmCmd(stack *string) *cobra.Command {
 			if err != nil {
 				return err
 			}
+			if !path {
+				delete(ps.KeyProviders, key.String())
+				ps.Metadata.Remove(key, "")
+			}
 
 			return saveProjectStack(s, ps)
 		}),
@@ -366,6 +413,10 @@ func newConfigRmAllCmd(stack *string) *cobra.Command {
 				if err != nil {
 					return err
 				}
+				if !path {
+					delete(ps.KeyProviders, key.String())
+					ps.Metadata.Remove(key, "")
+				}
 			}
 
 			return saveProjectStack(s, ps)
@@ -378,6 +429,196 @@ func newConfigRmAllCmd(stack *string) *cobra.Command {
 	return rmAllCmd
 }
 
+func newConfigMigrateCmd(stack *string) *cobra.Command {
+	var allStacks bool
+	migrateCmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Rename configuration keys according to the project's config schema",
+		Long: "Rename configuration keys according to the project's config schema.\n" +
+			"\n" +
+			"Values that are set under an alias of a key, or under a deprecated key that has a replacement, are moved\n" +
+			"to the key that the project's config schema declares. By default, the current stack's configuration file\n" +
+			"is rewritten; pass `--all` to rewrite the configuration files of every stack in the project.",
+		Args: cmdutil.NoArgs,
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+
+			proj, projPath, err := workspace.DetectProjectAndPath()
+			if err != nil {
+				return err
+			}
+			schema, err := proj.ParseConfigSchema()
+			if err != nil {
+				return err
+			}
+
+			var paths []string
+			if allStacks {
+				pattern := fmt.Sprintf("%s.*%s", workspace.ProjectFile, filepath.Ext(projPath))
+				if paths, err = filepath.Glob(filepath.Join(filepath.Dir(projPath), proj.Config, pattern)); err != nil {
+					return err
+				}
+			} else {
+				s, err := requireStack(*stack, false, opts, true /*setCurrent*/)
+				if err != nil {
+					return err
+				}
+				path, err := getProjectStackPath(s)
+				if err != nil {
+					return err
+				}
+				paths = []string{path}
+			}
+
+			for _, path := range paths {
+				if err := migrateConfigFile(schema, path); err != nil {
+					return errors.Wrapf(err, "migrating %s", path)
+				}
+			}
+			return nil
+		}),
+	}
+
+	migrateCmd.PersistentFlags().BoolVar(
+		&allStacks, "all", false,
+		"Migrate the configuration files of every stack in the project")
+
+	return migrateCmd
+}
+
+// migrateConfigFile rewrites the stack configuration file at path so that aliased and deprecated keys are renamed to
+// the keys that replace them.
+func migrateConfigFile(schema config.Schema, path string) error {
+	ps, err := workspace.LoadProjectStack(path)
+	if err != nil {
+		return err
+	}
+
+	renames := make(map[config.Key]config.Key)
+	deprecated, err := schema.MigrateDeprecated(ps.Config)
+	if err != nil {
+		return err
+	}
+	for _, k := range deprecated {
+		replacement, err := config.ParseKey(schema[k].ReplacedBy)
+		contract.AssertNoError(err)
+		renames[k] = replacement
+	}
+	aliases, err := schema.MigrateAliases(ps.Config)
+	if err != nil {
+		return err
+	}
+	for _, k := range aliases {
+		target, ok := schema.AliasOf(k)
+		contract.Assert(ok)
+		renames[k] = target
+	}
+	if len(renames) == 0 {
+		return nil
+	}
+
+	var keys config.KeyArray
+	for k := range renames {
+		keys = append(keys, k)
+	}
+	sort.Sort(keys)
+	for _, k := range keys {
+		// A key's own secrets provider follows it to its new name.
+		if kp, has := ps.KeyProviders[k.String()]; has {
+			ps.KeyProviders[renames[k].String()] = kp
+			delete(ps.KeyProviders, k.String())
+		}
+		ps.Metadata.Rename(k, renames[k])
+		cmdutil.Diag().Infof(diag.Message("", "%s: renamed %s to %s"), filepath.Base(path), k, renames[k])
+	}
+	return ps.Save(path)
+}
+
+func newConfigLabelCmd(stack *string) *cobra.Command {
+	labelCmd := &cobra.Command{
+		Use:   "label <key> [name=value | name-]...",
+		Short: "Show or change the labels attached to a configuration key",
+		Long: "Show or change the labels attached to a configuration key.\n" +
+			"\n" +
+			"Labels record metadata about a key, such as its owner, the ticket that introduced it or the date its\n" +
+			"value is next due to be rotated. They are stored in the stack's configuration file but are never seen by\n" +
+			"programs. With no labels, the key's current labels are printed. `name=value` attaches a label, replacing\n" +
+			"any existing value, and `name-` removes one. Use `pulumi config --label name=value` to list the keys\n" +
+			"with a given label.",
+		Args: cmdutil.MinimumNArgs(1),
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+
+			s, err := requireStack(*stack, true, opts, true /*setCurrent*/)
+			if err != nil {
+				return err
+			}
+
+			key, err := parseConfigKey(args[0])
+			if err != nil {
+				return errors.Wrap(err, "invalid configuration key")
+			}
+
+			ps, err := loadProjectStack(s)
+			if err != nil {
+				return err
+			}
+
+			if len(args) == 1 {
+				labels := ps.Metadata.Get(key)
+				names := make([]string, 0, len(labels))
+				for name := range labels {
+					names = append(names, name)
+				}
+				sort.Strings(names)
+				for _, name := range names {
+					fmt.Printf("%s=%s\n", name, labels[name])
+				}
+				return nil
+			}
+
+			if _, ok := ps.Config[key]; !ok {
+				return errors.Errorf(
+					"configuration key '%s' not found for stack '%s'", prettyKey(key), s.Ref())
+			}
+			if ps.Metadata == nil {
+				ps.Metadata = make(config.Metadata)
+			}
+			for _, arg := range args[1:] {
+				if strings.HasSuffix(arg, "-") && !strings.Contains(arg, "=") {
+					ps.Metadata.Remove(key, strings.TrimSuffix(arg, "-"))
+					continue
+				}
+				name, value, err := config.ParseLabel(arg)
+				if err != nil {
+					return err
+				}
+				if err = ps.Metadata.Set(key, name, value); err != nil {
+					return err
+				}
+			}
+
+			return saveProjectStack(s, ps)
+		}),
+	}
+
+	return labelCmd
+}
+
+// formatLabels returns labels as a comma-separated list of `name=value` pairs, sorted by name.
+func formatLabels(labels config.Labels) string {
+	pairs := make([]string, 0, len(labels))
+	for name, value := range labels {
+		pairs = append(pairs, fmt.Sprintf("%s=%s", name, value))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
 func newConfigRefreshCmd(stack *string) *cobra.Command {
 	var force bool
 	refreshCmd := &cobra.Command{
@@ -449,10 +690,126 @@ func newConfigRefreshCmd(stack *string) *cobra.Command {
 	return refreshCmd
 }
 
+func newConfigDiffCmd(stack *string) *cobra.Command {
+	var jsonOut bool
+	diffCmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Show how the local configuration differs from the most recent deployment of the stack",
+		Long: "Show how the local configuration differs from the most recent deployment of the stack.\n\n" +
+			"Secret values are compared by their encrypted values, so they are never decrypted. A secret that has " +
+			"been\nre-encrypted is shown as changed even if its value is the same.",
+		Args: cmdutil.NoArgs,
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+
+			s, err := requireStack(*stack, false, opts, false /*setCurrent*/)
+			if err != nil {
+				return err
+			}
+
+			deployed, err := backend.GetLatestConfiguration(commandContext(), s)
+			if err != nil {
+				return err
+			}
+
+			ps, err := loadProjectStack(s)
+			if err != nil {
+				return err
+			}
+			current, err := resolveConfigBlobs(s, ps.Config)
+			if err != nil {
+				return err
+			}
+
+			return printConfigDiff(deployed, current, jsonOut)
+		}),
+	}
+	diffCmd.PersistentFlags().BoolVarP(
+		&jsonOut, "json", "j", false,
+		"Emit output as JSON")
+
+	return diffCmd
+}
+
+// configDiffJSON is the shape of the --json output of the config diff command.
+type configDiffJSON struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	Changed []string `json:"changed"`
+}
+
+func printConfigDiff(before, after config.Map, jsonOut bool) error {
+	diff := before.Diff(after)
+
+	if jsonOut {
+		keyStrings := func(keys []config.Key) []string {
+			result := make([]string, len(keys))
+			for i, k := range keys {
+				result[i] = k.String()
+			}
+			return result
+		}
+		return printJSON(configDiffJSON{
+			Added:   keyStrings(diff.Added),
+			Removed: keyStrings(diff.Removed),
+			Changed: keyStrings(diff.Changed),
+		})
+	}
+
+	if diff.Empty() {
+		fmt.Println("The configuration matches the most recent deployment.")
+		return nil
+	}
+
+	decrypter := config.NewBlindingDecrypter()
+	valueOf := func(m config.Map, k config.Key) (string, error) {
+		v, ok := m[k]
+		if !ok {
+			return "", nil
+		}
+		return v.Value(decrypter)
+	}
+
+	rows := []cmdutil.TableRow{}
+	addRows := func(change string, keys []config.Key) error {
+		for _, k := range keys {
+			b, err := valueOf(before, k)
+			if err != nil {
+				return err
+			}
+			a, err := valueOf(after, k)
+			if err != nil {
+				return err
+			}
+			rows = append(rows, cmdutil.TableRow{Columns: []string{prettyKey(k), change, b, a}})
+		}
+		return nil
+	}
+	if err := addRows("added", diff.Added); err != nil {
+		return err
+	}
+	if err := addRows("removed", diff.Removed); err != nil {
+		return err
+	}
+	if err := addRows("changed", diff.Changed); err != nil {
+		return err
+	}
+
+	cmdutil.PrintTable(cmdutil.Table{
+		Headers: []string{"KEY", "CHANGE", "DEPLOYED", "CURRENT"},
+		Rows:    rows,
+	})
+	return nil
+}
+
 func newConfigSetCmd(stack *string) *cobra.Command {
 	var plaintext bool
 	var secret bool
 	var path bool
+	var secretsProvider string
+	var external bool
 
 	setCmd := &cobra.Command{
 		Use:   "set <key> [value]",
@@ -508,21 +865,56 @@ func newConfigSetCmd(stack *string) *cobra.Command {
 				}
 			}
 
+			if secretsProvider != "" && (!secret || path) {
+				return errors.New("--secrets-provider may only be used with --secret, and not with --path")
+			}
+			if external && path {
+				return errors.New("--external may not be used with --path")
+			}
+
+			// If the project declares a schema for this key, check the plaintext before it is encrypted.
+			if !path {
+				if err := validateConfigValue(key, value, secret); err != nil {
+					return err
+				}
+			}
+
+			if err := shareDataKeyWithFallbacks(s); err != nil {
+				return err
+			}
+			ps, err := loadProjectStack(s)
+			if err != nil {
+				return err
+			}
+
 			// Encrypt the config value if needed.
 			var v config.Value
 			if secret {
-				c, cerr := getStackEncrypter(s)
+				var c config.Encrypter
+				var cerr error
+				if _, has := ps.KeyProviders[key.String()]; has || secretsProvider != "" {
+					if secretsProvider == "" {
+						secretsProvider = ps.KeyProviders[key.String()].SecretsProvider
+					}
+					c, cerr = setKeySecretsProvider(ps, s.Ref().Name(), key, secretsProvider)
+				} else {
+					c, cerr = getStackEncrypter(s)
+				}
 				if cerr != nil {
 					return cerr
 				}
-				enc, eerr := c.EncryptValue(value)
-				if eerr != nil {
-					return eerr
+				if external {
+					if v, err = newExternalConfigValue(s, key, value, c); err != nil {
+						return err
+					}
+				} else {
+					enc, eerr := c.EncryptValue(value)
+					if eerr != nil {
+						return eerr
+					}
+					v = config.NewSecureValue(enc)
 				}
-				v = config.NewSecureValue(enc)
 			} else {
-				v = config.NewValue(value)
-
 				// If we saved a plaintext configuration value, and --plaintext was not passed, warn the user.
 				if !plaintext && looksLikeSecret(key, value) {
 					return errors.Errorf(
@@ -530,14 +922,26 @@ func newConfigSetCmd(stack *string) *cobra.Command {
 							"rerun with --secret to encrypt it, or --plaintext if you meant to store in plaintext",
 						value)
 				}
-			}
 
-			ps, err := loadProjectStack(s)
-			if err != nil {
-				return err
+				v = config.NewValue(value)
+				if external {
+					if v, err = newExternalConfigValue(s, key, value, nil); err != nil {
+						return err
+					}
+				}
+
+				// A plaintext value no longer needs the secrets provider that encrypted its previous value.
+				if !path {
+					delete(ps.KeyProviders, key.String())
+				}
 			}
 
-			err = ps.Config.Set(key, v, path)
+			// A value set by path can only be checked against the schema once it is part of the key's whole value.
+			if path {
+				err = setValidatedConfigPath(ps, key, v)
+			} else {
+				err = ps.Config.Set(key, v, path)
+			}
 			if err != nil {
 				return err
 			}
@@ -555,10 +959,28 @@ func newConfigSetCmd(stack *string) *cobra.Command {
 	setCmd.PersistentFlags().BoolVar(
 		&secret, "secret", false,
 		"Encrypt the value instead of storing it in plaintext")
+	setCmd.PersistentFlags().StringVar(
+		&secretsProvider, "secrets-provider", "",
+		"Encrypt the value with the given cloud secrets provider instead of the stack's secrets provider")
+	setCmd.PersistentFlags().BoolVar(
+		&external, "external", false,
+		"Store the value in a file alongside the stack's configuration file, which records only a reference to it")
 
 	return setCmd
 }
 
+// newExternalConfigValue stores value in the stack's blob store, returning a reference to it. If encrypter is non-nil,
+// the value is encrypted before it is stored.
+func newExternalConfigValue(stack backend.Stack, key config.Key, value string,
+	encrypter config.Encrypter) (config.Value, error) {
+
+	store, err := getStackBlobStore(stack)
+	if err != nil {
+		return config.Value{}, err
+	}
+	return config.NewBlobValue(store, config.BlobName(key), value, encrypter)
+}
+
 func newConfigSetAllCmd(stack *string) *cobra.Command {
 	var plaintextArgs []string
 	var secretArgs []string
@@ -590,43 +1012,20 @@ func newConfigSetAllCmd(stack *string) *cobra.Command {
 				return err
 			}
 
+			if err := shareDataKeyWithFallbacks(s); err != nil {
+				return err
+			}
 			ps, err := loadProjectStack(s)
 			if err != nil {
 				return err
 			}
 
-			for _, ptArg := range plaintextArgs {
-				key, value, err := parseKeyValuePair(ptArg)
-				if err != nil {
-					return err
-				}
-				v := config.NewValue(value)
-
-				err = ps.Config.Set(key, v, path)
-				if err != nil {
-					return err
-				}
-			}
-
-			for _, sArg := range secretArgs {
-				key, value, err := parseKeyValuePair(sArg)
-				if err != nil {
-					return err
-				}
-				c, cerr := getStackEncrypter(s)
-				if cerr != nil {
-					return cerr
-				}
-				enc, eerr := c.EncryptValue(value)
-				if eerr != nil {
-					return eerr
-				}
-				v := config.NewSecureValue(enc)
-
-				err = ps.Config.Set(key, v, path)
-				if err != nil {
-					return err
-				}
+			// Either every value is set or none are: if any value is rejected, roll the configuration back to its
+			// original state before saving anything.
+			snap := ps.Config.Snapshot()
+			if err := setAllConfig(s, ps, plaintextArgs, secretArgs, path); err != nil {
+				ps.Config.Restore(snap)
+				return err
 			}
 
 			return saveProjectStack(s, ps)
@@ -646,6 +1045,60 @@ func newConfigSetAllCmd(stack *string) *cobra.Command {
 	return setCmd
 }
 
+// setAllConfig sets each of the given plaintext and secret `key=value` pairs in ps, validating each value against the
+// project's config schema.
+func setAllConfig(s backend.Stack, ps *workspace.ProjectStack, plaintextArgs, secretArgs []string, path bool) error {
+	for _, ptArg := range plaintextArgs {
+		key, value, err := parseKeyValuePair(ptArg)
+		if err != nil {
+			return err
+		}
+		if !path {
+			if err := validateConfigValue(key, value, false); err != nil {
+				return err
+			}
+		}
+		v := config.NewValue(value)
+
+		err = ps.Config.Set(key, v, path)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(secretArgs) == 0 {
+		return nil
+	}
+
+	// Create the encrypter once, so that its data key is only decrypted once however many values are set.
+	c, cerr := getStackEncrypter(s)
+	if cerr != nil {
+		return cerr
+	}
+	for _, sArg := range secretArgs {
+		key, value, err := parseKeyValuePair(sArg)
+		if err != nil {
+			return err
+		}
+		if !path {
+			if err := validateConfigValue(key, value, true); err != nil {
+				return err
+			}
+		}
+		enc, eerr := c.EncryptValue(value)
+		if eerr != nil {
+			return eerr
+		}
+		v := config.NewSecureValue(enc)
+
+		err = ps.Config.Set(key, v, path)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func parseKeyValuePair(pair string) (config.Key, string, error) {
 	// Split the arg on the first '=' to separate key and value.
 	splitArg := strings.SplitN(pair, "=", 2)
@@ -678,6 +1131,28 @@ func getProjectStackPath(stack backend.Stack) (string, error) {
 	return stackConfigFile, nil
 }
 
+// getStackBlobStore returns the store for config values that are kept outside of the stack's configuration file. The
+// values are kept in a directory alongside the file, e.g. `Pulumi.dev.blobs` for `Pulumi.dev.yaml`.
+func getStackBlobStore(stack backend.Stack) (config.BlobStore, error) {
+	stackPath, err := getProjectStackPath(stack)
+	if err != nil {
+		return nil, err
+	}
+	return config.NewFileBlobStore(strings.TrimSuffix(stackPath, filepath.Ext(stackPath)) + ".blobs"), nil
+}
+
+// resolveConfigBlobs replaces any references to externally stored values in cfg with the values themselves.
+func resolveConfigBlobs(stack backend.Stack, cfg config.Map) (config.Map, error) {
+	if !cfg.HasBlobRef() {
+		return cfg, nil
+	}
+	store, err := getStackBlobStore(stack)
+	if err != nil {
+		return nil, err
+	}
+	return cfg.ResolveBlobs(store)
+}
+
 func loadProjectStack(stack backend.Stack) (*workspace.ProjectStack, error) {
 	if stackConfigFile == "" {
 		return workspace.DetectProjectStack(stack.Ref().Name())
@@ -692,6 +1167,188 @@ func saveProjectStack(stack backend.Stack, ps *workspace.ProjectStack) error {
 	return ps.Save(stackConfigFile)
 }
 
+// validateConfigValue checks a value that is about to be set against the current project's config schema, if any.
+func validateConfigValue(key config.Key, value string, secret bool) error {
+	proj, err := workspace.DetectProject()
+	if err != nil {
+		return err
+	}
+	schema, err := proj.ParseConfigSchema()
+	if err != nil {
+		return err
+	}
+
+	// The value has not been encrypted yet, so a secret can be validated by treating its plaintext as the ciphertext
+	// of a secure value and "decrypting" it with the NopDecrypter.
+	v := config.NewValue(value)
+	if secret {
+		v = config.NewSecureValue(value)
+	}
+	diags := schema.ValidateValue(key, v, config.NopDecrypter)
+	for _, d := range diags {
+		if d.Severity == diag.Warning {
+			cmdutil.Diag().Warningf(diag.Message("", d.String()))
+		}
+	}
+	return config.NewValidationError(diags)
+}
+
+// setValidatedConfigPath sets the value at the path key within ps, so long as the resulting value of the key is valid
+// according to the current project's config schema.
+func setValidatedConfigPath(ps *workspace.ProjectStack, key config.Key, v config.Value) error {
+	proj, err := workspace.DetectProject()
+	if err != nil {
+		return err
+	}
+	schema, err := proj.ParseConfigSchema()
+	if err != nil {
+		return err
+	}
+	return schema.Set(ps.Config, key, v, true /*path*/, nil)
+}
+
+// validateStackConfig checks the configuration loaded from a stack's configuration file against the current
+// project's config schema, reporting any problems at their position in the file. Secure values are not decrypted, so
+// only their secret-ness is checked.
+func validateStackConfig(stack backend.Stack, cfg config.Map) error {
+	proj, err := workspace.DetectProject()
+	if err != nil {
+		return err
+	}
+	schema, err := proj.ParseConfigSchema()
+	if err != nil {
+		return err
+	}
+	diags := schema.Validate(cfg, nil)
+	if proj.ConfigStrict && len(schema) > 0 {
+		diags = append(diags, schema.ValidateKeys(cfg)...)
+	}
+	if len(diags) == 0 {
+		return nil
+	}
+
+	if stackPath, err := getProjectStackPath(stack); err == nil {
+		if content, err := ioutil.ReadFile(stackPath); err == nil {
+			diags = config.LocateDiagnostics(diags, filepath.Base(stackPath), content)
+		}
+	}
+	for _, d := range diags {
+		if d.Severity == diag.Warning {
+			cmdutil.Diag().Warningf(diag.Message("", d.String()))
+		}
+	}
+	return config.NewValidationError(diags)
+}
+
+// checkRequiredConfig reports every key that the current project's config schema requires but cfg does not set. It is
+// run before a program is, so that all of the missing keys are reported at once.
+func checkRequiredConfig(cfg config.Map) error {
+	proj, err := workspace.DetectProject()
+	if err != nil {
+		return err
+	}
+	schema, err := proj.ParseConfigSchema()
+	if err != nil {
+		return err
+	}
+	if diags := schema.Missing(cfg); len(diags) > 0 {
+		return config.NewValidationError(diags)
+	}
+	return nil
+}
+
+// normalizeConfigBools rewrites the unquoted boolean literals in cfg, if the current project uses the YAML 1.1 boolean
+// syntax, so that programs always see `true` or `false`.
+func normalizeConfigBools(cfg config.Map) (config.Map, error) {
+	proj, err := workspace.DetectProject()
+	if err != nil {
+		return nil, err
+	}
+	return cfg.NormalizeBools(proj.ConfigBooleans)
+}
+
+// inheritConfig places the stack configuration cfg over the current project's schema defaults and the configuration
+// documents that it inherits values from, returning the effective configuration.
+func inheritConfig(cfg config.Map) (config.Map, error) {
+	layers, err := stackConfigLayers(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return layers.Effective(), nil
+}
+
+// stackConfigLayers returns the layers that make up the effective configuration of a stack whose configuration file
+// holds cfg: the current project's schema defaults, then the configuration documents that it inherits values from,
+// and then cfg itself.
+func stackConfigLayers(cfg config.Map) (config.Layers, error) {
+	proj, projPath, err := workspace.DetectProjectAndPath()
+	if err != nil {
+		return nil, err
+	}
+	schema, err := proj.ParseConfigSchema()
+	if err != nil {
+		return nil, err
+	}
+	inherited, err := workspace.LoadInheritedConfig(proj, projPath)
+	if err != nil {
+		return nil, err
+	}
+	return config.WithDefaults(cfg, append([]config.Layer{schema.Defaults()}, inherited...)...), nil
+}
+
+// interpolateConfig replaces the `${<namespace>:<name>}` references between the values of cfg, returning the
+// interpolated configuration and a decrypter for it that interpolates secrets as it decrypts them. References are only
+// replaced if the current project opts in to them, and references to environment variables only if it opts in to
+// those as well; otherwise cfg and dec are returned unchanged.
+func interpolateConfig(cfg config.Map, dec config.Decrypter) (config.Map, config.Decrypter, error) {
+	proj, err := workspace.DetectProject()
+	if err != nil {
+		return nil, nil, err
+	}
+	if !proj.ConfigInterpolation && !proj.ConfigEnvInterpolation {
+		return cfg, dec, nil
+	}
+	var opts config.InterpolateOptions
+	if proj.ConfigEnvInterpolation {
+		opts.LookupEnv = os.LookupEnv
+	}
+	return cfg.Interpolated(dec, opts)
+}
+
+// forwardConfigAliases forwards the values of keys to their aliases, as declared by the current project's config
+// schema, so that reads of an alias see the value of the key that it names. A warning is printed for each value that
+// is still set under an alias.
+func forwardConfigAliases(cfg config.Map) (config.Map, error) {
+	proj, err := workspace.DetectProject()
+	if err != nil {
+		return nil, err
+	}
+	schema, err := proj.ParseConfigSchema()
+	if err != nil {
+		return nil, err
+	}
+	forwarded, diags := schema.ForwardAliases(cfg)
+	for _, d := range diags {
+		cmdutil.Diag().Warningf(diag.Message("", d.String()))
+	}
+	return forwarded, nil
+}
+
+// warnIfDeprecated prints a warning if the current project's config schema marks key as deprecated.
+func warnIfDeprecated(key config.Key) {
+	proj, err := workspace.DetectProject()
+	if err != nil {
+		return
+	}
+	schema, err := proj.ParseConfigSchema()
+	if err != nil {
+		return
+	}
+	if d, ok := schema.Deprecation(key); ok {
+		cmdutil.Diag().Warningf(diag.Message("", d.String()))
+	}
+}
+
 func parseConfigKey(key string) (config.Key, error) {
 	// As a convenience, we'll treat any key with no delimiter as if:
 	// <program-name>:<key> had been written instead
@@ -717,6 +1374,8 @@ func prettyKey(k config.Key) string {
 }
 
 func prettyKeyForProject(k config.Key, proj *workspace.Project) string {
+	// Only keys directly in the project's namespace may be shortened. A key in a hierarchical namespace beneath the
+	// project (e.g. `proj:db:password`) must be shown in full, as `db:password` would name a key in the `db` namespace.
 	if k.Namespace() == string(proj.Name) {
 		return k.Name()
 	}
@@ -732,15 +1391,23 @@ type configValueJSON struct {
 	Value       *string     `json:"value,omitempty"`
 	ObjectValue interface{} `json:"objectValue,omitempty"`
 	Secret      bool        `json:"secret"`
+	// Labels holds the metadata attached to the key, if any.
+	Labels map[string]string `json:"labels,omitempty"`
 }
 
-func listConfig(stack backend.Stack, showSecrets bool, jsonOut bool) error {
+func listConfig(stack backend.Stack, showSecrets bool, jsonOut bool, selector config.Labels) error {
 	ps, err := loadProjectStack(stack)
 	if err != nil {
 		return err
 	}
 
-	cfg := ps.Config
+	cfg, err := resolveConfigBlobs(stack, ps.Config)
+	if err != nil {
+		return err
+	}
+	if len(selector) > 0 {
+		cfg = cfg.Labeled(ps.Metadata, selector)
+	}
 
 	// By default, we will use a blinding decrypter to show "[secret]". If requested, display secrets in plaintext.
 	decrypter := config.NewBlindingDecrypter()
@@ -749,7 +1416,9 @@ func listConfig(stack backend.Stack, showSecrets bool, jsonOut bool) error {
 		if decerr != nil {
 			return decerr
 		}
-		decrypter = dec
+		if cfg, decrypter, err = resolveConfigRefs(stack, cfg, dec); err != nil {
+			return err
+		}
 	}
 
 	var keys config.KeyArray
@@ -765,6 +1434,7 @@ func listConfig(stack backend.Stack, showSecrets bool, jsonOut bool) error {
 		for _, key := range keys {
 			entry := configValueJSON{
 				Secret: cfg[key].Secure(),
+				Labels: ps.Metadata.Get(key),
 			}
 
 			decrypted, err := cfg[key].Value(decrypter)
@@ -804,11 +1474,20 @@ func listConfig(stack backend.Stack, showSecrets bool, jsonOut bool) error {
 				return errors.Wrap(err, "could not decrypt configuration value")
 			}
 
-			rows = append(rows, cmdutil.TableRow{Columns: []string{prettyKey(key), decrypted}})
+			columns := []string{prettyKey(key), decrypted}
+			if len(ps.Metadata) > 0 {
+				columns = append(columns, formatLabels(ps.Metadata.Get(key)))
+			}
+			rows = append(rows, cmdutil.TableRow{Columns: columns})
 		}
 
+		// Labels are only shown for stacks that use them.
+		headers := []string{"KEY", "VALUE"}
+		if len(ps.Metadata) > 0 {
+			headers = append(headers, "LABELS")
+		}
 		cmdutil.PrintTable(cmdutil.Table{
-			Headers: []string{"KEY", "VALUE"},
+			Headers: headers,
 			Rows:    rows,
 		})
 	}
@@ -822,7 +1501,29 @@ func getConfig(stack backend.Stack, key config.Key, path, jsonOut bool) error {
 		return err
 	}
 
-	cfg := ps.Config
+	cfg, err := resolveConfigBlobs(stack, ps.Config)
+	if err != nil {
+		return err
+	}
+	if cfg, err = inheritConfig(cfg); err != nil {
+		return err
+	}
+	cfg, dec, err := interpolateConfig(cfg, config.NewLazyDecrypter(func() (config.Decrypter, error) {
+		dec, err := getStackDecrypter(stack)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not create a decrypter")
+		}
+		return dec, nil
+	}))
+	if err != nil {
+		return err
+	}
+	if cfg, err = forwardConfigAliases(cfg); err != nil {
+		return err
+	}
+	if !path {
+		warnIfDeprecated(key)
+	}
 
 	v, ok, err := cfg.Get(key, path)
 	if err != nil {
@@ -830,12 +1531,17 @@ func getConfig(stack backend.Stack, key config.Key, path, jsonOut bool) error {
 	}
 	if ok {
 		var d config.Decrypter
-		if v.Secure() {
-			var err error
-			if d, err = getStackDecrypter(stack); err != nil {
-				return errors.Wrap(err, "could not create a decrypter")
+		_, isRef := v.ExternalRef()
+		switch {
+		case isRef:
+			resolved, dec, err := resolveConfigRefs(stack, config.Map{key: v}, config.NewPanicCrypter())
+			if err != nil {
+				return err
 			}
-		} else {
+			v, d = resolved[key], dec
+		case v.Secure():
+			d = dec
+		default:
 			d = config.NewPanicCrypter()
 		}
 		raw, err := v.Value(d)
@@ -869,10 +1575,70 @@ func getConfig(stack backend.Stack, key config.Key, path, jsonOut bool) error {
 		return nil
 	}
 
+	// Keys are case-sensitive, but a key that differs only in case is most likely a typo.
+	if !path {
+		if k, _, ok, err := cfg.Lookup(key, config.LookupOptions{IgnoreCase: true}); err == nil && ok {
+			return errors.Errorf("configuration key '%s' not found for stack '%s'; did you mean '%s'?",
+				prettyKey(key), stack.Ref(), prettyKey(k))
+		}
+	}
+
 	return errors.Errorf(
 		"configuration key '%s' not found for stack '%s'", prettyKey(key), stack.Ref())
 }
 
+// explainConfig prints every configuration layer that sets key, starting with the one whose value is used. Secret
+// values are not displayed.
+func explainConfig(stack backend.Stack, key config.Key) error {
+	ps, err := loadProjectStack(stack)
+	if err != nil {
+		return err
+	}
+	if ps.Config, err = resolveConfigBlobs(stack, ps.Config); err != nil {
+		return err
+	}
+	stackPath, err := getProjectStackPath(stack)
+	if err != nil {
+		return err
+	}
+	layers, err := stackConfigLayers(ps.Config)
+	if err != nil {
+		return err
+	}
+	layers[len(layers)-1].Source = stackPath
+
+	explained := layers.Explain(key)
+	if len(explained) == 0 {
+		return errors.Errorf(
+			"configuration key '%s' not found for stack '%s'", prettyKey(key), stack.Ref())
+	}
+
+	rows := []cmdutil.TableRow{}
+	for i, p := range explained {
+		value := "[secret]"
+		if !p.Value.Secure() {
+			if value, err = p.Value.Value(config.NewPanicCrypter()); err != nil {
+				return err
+			}
+		}
+		layer := p.Layer
+		if p.Source != "" {
+			layer = fmt.Sprintf("%s (%s)", p.Layer, p.Source)
+		}
+		status := "overridden"
+		if i == 0 {
+			status = "used"
+		}
+		rows = append(rows, cmdutil.TableRow{Columns: []string{layer, value, status}})
+	}
+
+	cmdutil.PrintTable(cmdutil.Table{
+		Headers: []string{"LAYER", "VALUE", "STATUS"},
+		Rows:    rows,
+	})
+	return nil
+}
+
 var (
 	// keyPattern is the regular expression a configuration key must match before we check (and error) if we think
 	// it is a password
@@ -914,24 +1680,59 @@ func getStackConfiguration(stack backend.Stack, sm secrets.Manager) (backend.Sta
 	if err != nil {
 		return backend.StackConfiguration{}, errors.Wrap(err, "loading stack configuration")
 	}
+	if workspaceStack.Config, err = resolveConfigBlobs(stack, workspaceStack.Config); err != nil {
+		return backend.StackConfiguration{}, errors.Wrap(err, "loading stack configuration")
+	}
+	if workspaceStack.Config, err = inheritConfig(workspaceStack.Config); err != nil {
+		return backend.StackConfiguration{}, errors.Wrap(err, "loading stack configuration")
+	}
+
+	// Defer constructing the decrypter until a secret is actually read, since doing so may prompt for a passphrase or
+	// contact a key management service.
+	crypter := config.NewLazyDecrypter(func() (config.Decrypter, error) {
+		dec := config.NewLazyDecrypter(func() (config.Decrypter, error) {
+			dec, err := sm.Decrypter()
+			if err != nil {
+				return nil, errors.Wrap(err, "getting configuration decrypter")
+			}
+			return dec, nil
+		})
+		// Values of keys with their own secrets provider never require the stack's decrypter.
+		return newKeyedDecrypter(workspaceStack, stack.Ref().Name(), dec)
+	})
+	crypter = config.NewCachingDecrypter(crypter, plaintextCache)
+
+	cfg, crypter, err := resolveConfigRefs(stack, workspaceStack.Config, crypter)
+	if err != nil {
+		return backend.StackConfiguration{}, errors.Wrap(err, "loading stack configuration")
+	}
+	// References between values are interpolated before anything is decrypted: secrets are interpolated as they are
+	// decrypted.
+	if cfg, crypter, err = interpolateConfig(cfg, crypter); err != nil {
+		return backend.StackConfiguration{}, errors.Wrap(err, "loading stack configuration")
+	}
+	if cfg, err = normalizeConfigBools(cfg); err != nil {
+		return backend.StackConfiguration{}, errors.Wrap(err, "loading stack configuration")
+	}
+	if err = validateStackConfig(stack, cfg); err != nil {
+		return backend.StackConfiguration{}, err
+	}
+	if cfg, err = forwardConfigAliases(cfg); err != nil {
+		return backend.StackConfiguration{}, errors.Wrap(err, "loading stack configuration")
+	}
 
 	// If there are no secrets in the configuration, we should never use the decrypter, so it is safe to return
 	// one which panics if it is used. This provides for some nice UX in the common case (since, for example, building
 	// the correct decrypter for the local backend would involve prompting for a passphrase)
-	if !workspaceStack.Config.HasSecureValue() {
+	if !cfg.HasSecureValue() {
 		return backend.StackConfiguration{
-			Config:    workspaceStack.Config,
+			Config:    cfg,
 			Decrypter: config.NewPanicCrypter(),
 		}, nil
 	}
 
-	crypter, err := sm.Decrypter()
-	if err != nil {
-		return backend.StackConfiguration{}, errors.Wrap(err, "getting configuration decrypter")
-	}
-
 	return backend.StackConfiguration{
-		Config:    workspaceStack.Config,
+		Config:    cfg,
 		Decrypter: crypter,
 	}, nil
 }
@@ -0,0 +1,131 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ssm"
+	vault "github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/pkg/v2/backend"
+	"github.com/pulumi/pulumi/pkg/v2/secrets/onepassword"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
+)
+
+// resolveConfigRefs resolves any references in cfg to values held in external stores, returning the resolved
+// configuration and a decrypter for it that defers to dec for the configuration's other secrets.
+func resolveConfigRefs(stack backend.Stack, cfg config.Map,
+	dec config.Decrypter) (config.Map, config.Decrypter, error) {
+
+	if !cfg.HasExternalRef() {
+		return cfg, dec, nil
+	}
+	stackPath, err := getProjectStackPath(stack)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cfg.ResolveRefs(commandContext(), newConfigRefResolvers(filepath.Dir(stackPath)), dec)
+}
+
+// newConfigRefResolvers returns the resolvers for the external stores that config values may refer to:
+//
+//   - `file:<path>` refers to the content of a file. Relative paths are relative to dir.
+//   - `aws-ssm:///<name>[?region=<region>]` refers to an AWS Systems Manager parameter, which is decrypted if it is a
+//     SecureString.
+//   - `vault:///<path>[#<field>]` refers to a field (by default, `value`) of a HashiCorp Vault secret. The server is
+//     located using the standard `VAULT_ADDR` and `VAULT_TOKEN` environment variables.
+//   - `op://<vault>/<item>[/<section>]/<field>` refers to a field of a 1Password item, read through a Connect server or
+//     with a service account.
+func newConfigRefResolvers(dir string) config.RefResolvers {
+	resolvers := config.RefResolvers{}
+	resolvers.Register("file", config.NewFileRefResolver(dir))
+	resolvers.Register("aws-ssm", ssmRefResolver{})
+	resolvers.Register("vault", vaultRefResolver{})
+	resolvers.Register(onepassword.RefScheme, onepassword.RefResolver{})
+	return resolvers
+}
+
+type ssmRefResolver struct{}
+
+func (ssmRefResolver) ResolveRef(ctx context.Context, uri *url.URL) (string, error) {
+	if uri.Path == "" || uri.Path == "/" {
+		return "", errors.New("an aws-ssm reference must include a parameter name")
+	}
+
+	sess, err := session.NewSessionWithOptions(session.Options{SharedConfigState: session.SharedConfigEnable})
+	if err != nil {
+		return "", errors.Wrap(err, "creating AWS session")
+	}
+	cfg := aws.NewConfig()
+	if region := uri.Query().Get("region"); region != "" {
+		cfg = cfg.WithRegion(region)
+	}
+
+	out, err := ssm.New(sess, cfg).GetParameterWithContext(ctx, &ssm.GetParameterInput{
+		Name:           aws.String(uri.Path),
+		WithDecryption: aws.Bool(true),
+	})
+	if err != nil {
+		return "", err
+	}
+	return aws.StringValue(out.Parameter.Value), nil
+}
+
+type vaultRefResolver struct{}
+
+func (vaultRefResolver) ResolveRef(ctx context.Context, uri *url.URL) (string, error) {
+	path := strings.TrimPrefix(uri.Path, "/")
+	if path == "" {
+		return "", errors.New("a vault reference must include a secret path")
+	}
+	field := uri.Fragment
+	if field == "" {
+		field = "value"
+	}
+
+	client, err := vault.NewClient(vault.DefaultConfig())
+	if err != nil {
+		return "", errors.Wrap(err, "creating Vault client")
+	}
+	secret, err := client.Logical().Read(path)
+	if err != nil {
+		return "", err
+	}
+	if secret == nil {
+		return "", errors.Errorf("no secret found at %s", path)
+	}
+
+	// Secrets in version 2 of the KV engine nest their fields within a `data` field.
+	data := secret.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+	v, ok := data[field]
+	if !ok {
+		return "", errors.Errorf("the secret at %s has no field %q", path, field)
+	}
+	if s, ok := v.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprintf("%v", v), nil
+}
@@ -15,10 +15,25 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	_ "gocloud.dev/secrets/localsecrets" // support for base64key://
 
+	"github.com/pulumi/pulumi/pkg/v2/backend/httpstate/client"
+	"github.com/pulumi/pulumi/pkg/v2/secrets/cloud"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/diag"
 	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
 	"github.com/pulumi/pulumi/sdk/v2/go/common/tokens"
 	"github.com/pulumi/pulumi/sdk/v2/go/common/workspace"
@@ -32,6 +47,8 @@ func TestPrettyKeyForProject(t *testing.T) {
 
 	assert.Equal(t, "foo", prettyKeyForProject(config.MustMakeKey("test-package", "foo"), proj))
 	assert.Equal(t, "other-package:bar", prettyKeyForProject(config.MustMakeKey("other-package", "bar"), proj))
+	assert.Equal(t, "test-package:db:password",
+		prettyKeyForProject(config.MustMakeKey("test-package:db", "password"), proj))
 }
 
 func TestSecretDetection(t *testing.T) {
@@ -41,3 +58,253 @@ func TestSecretDetection(t *testing.T) {
 	// The key name does not match the pattern, so even though this "looks like" a secret, we say it is not.
 	assert.False(t, looksLikeSecret(config.MustMakeKey("test", "okay"), "1415fc1f4eaeb5e096ee58c1480016638fff29bf"))
 }
+
+// withServiceProject runs f in a project with the given Pulumi.yaml, whose stack "dev" is held by a test Pulumi
+// service and has the given configuration file. The service "decrypts" a ciphertext to the text that it base64-encodes.
+func withServiceProject(t *testing.T, project, stackConfig string, f func(s *serviceStack)) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/batch-decrypt"):
+			var req apitype.BatchDecryptRequest
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			resp := apitype.BatchDecryptResponse{Plaintexts: make(map[string][]byte)}
+			for _, ct := range req.Ciphertexts {
+				resp.Plaintexts[base64.StdEncoding.EncodeToString(ct)] = ct
+			}
+			assert.NoError(t, json.NewEncoder(w).Encode(resp))
+		case strings.HasSuffix(r.URL.Path, "/decrypt"):
+			var req apitype.DecryptValueRequest
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.NoError(t, json.NewEncoder(w).Encode(apitype.DecryptValueResponse{Plaintext: req.Ciphertext}))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "service-project")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, os.Chdir(cwd)) }()
+	assert.NoError(t, os.Chdir(dir))
+
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "Pulumi.yaml"), []byte(project), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "Pulumi.dev.yaml"), []byte(stackConfig), 0600))
+
+	c := client.NewClient(server.URL, "token", diag.DefaultSink(ioutil.Discard, ioutil.Discard, diag.FormatOptions{}))
+	f(&serviceStack{b: &serviceBackend{client: c}})
+}
+
+// captureStdout returns everything that f writes to stdout.
+func captureStdout(t *testing.T, f func()) string {
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	f()
+	assert.NoError(t, w.Close())
+	out, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	return string(out)
+}
+
+func TestConfigInterpolation(t *testing.T) {
+	encode := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+	stackConfig := `config:
+  proj:region: us-west-2
+  proj:bucket: app-${proj:region}
+  proj:password:
+    secure: ` + encode("pw") + `
+  proj:url:
+    secure: ` + encode("admin:${proj:password}@${proj:region}") + `
+`
+
+	withServiceProject(t, "name: proj\nruntime: go\nconfigInterpolation: true\n", stackConfig, func(s *serviceStack) {
+		sm, err := getStackSecretsManager(s)
+		assert.NoError(t, err)
+		cfg, err := getStackConfiguration(s, sm)
+		assert.NoError(t, err)
+		plaintexts, err := cfg.Config.DecryptAll(context.Background(), cfg.Decrypter)
+		assert.NoError(t, err)
+		assert.Equal(t, map[config.Key]string{
+			config.MustMakeKey("proj", "region"):   "us-west-2",
+			config.MustMakeKey("proj", "bucket"):   "app-us-west-2",
+			config.MustMakeKey("proj", "password"): "pw",
+			config.MustMakeKey("proj", "url"):      "admin:pw@us-west-2",
+		}, plaintexts)
+
+		out := captureStdout(t, func() {
+			assert.NoError(t, getConfig(s, config.MustMakeKey("proj", "bucket"), false, false))
+			assert.NoError(t, getConfig(s, config.MustMakeKey("proj", "url"), false, false))
+		})
+		assert.Equal(t, "app-us-west-2\nadmin:pw@us-west-2\n", out)
+	})
+}
+
+func TestConfigInterpolationOptIn(t *testing.T) {
+	// Projects that do not opt in to interpolation see values with `${...}` exactly as they are written.
+	stackConfig := `config:
+  proj:region: us-west-2
+  proj:template: arn:aws:s3:::${AWS::Region}-${proj:region}
+  proj:script: echo ${HOME} ${USER:-nobody}
+`
+	withServiceProject(t, "name: proj\nruntime: go\n", stackConfig, func(s *serviceStack) {
+		cfg, err := getStackConfiguration(s, nil)
+		assert.NoError(t, err)
+		plaintexts, err := cfg.Config.Decrypt(cfg.Decrypter)
+		assert.NoError(t, err)
+		assert.Equal(t, map[config.Key]string{
+			config.MustMakeKey("proj", "region"):   "us-west-2",
+			config.MustMakeKey("proj", "template"): "arn:aws:s3:::${AWS::Region}-${proj:region}",
+			config.MustMakeKey("proj", "script"):   "echo ${HOME} ${USER:-nobody}",
+		}, plaintexts)
+
+		out := captureStdout(t, func() {
+			assert.NoError(t, getConfig(s, config.MustMakeKey("proj", "template"), false, false))
+		})
+		assert.Equal(t, "arn:aws:s3:::${AWS::Region}-${proj:region}\n", out)
+	})
+}
+
+func TestConfigEnvInterpolation(t *testing.T) {
+	if old, ok := os.LookupEnv("PULUMI_TEST_DATABASE_URL"); ok {
+		defer os.Setenv("PULUMI_TEST_DATABASE_URL", old)
+	} else {
+		defer os.Unsetenv("PULUMI_TEST_DATABASE_URL")
+	}
+	assert.NoError(t, os.Setenv("PULUMI_TEST_DATABASE_URL", "postgres://db"))
+
+	stackConfig := "config:\n  proj:url: ${env:PULUMI_TEST_DATABASE_URL}/app\n"
+	key := config.MustMakeKey("proj", "url")
+
+	withServiceProject(t, "name: proj\nruntime: go\nconfigEnvInterpolation: true\n", stackConfig, func(s *serviceStack) {
+		cfg, err := getStackConfiguration(s, nil)
+		assert.NoError(t, err)
+		v, err := cfg.Config[key].Value(cfg.Decrypter)
+		assert.NoError(t, err)
+		assert.Equal(t, "postgres://db/app", v)
+
+		out := captureStdout(t, func() {
+			assert.NoError(t, getConfig(s, key, false, false))
+		})
+		assert.Equal(t, "postgres://db/app\n", out)
+	})
+
+	// Without the project's opt-in, environment variables are not read.
+	withServiceProject(t, "name: proj\nruntime: go\nconfigInterpolation: true\n", stackConfig, func(s *serviceStack) {
+		_, err := getStackConfiguration(s, nil)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "references env:PULUMI_TEST_DATABASE_URL, which is not set")
+		}
+		assert.Error(t, getConfig(s, key, false, false))
+	})
+}
+
+func TestConfigSchemaDefaults(t *testing.T) {
+	project := `name: proj
+runtime: go
+configSchema:
+  replicas:
+    type: int
+    default: "3"
+  region:
+    default: us-east-1
+`
+	stackConfig := "config:\n  proj:region: us-west-2\n"
+
+	withServiceProject(t, project, stackConfig, func(s *serviceStack) {
+		cfg, err := getStackConfiguration(s, nil)
+		assert.NoError(t, err)
+		plaintexts, err := cfg.Config.Decrypt(cfg.Decrypter)
+		assert.NoError(t, err)
+		assert.Equal(t, map[config.Key]string{
+			config.MustMakeKey("proj", "replicas"): "3",
+			config.MustMakeKey("proj", "region"):   "us-west-2",
+		}, plaintexts)
+		assert.Equal(t, config.TypeInt, cfg.Config[config.MustMakeKey("proj", "replicas")].Type())
+
+		out := captureStdout(t, func() {
+			assert.NoError(t, getConfig(s, config.MustMakeKey("proj", "replicas"), false, false))
+		})
+		assert.Equal(t, "3\n", out)
+	})
+}
+
+func TestConfigBoolSyntax(t *testing.T) {
+	stackConfig := `config:
+  proj:flag: yes
+  proj:quoted: "True"
+  proj:labels:
+    enabled: "true"
+    name: "TRUE"
+`
+	labels := `{"enabled":"true","name":"TRUE"}`
+
+	project := "name: proj\nruntime: go\nconfigBooleans: yaml1.1\nconfigSchema:\n  flag:\n    type: bool\n"
+	withServiceProject(t, project, stackConfig, func(s *serviceStack) {
+		cfg, err := getStackConfiguration(s, nil)
+		assert.NoError(t, err)
+		plaintexts, err := cfg.Config.Decrypt(cfg.Decrypter)
+		assert.NoError(t, err)
+		assert.Equal(t, map[config.Key]string{
+			config.MustMakeKey("proj", "flag"):   "true",
+			config.MustMakeKey("proj", "quoted"): "True",
+			config.MustMakeKey("proj", "labels"): labels,
+		}, plaintexts)
+		assert.Equal(t, config.TypeBool, cfg.Config[config.MustMakeKey("proj", "flag")].Type())
+	})
+
+	// By default, values are left as they are written.
+	withServiceProject(t, "name: proj\nruntime: go\n", stackConfig, func(s *serviceStack) {
+		cfg, err := getStackConfiguration(s, nil)
+		assert.NoError(t, err)
+		plaintexts, err := cfg.Config.Decrypt(cfg.Decrypter)
+		assert.NoError(t, err)
+		assert.Equal(t, map[config.Key]string{
+			config.MustMakeKey("proj", "flag"):   "yes",
+			config.MustMakeKey("proj", "quoted"): "True",
+			config.MustMakeKey("proj", "labels"): labels,
+		}, plaintexts)
+	})
+}
+
+func TestFallbackDataKeySharing(t *testing.T) {
+	primaryURL := "base64key://" + base64.StdEncoding.EncodeToString(make([]byte, 32))
+	fallbackURL := "base64key://" + base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	dataKey, err := cloud.GenerateNewDataKey(primaryURL)
+	assert.NoError(t, err)
+	stackConfig := "secretsprovider: " + primaryURL + "\n" +
+		"encryptedkey: " + base64.StdEncoding.EncodeToString(dataKey) + "\n" +
+		"fallbackproviders:\n" +
+		"- secretsprovider: " + fallbackURL + "\n"
+
+	withServiceProject(t, "name: proj\nruntime: go\n", stackConfig, func(s *serviceStack) {
+		// Reading the stack's secrets does not give the fallback its copy of the data key, which would write the
+		// stack's configuration.
+		sm, err := getStackSecretsManager(s)
+		assert.NoError(t, err)
+		enc, err := sm.Encrypter()
+		assert.NoError(t, err)
+		ct, err := enc.EncryptValue("hunter2")
+		assert.NoError(t, err)
+		ps, err := loadProjectStack(s)
+		assert.NoError(t, err)
+		assert.Equal(t, "", ps.FallbackProviders[0].EncryptedKey)
+
+		assert.NoError(t, shareDataKeyWithFallbacks(s))
+		ps, err = loadProjectStack(s)
+		assert.NoError(t, err)
+		assert.NotEqual(t, "", ps.FallbackProviders[0].EncryptedKey)
+		fallback, err := newKeySecretsManager(s.Ref().Name(), ps.FallbackProviders[0])
+		assert.NoError(t, err)
+		dec, err := fallback.Decrypter()
+		assert.NoError(t, err)
+		pt, err := dec.DecryptValue(ct)
+		assert.NoError(t, err)
+		assert.Equal(t, "hunter2", pt)
+	})
+}
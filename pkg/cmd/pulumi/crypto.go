@@ -15,6 +15,7 @@
 package main
 
 import (
+	"encoding/base64"
 	"reflect"
 	"strings"
 
@@ -24,10 +25,19 @@ import (
 	"github.com/pulumi/pulumi/pkg/v2/backend/httpstate"
 	"github.com/pulumi/pulumi/pkg/v2/resource/stack"
 	"github.com/pulumi/pulumi/pkg/v2/secrets"
+	"github.com/pulumi/pulumi/pkg/v2/secrets/cloud"
 	"github.com/pulumi/pulumi/pkg/v2/secrets/passphrase"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/diag"
 	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/tokens"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/cmdutil"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/workspace"
 )
 
+// plaintextCache holds the plaintexts of the secrets decrypted by this process, so that a secret that is read more
+// than once, e.g. during a preview and then an update, is only decrypted by its secrets provider once.
+var plaintextCache = config.NewPlaintextCache()
+
 func getStackEncrypter(s backend.Stack) (config.Encrypter, error) {
 	sm, err := getStackSecretsManager(s)
 	if err != nil {
@@ -43,51 +53,279 @@ func getStackDecrypter(s backend.Stack) (config.Decrypter, error) {
 		return nil, err
 	}
 
-	return sm.Decrypter()
-}
+	dec, err := sm.Decrypter()
+	if err != nil {
+		return nil, err
+	}
 
-func getStackSecretsManager(s backend.Stack) (secrets.Manager, error) {
 	ps, err := loadProjectStack(s)
 	if err != nil {
 		return nil, err
 	}
+	if dec, err = newKeyedDecrypter(ps, s.Ref().Name(), dec); err != nil {
+		return nil, err
+	}
+	return config.NewCachingDecrypter(dec, plaintextCache), nil
+}
 
-	sm, err := func() (secrets.Manager, error) {
-		if ps.SecretsProvider != passphrase.Type && ps.SecretsProvider != "default" && ps.SecretsProvider != "" {
-			return newCloudSecretsManager(s.Ref().Name(), stackConfigFile, ps.SecretsProvider)
-		}
+// newKeyedDecrypter returns a decrypter for the configuration in ps that decrypts the values of keys with their own
+// secrets provider using that provider, and all other values using dec.
+func newKeyedDecrypter(ps *workspace.ProjectStack, stackName tokens.QName,
+	dec config.Decrypter) (config.Decrypter, error) {
+
+	if len(ps.KeyProviders) == 0 {
+		return dec, nil
+	}
 
-		if ps.EncryptionSalt != "" {
-			return newPassphraseSecretsManager(s.Ref().Name(), stackConfigFile,
-				false /* rotatePassphraseSecretsProvider */)
+	// Keys that share a provider and data key share a single secrets manager.
+	managers := make(map[workspace.KeySecretsProvider]config.Decrypter)
+	decrypters := make(map[config.Key]config.Decrypter)
+	for name, p := range ps.KeyProviders {
+		key, err := config.ParseKey(name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid secrets provider for config key %q", name)
 		}
+		d, ok := managers[p]
+		if !ok {
+			sm, err := cacheSecretsOffline(newKeySecretsManager(stackName, p))
+			if err != nil {
+				return nil, errors.Wrapf(err, "creating secrets provider for config key %v", key)
+			}
+			if d, err = sm.Decrypter(); err != nil {
+				return nil, err
+			}
+			managers[p] = d
+		}
+		decrypters[key] = d
+	}
+	return config.NewKeyedDecrypter(ps.Config, dec, decrypters)
+}
+
+// newKeySecretsManager returns the secrets manager for a config key with its own secrets provider.
+func newKeySecretsManager(stackName tokens.QName, p workspace.KeySecretsProvider) (secrets.Manager, error) {
+	url, err := resolveSecretsProvider(stackName, p.SecretsProvider)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := base64.StdEncoding.DecodeString(p.EncryptedKey)
+	if err != nil {
+		return nil, err
+	}
+	return cloud.NewCloudSecretsManager(url, dataKey)
+}
 
-		switch s.(type) {
-		case filestate.Stack:
-			return newPassphraseSecretsManager(s.Ref().Name(), stackConfigFile,
-				false /* rotatePassphraseSecretsProvider */)
-		case httpstate.Stack:
-			return newServiceSecretsManager(s.(httpstate.Stack), s.Ref().Name(), stackConfigFile)
+// resolveSecretsProvider expands the references in a secrets provider URL to the stack's name, as `${stack}`, its
+// project's name, as `${project}`, and environment variables, as `${env:NAME}`. Stack files record the unexpanded URL,
+// so that many stacks can share one secrets provider configuration.
+func resolveSecretsProvider(stackName tokens.QName, secretsProvider string) (string, error) {
+	if !cloud.IsURLTemplate(secretsProvider) {
+		return secretsProvider, nil
+	}
+	vars := map[string]string{"stack": string(stackName)}
+	if proj, _, err := readProject(); err == nil {
+		vars["project"] = string(proj.Name)
+	}
+	return cloud.ExpandURL(secretsProvider, vars)
+}
+
+// setKeySecretsProvider records that the value of key is encrypted by the given secrets provider, generating a new
+// data key if the key does not already use that provider. It returns an encrypter for the key's value.
+func setKeySecretsProvider(ps *workspace.ProjectStack, stackName tokens.QName, key config.Key,
+	secretsProvider string) (config.Encrypter, error) {
+
+	if err := validateSecretsProvider(secretsProvider); err != nil {
+		return nil, err
+	}
+	if secretsProvider == passphrase.Type || secretsProvider == "default" {
+		return nil, errors.Errorf("config keys may only override the stack's secrets provider with a cloud secrets "+
+			"provider, not '%s'", secretsProvider)
+	}
+
+	p, ok := ps.KeyProviders[key.String()]
+	if !ok || p.SecretsProvider != secretsProvider {
+		// Reuse the data key of another config key that uses the same provider, if there is one.
+		p = workspace.KeySecretsProvider{SecretsProvider: secretsProvider}
+		for _, other := range ps.KeyProviders {
+			if other.SecretsProvider == secretsProvider {
+				p = other
+				break
+			}
 		}
+		if p.EncryptedKey == "" {
+			url, err := resolveSecretsProvider(stackName, secretsProvider)
+			if err != nil {
+				return nil, err
+			}
+			dataKey, err := cloud.GenerateNewDataKey(url)
+			if err != nil {
+				return nil, err
+			}
+			p.EncryptedKey = base64.StdEncoding.EncodeToString(dataKey)
+		}
+	}
+
+	sm, err := newKeySecretsManager(stackName, p)
+	if err != nil {
+		return nil, err
+	}
+	if ps.KeyProviders == nil {
+		ps.KeyProviders = make(map[string]workspace.KeySecretsProvider)
+	}
+	ps.KeyProviders[key.String()] = p
+	return sm.Encrypter()
+}
+
+func getStackSecretsManager(s backend.Stack) (secrets.Manager, error) {
+	ps, err := loadProjectStack(s)
+	if err != nil {
+		return nil, err
+	}
 
-		return nil, errors.Errorf("unknown stack type %s", reflect.TypeOf(s))
-	}()
+	sm, err := newPrimarySecretsManager(s, ps)
+	if len(ps.FallbackProviders) > 0 {
+		sm, err = newFallbackSecretsManager(s, sm, err)
+	}
+	sm, err = cacheSecretsOffline(sm, err)
 	if err != nil {
 		return nil, err
 	}
 	return stack.NewCachingSecretsManager(sm), nil
 }
 
+// newPrimarySecretsManager returns the secrets manager of the stack's own secrets provider, which is recorded in ps.
+func newPrimarySecretsManager(s backend.Stack, ps *workspace.ProjectStack) (secrets.Manager, error) {
+	if ps.SecretsProvider != passphrase.Type && ps.SecretsProvider != "default" && ps.SecretsProvider != "" {
+		return newCloudSecretsManager(s.Ref().Name(), stackConfigFile, ps.SecretsProvider)
+	}
+
+	if ps.EncryptionSalt != "" {
+		return newPassphraseSecretsManager(s.Ref().Name(), stackConfigFile,
+			false /* rotatePassphraseSecretsProvider */)
+	}
+
+	switch s.(type) {
+	case filestate.Stack:
+		return newPassphraseSecretsManager(s.Ref().Name(), stackConfigFile,
+			false /* rotatePassphraseSecretsProvider */)
+	case httpstate.Stack:
+		return newServiceSecretsManager(s.(httpstate.Stack), s.Ref().Name(), stackConfigFile)
+	}
+
+	return nil, errors.Errorf("unknown stack type %s", reflect.TypeOf(s))
+}
+
+// shareDataKeyWithFallbacks gives each of the stack's fallback secrets providers that has no encrypted key a copy of
+// the stack's data key, encrypted by the fallback's KMS, and saves the stack's configuration if any were given one.
+// This writes the stack's configuration, so it is only done by commands that change it.
+func shareDataKeyWithFallbacks(s backend.Stack) error {
+	ps, err := loadProjectStack(s)
+	if err != nil {
+		return err
+	}
+	missing := false
+	for _, p := range ps.FallbackProviders {
+		missing = missing || p.EncryptedKey == ""
+	}
+	if !missing {
+		return nil
+	}
+
+	primary, err := newPrimarySecretsManager(s, ps)
+	if err != nil {
+		return err
+	}
+	cm, ok := primary.(*cloud.Manager)
+	if !ok {
+		return errors.New("the stack's data key can only be shared with fallback secrets providers by a cloud " +
+			"secrets provider")
+	}
+
+	// Reload the project stack, as creating the primary secrets manager may have changed it.
+	if ps, err = loadProjectStack(s); err != nil {
+		return err
+	}
+	for i, p := range ps.FallbackProviders {
+		if p.EncryptedKey != "" {
+			continue
+		}
+		url, err := resolveSecretsProvider(s.Ref().Name(), p.SecretsProvider)
+		if err != nil {
+			return err
+		}
+		dataKey, err := cm.WrapDataKey(url)
+		if err != nil {
+			return errors.Wrapf(err, "sharing data key with fallback secrets provider %s", p.SecretsProvider)
+		}
+		p.EncryptedKey = base64.StdEncoding.EncodeToString(dataKey)
+		ps.FallbackProviders[i] = p
+	}
+	return saveProjectStack(s, ps)
+}
+
+// newFallbackSecretsManager returns a secrets manager that encrypts with the stack's secrets manager, primary, and
+// decrypts with it and then with each of the stack's fallback secrets providers. If primary could not be created,
+// unavailable is the reason, and secrets are decrypted by the fallbacks alone. Fallbacks that have not yet been given a
+// copy of the stack's data key (see shareDataKeyWithFallbacks), or that cannot be created, are skipped with a warning.
+func newFallbackSecretsManager(s backend.Stack, primary secrets.Manager,
+	unavailable error) (secrets.Manager, error) {
+
+	if unavailable != nil {
+		primary = nil
+	}
+
+	// Reload the project stack, as creating the primary secrets manager may have changed it.
+	ps, err := loadProjectStack(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var fallbacks []secrets.Manager
+	for _, p := range ps.FallbackProviders {
+		if p.SecretsProvider == passphrase.Type || p.SecretsProvider == "default" {
+			return nil, errors.Errorf("fallback secrets providers must be cloud secrets providers, not '%s'",
+				p.SecretsProvider)
+		}
+		if p.EncryptedKey == "" {
+			cmdutil.Diag().Warningf(diag.Message("", "fallback secrets provider %s has no copy of the stack's data "+
+				"key; run `pulumi config set` or `pulumi stack rotate-secrets` to give it one"), p.SecretsProvider)
+			continue
+		}
+
+		sm, err := newKeySecretsManager(s.Ref().Name(), p)
+		if err != nil {
+			cmdutil.Diag().Warningf(diag.Message("", "fallback secrets provider %s is unavailable: %v"),
+				p.SecretsProvider, err)
+			continue
+		}
+		fallbacks = append(fallbacks, sm)
+	}
+
+	if unavailable != nil {
+		if len(fallbacks) == 0 {
+			return nil, unavailable
+		}
+		cmdutil.Diag().Warningf(diag.Message("", "secrets provider is unavailable, so secrets can only be read "+
+			"using its fallbacks: %v"), unavailable)
+	}
+	return secrets.NewFallbackManager(primary, unavailable, fallbacks...), nil
+}
+
 func validateSecretsProvider(typ string) error {
 	kind := strings.SplitN(typ, ":", 2)[0]
-	supportedKinds := []string{"default", "passphrase", "awskms", "azurekeyvault", "gcpkms", "hashivault"}
+	supportedKinds := []string{"default", "passphrase", "awskms", "azurekeyvault", "gcpkms", "hashivault", "vault", "age",
+		"onepassword", "pkcs11"}
 	for _, supportedKind := range supportedKinds {
 		if kind == supportedKind {
 			return nil
 		}
 	}
+	// Other kinds may be served by a secrets provider plugin.
+	if cloud.HasSecretsPlugin(kind) {
+		return nil
+	}
 	return errors.Errorf(
-		"unknown secrets provider type '%s' (supported values: %s)",
+		"unknown secrets provider type '%s' (supported values: %s, or any scheme with a pulumi-secrets-<scheme> "+
+			"plugin installed)",
 		kind,
 		strings.Join(supportedKinds, ","),
 	)
@@ -47,12 +47,17 @@ func newCloudSecretsManager(stackName tokens.QName, configFile, secretsProvider
 		info.EncryptionSalt = ""
 	}
 
+	url, err := resolveSecretsProvider(stackName, secretsProvider)
+	if err != nil {
+		return nil, err
+	}
+
 	var secretsManager *cloud.Manager
 
 	// if there is no key OR the secrets provider is changing
 	// then we need to generate the new key based on the new secrets provider
 	if info.EncryptedKey == "" || info.SecretsProvider != secretsProvider {
-		dataKey, err := cloud.GenerateNewDataKey(secretsProvider)
+		dataKey, err := cloud.GenerateNewDataKey(url)
 		if err != nil {
 			return nil, err
 		}
@@ -67,7 +72,7 @@ func newCloudSecretsManager(stackName tokens.QName, configFile, secretsProvider
 	if err != nil {
 		return nil, err
 	}
-	secretsManager, err = cloud.NewCloudSecretsManager(secretsProvider, dataKey)
+	secretsManager, err = cloud.NewCloudSecretsManager(url, dataKey)
 	if err != nil {
 		return nil, err
 	}
@@ -1,9 +1,24 @@
 package main
 
 import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/pulumi/pulumi/pkg/v2/backend"
+	"github.com/pulumi/pulumi/pkg/v2/backend/httpstate"
+	"github.com/pulumi/pulumi/pkg/v2/backend/httpstate/client"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/diag"
 	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/tokens"
 	"github.com/pulumi/pulumi/sdk/v2/go/common/workspace"
 	"github.com/stretchr/testify/assert"
 )
@@ -47,3 +62,86 @@ func TestChangeProjectStackSecretDetails(t *testing.T) {
 		})
 	}
 }
+
+// serviceStackRef, serviceStack and serviceBackend stand in for a stack in the Pulumi service, whose client talks to
+// a test server.
+type serviceStackRef string
+
+func (r serviceStackRef) String() string     { return string(r) }
+func (r serviceStackRef) Name() tokens.QName { return tokens.QName(r) }
+
+type serviceStack struct {
+	httpstate.Stack
+	b httpstate.Backend
+}
+
+func (s *serviceStack) Ref() backend.StackReference { return serviceStackRef("dev") }
+func (s *serviceStack) Backend() backend.Backend    { return s.b }
+func (s *serviceStack) StackIdentifier() client.StackIdentifier {
+	return client.StackIdentifier{Owner: "owner", Project: "proj", Stack: "dev"}
+}
+
+type serviceBackend struct {
+	httpstate.Backend
+	client *client.Client
+}
+
+func (b *serviceBackend) Client() *client.Client { return b.client }
+
+func TestServiceSecretsAreBatchDecrypted(t *testing.T) {
+	var batches, singles int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/batch-decrypt"):
+			batches++
+			var req apitype.BatchDecryptRequest
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			resp := apitype.BatchDecryptResponse{Plaintexts: make(map[string][]byte)}
+			for _, ct := range req.Ciphertexts {
+				resp.Plaintexts[base64.StdEncoding.EncodeToString(ct)] = append([]byte("plain-"), ct...)
+			}
+			assert.NoError(t, json.NewEncoder(w).Encode(resp))
+		case strings.HasSuffix(r.URL.Path, "/decrypt"):
+			singles++
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "service-secrets")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cwd, err := os.Getwd()
+	assert.NoError(t, err)
+	defer func() { assert.NoError(t, os.Chdir(cwd)) }()
+	assert.NoError(t, os.Chdir(dir))
+
+	encode := func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) }
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "Pulumi.yaml"), []byte("name: proj\nruntime: go\n"), 0600))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "Pulumi.dev.yaml"), []byte(`config:
+  proj:a:
+    secure: `+encode("a")+`
+  proj:b:
+    secure: `+encode("b")+`
+  proj:c: plain
+`), 0600))
+
+	c := client.NewClient(server.URL, "token", diag.DefaultSink(ioutil.Discard, ioutil.Discard, diag.FormatOptions{}))
+	s := &serviceStack{b: &serviceBackend{client: c}}
+
+	sm, err := getStackSecretsManager(s)
+	assert.NoError(t, err)
+	cfg, err := getStackConfiguration(s, sm)
+	assert.NoError(t, err)
+	plaintexts, err := cfg.Config.DecryptAll(context.Background(), cfg.Decrypter)
+	assert.NoError(t, err)
+	assert.Equal(t, map[config.Key]string{
+		config.MustMakeKey("proj", "a"): "plain-a",
+		config.MustMakeKey("proj", "b"): "plain-b",
+		config.MustMakeKey("proj", "c"): "plain",
+	}, plaintexts)
+	assert.Equal(t, 1, batches)
+	assert.Equal(t, 0, singles)
+}
@@ -348,7 +348,11 @@ func newNewCmd() *cobra.Command {
 			"* `pulumi new --secrets-provider=\"awskms://1234abcd-12ab-34cd-56ef-1234567890ab?region=us-east-1\"`\n" +
 			"* `pulumi new --secrets-provider=\"azurekeyvault://mykeyvaultname.vault.azure.net/keys/mykeyname\"`\n" +
 			"* `pulumi new --secrets-provider=\"gcpkms://projects/p/locations/l/keyRings/r/cryptoKeys/k\"`\n" +
-			"* `pulumi new --secrets-provider=\"hashivault://mykey\"`" +
+			"* `pulumi new --secrets-provider=\"hashivault://mykey\"`\n" +
+			"* `pulumi new --secrets-provider=\"vault://mykey?namespace=myns&auth=approle\"`\n" +
+			"* `pulumi new --secrets-provider=\"age://?recipientsFile=team.txt\"`\n" +
+			"* `pulumi new --secrets-provider=\"onepassword://myvault\"`\n" +
+			"* `pulumi new --secrets-provider=\"pkcs11://?token=YubiKey%20PIV&object=mykey\"`" +
 			"\n\n" +
 			"To create a project from a specific source control location, pass the url as follows e.g.\n" +
 			"* `pulumi new https://gitlab.com/<user>/<repo>`\n" +
@@ -432,7 +436,8 @@ func newNewCmd() *cobra.Command {
 		"Skip prompts and proceed with default values")
 	cmd.PersistentFlags().StringVar(
 		&args.secretsProvider, "secrets-provider", "default", "The type of the provider that should be used to encrypt and "+
-			"decrypt secrets (possible choices: default, passphrase, awskms, azurekeyvault, gcpkms, hashivault)")
+			"decrypt secrets (possible choices: default, passphrase, awskms, azurekeyvault, gcpkms, hashivault, vault, age, "+
+			"onepassword, pkcs11)")
 
 	return cmd
 }
@@ -143,6 +143,9 @@ func newPreviewCmd() *cobra.Command {
 			if err != nil {
 				return result.FromError(errors.Wrap(err, "getting stack configuration"))
 			}
+			if err = checkRequiredConfig(cfg.Config); err != nil {
+				return result.FromError(err)
+			}
 
 			targetURNs := []resource.URN{}
 			for _, t := range targets {
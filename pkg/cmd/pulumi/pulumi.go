@@ -40,6 +40,7 @@ import (
 	"github.com/pulumi/pulumi/pkg/v2/backend/filestate"
 	"github.com/pulumi/pulumi/pkg/v2/backend/httpstate"
 	"github.com/pulumi/pulumi/pkg/v2/backend/httpstate/client"
+	"github.com/pulumi/pulumi/pkg/v2/resource/stack"
 	"github.com/pulumi/pulumi/pkg/v2/version"
 	"github.com/pulumi/pulumi/sdk/v2/go/common/diag"
 	"github.com/pulumi/pulumi/sdk/v2/go/common/diag/colors"
@@ -119,6 +120,12 @@ func NewPulumiCmd() *cobra.Command {
 				tracingHeader = tracingHeaderFlag
 			}
 
+			// Read checkpoints through the offline secret cache, so that their secrets can be read while their
+			// secrets provider is unreachable.
+			if secretCacheEnabled() {
+				stack.DefaultSecretsProvider = offlineSecretsProvider{stack.DefaultSecretsProvider}
+			}
+
 			if profiling != "" {
 				if err := cmdutil.InitProfiling(profiling); err != nil {
 					logging.Warningf("could not initialize profiling: %v", err)
@@ -149,6 +156,8 @@ func NewPulumiCmd() *cobra.Command {
 				cmdutil.Diag().Warningf(checkVersionMsg)
 			}
 
+			saveSecretCache()
+
 			logging.Flush()
 			cmdutil.CloseTracing()
 
@@ -180,6 +189,8 @@ func NewPulumiCmd() *cobra.Command {
 		"Enable verbose logging (e.g., v=3); anything >3 is very verbose")
 	cmd.PersistentFlags().StringVar(
 		&color, "color", "auto", "Colorize output. Choices are: always, never, raw, auto")
+	cmd.PersistentFlags().BoolVar(&noSecretCache, "no-secret-cache", false,
+		"Neither read nor write the offline cache of decrypted secrets")
 
 	// Common commands:
 	//     - Getting Started Commands:
@@ -0,0 +1,177 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/v2/resource/stack"
+	"github.com/pulumi/pulumi/pkg/v2/secrets"
+	"github.com/pulumi/pulumi/pkg/v2/secrets/offline"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/diag"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/cmdutil"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/logging"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/workspace"
+)
+
+// noSecretCache disables the offline secret cache, even if its passphrase is set.
+var noSecretCache bool
+
+var (
+	secretCacheOnce sync.Once
+	secretCache     *offline.Cache
+)
+
+// secretCacheEnabled returns true if decrypted secrets should be cached offline.
+func secretCacheEnabled() bool {
+	return !noSecretCache && os.Getenv(offline.PassphraseEnvVar) != ""
+}
+
+func secretCachePath() (string, error) {
+	return workspace.GetPulumiPath("secret-cache.json")
+}
+
+// openSecretCache returns the offline secret cache, or nil if it is disabled or cannot be opened.
+func openSecretCache() *offline.Cache {
+	secretCacheOnce.Do(func() {
+		if !secretCacheEnabled() {
+			return
+		}
+
+		ttl := offline.DefaultTTL
+		if s := os.Getenv(offline.TTLEnvVar); s != "" {
+			d, err := time.ParseDuration(s)
+			if err != nil || d <= 0 {
+				cmdutil.Diag().Warningf(diag.Message("", "ignoring invalid %s %q"), offline.TTLEnvVar, s)
+			} else {
+				ttl = d
+			}
+		}
+
+		path, err := secretCachePath()
+		if err == nil {
+			secretCache, err = offline.Open(path, os.Getenv(offline.PassphraseEnvVar), ttl)
+		}
+		if err != nil {
+			cmdutil.Diag().Warningf(diag.Message("", "not using the offline secret cache: %v"), err)
+		}
+	})
+	return secretCache
+}
+
+// saveSecretCache saves the secrets decrypted during the command to the offline secret cache, if it is in use.
+func saveSecretCache() {
+	if secretCache == nil {
+		return
+	}
+	if err := secretCache.Save(); err != nil {
+		logging.V(3).Infof("could not save secret cache: %v", err)
+	}
+}
+
+// cacheSecretsOffline wraps a secrets manager so that the secrets it decrypts are cached offline, and are read from
+// the cache if it cannot decrypt them. If the secrets manager could not be created, err is the reason, and secrets
+// are read from the cache alone. If the cache is disabled, sm and err are returned unchanged.
+func cacheSecretsOffline(sm secrets.Manager, err error) (secrets.Manager, error) {
+	c := openSecretCache()
+	if c == nil {
+		return sm, err
+	}
+	if err != nil {
+		cmdutil.Diag().Warningf(diag.Message("", "secrets provider is unavailable, so secrets can only be read "+
+			"from the offline secret cache: %v"), err)
+		return offline.NewManager(&unavailableSecretsManager{err: err}, c), nil
+	}
+	return offline.NewManager(sm, c), nil
+}
+
+// unavailableSecretsManager is a secrets manager that could not be created, which fails to encrypt or decrypt.
+type unavailableSecretsManager struct {
+	err error
+}
+
+func (m *unavailableSecretsManager) Type() string                         { return "" }
+func (m *unavailableSecretsManager) State() interface{}                   { return nil }
+func (m *unavailableSecretsManager) Encrypter() (config.Encrypter, error) { return nil, m.err }
+func (m *unavailableSecretsManager) Decrypter() (config.Decrypter, error) { return nil, m.err }
+
+// offlineSecretsProvider creates the secrets managers for checkpoints, caching the secrets they decrypt offline.
+type offlineSecretsProvider struct {
+	stack.SecretsProvider
+}
+
+func (p offlineSecretsProvider) OfType(ty string, state json.RawMessage) (secrets.Manager, error) {
+	c := openSecretCache()
+	sm, err := p.SecretsProvider.OfType(ty, state)
+	switch {
+	case c == nil:
+		return sm, err
+	case err != nil:
+		cmdutil.Diag().Warningf(diag.Message("", "secrets provider is unavailable, so the stack's state can only "+
+			"be read using the offline secret cache: %v"), err)
+		sm = offline.NewUnavailableManager(ty, state, err, c)
+	default:
+		sm = offline.NewManager(sm, c)
+	}
+	// Wrap the manager again so that a deployment that is read and then written keeps the ciphertexts of its secrets.
+	return stack.NewCachingSecretsManager(sm), nil
+}
+
+func newConfigSecretCacheCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "secret-cache",
+		Short: "Manage the offline cache of decrypted secrets",
+		Long: "Manage the offline cache of decrypted secrets.\n" +
+			"\n" +
+			"When " + offline.PassphraseEnvVar + " is set, every secret that is decrypted is also stored in an\n" +
+			"encrypted cache in the Pulumi home directory, protected by that passphrase. A secret whose secrets\n" +
+			"provider cannot be reached, e.g. during a key management service outage or without a network\n" +
+			"connection, is read from the cache instead, so that commands like `pulumi preview` still work.\n" +
+			"\n" +
+			"A cached secret expires 24 hours after it was last decrypted by its secrets provider; set\n" +
+			offline.TTLEnvVar + " to a duration such as `8h` to change this. Pass `--no-secret-cache` to any\n" +
+			"command to neither read nor write the cache.",
+		Args: cmdutil.NoArgs,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "clear",
+		Short: "Remove every secret from the offline secret cache",
+		Long: "Remove every secret from the offline secret cache.\n" +
+			"\n" +
+			"This revokes every cached secret. It does not require the cache's passphrase.",
+		Args: cmdutil.NoArgs,
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			path, err := secretCachePath()
+			if err != nil {
+				return err
+			}
+			if err = offline.Clear(path); err != nil {
+				return err
+			}
+			fmt.Printf("Cleared the offline secret cache\n")
+			return nil
+		}),
+	})
+
+	return cmd
+}
@@ -20,6 +20,7 @@ import (
 	"fmt"
 	"github.com/pulumi/pulumi/pkg/v2/backend"
 	"github.com/pulumi/pulumi/pkg/v2/resource/stack"
+	"github.com/pulumi/pulumi/pkg/v2/secrets"
 	"github.com/pulumi/pulumi/sdk/v2/go/common/apitype"
 	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
 	"github.com/spf13/cobra"
@@ -34,7 +35,8 @@ func newStackChangeSecretsProviderCmd() *cobra.Command {
 		Args:  cmdutil.ExactArgs(1),
 		Short: "Change the secrets provider for the current stack",
 		Long: "Change the secrets provider for the current stack. " +
-			"Valid secret providers types are `default`, `passphrase`, `awskms`, `azurekeyvault`, `gcpkms`, `hashivault`.\n\n" +
+			"Valid secret providers types are `default`, `passphrase`, `awskms`, `azurekeyvault`, `gcpkms`, `hashivault`, " +
+			"`vault`, `age`, `onepassword`, `pkcs11`.\n\n" +
 			"To change to using the Pulumi Default Secrets Provider, use the following:\n" +
 			"\n" +
 			"pulumi stack change-secrets-provider default" +
@@ -50,7 +52,11 @@ func newStackChangeSecretsProviderCmd() *cobra.Command {
 			"\"azurekeyvault://mykeyvaultname.vault.azure.net/keys/mykeyname\"`\n" +
 			"* `pulumi stack change-secrets-provider " +
 			"\"gcpkms://projects/<p>/locations/<l>/keyRings/<r>/cryptoKeys/<k>\"`\n" +
-			"* `pulumi stack change-secrets-provider \"hashivault://mykey\"`",
+			"* `pulumi stack change-secrets-provider \"hashivault://mykey\"`\n" +
+			"* `pulumi stack change-secrets-provider \"vault://mykey?namespace=myns&auth=approle\"`\n" +
+			"* `pulumi stack change-secrets-provider \"age://?recipientsFile=team.txt\"`\n" +
+			"* `pulumi stack change-secrets-provider \"onepassword://myvault\"`\n" +
+			"* `pulumi stack change-secrets-provider \"pkcs11://?token=YubiKey%20PIV&object=mykey\"`",
 		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
 			opts := display.Options{
 				Color: cmdutil.GetGlobalColorization(),
@@ -122,8 +128,9 @@ func migrateOldConfigAndCheckpointToNewSecretsProvider(ctx context.Context, curr
 		return err
 	}
 
-	// Create a copy of the current config map and re-encrypt using the new secrets provider
-	newProjectConfig, err := currentConfig.Copy(decrypter, newEncrypter)
+	// Create a copy of the current config map and re-encrypt using the new secrets provider, including any secrets
+	// nested within objects and lists
+	newProjectConfig, err := currentConfig.Reencrypt(ctx, decrypter, newEncrypter)
 	if err != nil {
 		return err
 	}
@@ -140,10 +147,18 @@ func migrateOldConfigAndCheckpointToNewSecretsProvider(ctx context.Context, curr
 		}
 	}
 
+	// Every value is now encrypted by the new secrets provider, including those that had a provider of their own.
+	reloadedProjectStack.KeyProviders = nil
+
 	if err := saveProjectStack(currentStack, reloadedProjectStack); err != nil {
 		return err
 	}
 
+	return reencryptCheckpoint(ctx, currentStack, newSecretsManager)
+}
+
+// reencryptCheckpoint re-encrypts the secrets in the stack's latest checkpoint using secretsManager.
+func reencryptCheckpoint(ctx context.Context, currentStack backend.Stack, newSecretsManager secrets.Manager) error {
 	// Load the current checkpoint so those secrets can also be decrypted
 	checkpoint, err := currentStack.ExportDeployment(ctx)
 	if err != nil {
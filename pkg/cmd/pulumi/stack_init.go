@@ -26,7 +26,8 @@ import (
 
 const (
 	possibleSecretsProviderChoices = "The type of the provider that should be used to encrypt and decrypt secrets\n" +
-		"(possible choices: default, passphrase, awskms, azurekeyvault, gcpkms, hashivault)"
+		"(possible choices: default, passphrase, awskms, azurekeyvault, gcpkms, hashivault, vault, age, onepassword,\n" +
+		"pkcs11)"
 )
 
 func newStackInitCmd() *cobra.Command {
@@ -62,6 +63,16 @@ func newStackInitCmd() *cobra.Command {
 			"* `pulumi stack init --secrets-provider=\"azurekeyvault://mykeyvaultname.vault.azure.net/keys/mykeyname\"`\n" +
 			"* `pulumi stack init --secrets-provider=\"gcpkms://projects/<p>/locations/<l>/keyRings/<r>/cryptoKeys/<k>\"`\n" +
 			"* `pulumi stack init --secrets-provider=\"hashivault://mykey\"\n`" +
+			"* `pulumi stack init --secrets-provider=\"vault://mykey?namespace=myns&auth=approle\"`\n" +
+			"* `pulumi stack init --secrets-provider=\"age://?recipientsFile=team.txt\"`\n" +
+			"* `pulumi stack init --secrets-provider=\"onepassword://myvault\"`\n" +
+			"* `pulumi stack init --secrets-provider=\"pkcs11://?token=YubiKey%20PIV&object=mykey\"`\n" +
+			"\n" +
+			"A cloud secrets provider URL may refer to the stack's name as `${stack}`, its project's name as\n" +
+			"`${project}`, and environment variables as `${env:NAME}`. These are expanded whenever the URL is used,\n" +
+			"so that many stacks can share one secrets provider configuration, e.g.:\n" +
+			"\n" +
+			"* `pulumi stack init --secrets-provider='awskms://alias/pulumi-${stack}?region=${env:AWS_REGION}'`\n" +
 			"\n" +
 			"A stack can be created based on the configuration of an existing stack by passing the\n" +
 			"`--copy-config-from` flag.\n" +
@@ -0,0 +1,223 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/pulumi/pulumi/pkg/v2/backend"
+	"github.com/pulumi/pulumi/pkg/v2/backend/display"
+	"github.com/pulumi/pulumi/pkg/v2/backend/filestate"
+	"github.com/pulumi/pulumi/pkg/v2/secrets/cloud"
+	"github.com/pulumi/pulumi/pkg/v2/secrets/passphrase"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/tokens"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/cmdutil"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/workspace"
+)
+
+func newStackRotateSecretsCmd() *cobra.Command {
+	var stackName string
+
+	cmd := &cobra.Command{
+		Use:   "rotate-secrets",
+		Args:  cmdutil.MaximumNArgs(0),
+		Short: "Rotate the data keys that encrypt the current stack's secrets",
+		Long: "Rotate the data keys that encrypt the current stack's secrets.\n" +
+			"\n" +
+			"A new data key is generated by the stack's secrets provider, and by each secrets provider that\n" +
+			"encrypts individual config keys, and every secret in the stack's configuration and latest\n" +
+			"checkpoint is re-encrypted with the new keys. A stack that uses a passphrase is given a new\n" +
+			"encryption salt, and may be given a new passphrase at the same time. The time of the rotation\n" +
+			"and the number of rotations so far are recorded in the stack's configuration file.\n" +
+			"\n" +
+			"The keys of stacks that use the Pulumi Service's secrets provider are managed by the service,\n" +
+			"and cannot be rotated with this command.",
+		Run: cmdutil.RunFunc(func(cmd *cobra.Command, args []string) error {
+			opts := display.Options{
+				Color: cmdutil.GetGlobalColorization(),
+			}
+
+			s, err := requireStack(stackName, false, opts, true /*setCurrent*/)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Rotating data keys and re-encrypting configuration and state\n")
+			return rotateSecrets(commandContext(), s)
+		}),
+	}
+	cmd.PersistentFlags().StringVarP(
+		&stackName, "stack", "s", "", "The name of the stack to operate on. Defaults to the current stack")
+
+	return cmd
+}
+
+// rotateSecrets gives the stack's secrets providers new data keys, re-encrypts the secrets in the stack's
+// configuration and latest checkpoint with the new keys, and records the rotation in the stack's configuration.
+func rotateSecrets(ctx context.Context, s backend.Stack) error {
+	ps, err := loadProjectStack(s)
+	if err != nil {
+		return err
+	}
+
+	// The old keys must be loaded before they are replaced.
+	var decrypter config.Decrypter = config.NewPanicCrypter()
+	if ps.Config.HasSecureValue() {
+		if decrypter, err = getStackDecrypter(s); err != nil {
+			return err
+		}
+	}
+
+	_, isFilestate := s.(filestate.Stack)
+	switch {
+	case ps.SecretsProvider != passphrase.Type && ps.SecretsProvider != "default" && ps.SecretsProvider != "":
+		url, err := resolveSecretsProvider(s.Ref().Name(), ps.SecretsProvider)
+		if err != nil {
+			return err
+		}
+		dataKey, err := cloud.GenerateNewDataKey(url)
+		if err != nil {
+			return err
+		}
+		ps.EncryptedKey = base64.StdEncoding.EncodeToString(dataKey)
+
+		// Fallback secrets providers are given copies of the new data key once it has been saved.
+		for i := range ps.FallbackProviders {
+			ps.FallbackProviders[i].EncryptedKey = ""
+		}
+	case ps.EncryptionSalt != "" || isFilestate:
+		// A new salt derives a new key from the passphrase. The passphrase secrets manager saves the salt itself.
+		if _, err := newPassphraseSecretsManager(s.Ref().Name(), stackConfigFile,
+			true /* rotatePassphraseSecretsProvider */); err != nil {
+			return err
+		}
+		if ps, err = loadProjectStack(s); err != nil {
+			return err
+		}
+	default:
+		return errors.Errorf("the secrets of stack %s are encrypted by the Pulumi Service, which manages its own keys",
+			s.Ref())
+	}
+
+	// Config keys that share a secrets provider continue to share a data key.
+	dataKeys := make(map[string]string)
+	for name, p := range ps.KeyProviders {
+		if _, ok := dataKeys[p.SecretsProvider]; !ok {
+			url, err := resolveSecretsProvider(s.Ref().Name(), p.SecretsProvider)
+			if err != nil {
+				return err
+			}
+			dataKey, err := cloud.GenerateNewDataKey(url)
+			if err != nil {
+				return errors.Wrapf(err, "rotating data key for config key %s", name)
+			}
+			dataKeys[p.SecretsProvider] = base64.StdEncoding.EncodeToString(dataKey)
+		}
+		p.EncryptedKey = dataKeys[p.SecretsProvider]
+		ps.KeyProviders[name] = p
+	}
+	if err = saveProjectStack(s, ps); err != nil {
+		return err
+	}
+
+	if err = shareDataKeyWithFallbacks(s); err != nil {
+		return err
+	}
+
+	newSecretsManager, err := getStackSecretsManager(s)
+	if err != nil {
+		return err
+	}
+	newConfig, err := reencryptConfig(ctx, ps, s.Ref().Name(), decrypter, newSecretsManager.Encrypter)
+	if err != nil {
+		return err
+	}
+
+	// Reload the project stack, as the fallbacks have been given their copies of the new key.
+	if ps, err = loadProjectStack(s); err != nil {
+		return err
+	}
+	for key, val := range newConfig {
+		if err := ps.Config.Set(key, val, false); err != nil {
+			return err
+		}
+	}
+	rotation := workspace.SecretsRotation{Time: time.Now().UTC().Format(time.RFC3339)}
+	if ps.SecretsRotation != nil {
+		rotation.Count = ps.SecretsRotation.Count
+	}
+	rotation.Count++
+	ps.SecretsRotation = &rotation
+	if err = saveProjectStack(s, ps); err != nil {
+		return err
+	}
+
+	return reencryptCheckpoint(ctx, s, newSecretsManager)
+}
+
+// reencryptConfig returns a copy of the configuration in ps in which every secret is decrypted with decrypter and
+// encrypted again, using the secrets provider of its config key if it has one, or the stack's encrypter otherwise.
+func reencryptConfig(ctx context.Context, ps *workspace.ProjectStack, stackName tokens.QName,
+	decrypter config.Decrypter, stackEncrypter func() (config.Encrypter, error)) (config.Map, error) {
+
+	// Group the configuration by the secrets provider that encrypts it; the stack's own provider is "".
+	groups := make(map[string]config.Map)
+	encrypters := make(map[string]config.Encrypter)
+	for key, val := range ps.Config {
+		provider := ""
+		if p, ok := ps.KeyProviders[key.String()]; ok {
+			provider = p.SecretsProvider
+			if _, ok := encrypters[provider]; !ok {
+				sm, err := newKeySecretsManager(stackName, p)
+				if err != nil {
+					return nil, errors.Wrapf(err, "creating secrets provider for config key %v", key)
+				}
+				if encrypters[provider], err = sm.Encrypter(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		if groups[provider] == nil {
+			groups[provider] = make(config.Map)
+		}
+		groups[provider][key] = val
+	}
+	if _, ok := groups[""]; ok {
+		enc, err := stackEncrypter()
+		if err != nil {
+			return nil, err
+		}
+		encrypters[""] = enc
+	}
+
+	result := make(config.Map, len(ps.Config))
+	for provider, m := range groups {
+		reencrypted, err := m.Reencrypt(ctx, decrypter, encrypters[provider])
+		if err != nil {
+			return nil, err
+		}
+		for key, val := range reencrypted {
+			result[key] = val
+		}
+	}
+	return result, nil
+}
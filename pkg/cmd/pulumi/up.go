@@ -105,6 +105,9 @@ func newUpCmd() *cobra.Command {
 		if err != nil {
 			return result.FromError(errors.Wrap(err, "getting stack configuration"))
 		}
+		if err = checkRequiredConfig(cfg.Config); err != nil {
+			return result.FromError(err)
+		}
 
 		targetURNs := []resource.URN{}
 		for _, t := range targets {
@@ -284,6 +287,9 @@ func newUpCmd() *cobra.Command {
 		if err != nil {
 			return result.FromError(errors.Wrap(err, "getting stack configuration"))
 		}
+		if err = checkRequiredConfig(cfg.Config); err != nil {
+			return result.FromError(err)
+		}
 
 		opts.Engine = engine.UpdateOptions{
 			LocalPolicyPacks: engine.MakeLocalPolicyPacks(policyPackPaths, policyPackConfigPaths),
@@ -417,7 +423,8 @@ func newUpCmd() *cobra.Command {
 		"Config keys contain a path to a property in a map or list to set")
 	cmd.PersistentFlags().StringVar(
 		&secretsProvider, "secrets-provider", "default", "The type of the provider that should be used to encrypt and "+
-			"decrypt secrets (possible choices: default, passphrase, awskms, azurekeyvault, gcpkms, hashivault). Only"+
+			"decrypt secrets (possible choices: default, passphrase, awskms, azurekeyvault, gcpkms, hashivault, vault, "+
+			"age, onepassword, pkcs11). Only"+
 			"used when creating a new stack from an existing template")
 
 	cmd.PersistentFlags().StringVar(
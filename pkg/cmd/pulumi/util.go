@@ -42,6 +42,7 @@ import (
 	"github.com/pulumi/pulumi/pkg/v2/backend/state"
 	"github.com/pulumi/pulumi/pkg/v2/engine"
 	"github.com/pulumi/pulumi/pkg/v2/resource/stack"
+	"github.com/pulumi/pulumi/pkg/v2/secrets/cloud"
 	"github.com/pulumi/pulumi/pkg/v2/secrets/passphrase"
 	"github.com/pulumi/pulumi/pkg/v2/util/cancel"
 	"github.com/pulumi/pulumi/pkg/v2/util/tracing"
@@ -176,8 +177,9 @@ func createSecretsManager(b backend.Backend, stackRef backend.StackReference, se
 		// uses a URL schema to identify the provider
 
 		// Azure KeyVault never used to require an algorithm and there's no real reason to require it,
-		// but if someone specifies one, don't clobber it.
-		if strings.HasPrefix(secretsProvider, "azurekeyvault://") {
+		// but if someone specifies one, don't clobber it. Templates are left as written, as they may not
+		// parse until they are expanded.
+		if strings.HasPrefix(secretsProvider, "azurekeyvault://") && !cloud.IsURLTemplate(secretsProvider) {
 			parsed, err := url.Parse(secretsProvider)
 			if err != nil {
 				return errors.Wrap(err, "failed to parse secrets provider URL")
@@ -112,6 +112,9 @@ func newWatchCmd() *cobra.Command {
 			if err != nil {
 				return result.FromError(errors.Wrap(err, "getting stack configuration"))
 			}
+			if err = checkRequiredConfig(cfg.Config); err != nil {
+				return result.FromError(err)
+			}
 
 			opts.Engine = engine.UpdateOptions{
 				LocalPolicyPacks:          engine.MakeLocalPolicyPacks(policyPackPaths, policyPackConfigPaths),
@@ -160,7 +163,8 @@ func newWatchCmd() *cobra.Command {
 		"Config keys contain a path to a property in a map or list to set")
 	cmd.PersistentFlags().StringVar(
 		&secretsProvider, "secrets-provider", "default", "The type of the provider that should be used to encrypt and "+
-			"decrypt secrets (possible choices: default, passphrase, awskms, azurekeyvault, gcpkms, hashivault). Only"+
+			"decrypt secrets (possible choices: default, passphrase, awskms, azurekeyvault, gcpkms, hashivault, vault, "+
+			"age, onepassword, pkcs11). Only"+
 			"used when creating a new stack from an existing template")
 
 	cmd.PersistentFlags().StringVarP(
@@ -206,7 +206,7 @@ func makeEventEmitter(events chan<- Event, update UpdateInfo) (eventEmitter, err
 	target := update.GetTarget()
 	var secrets []string
 	if target != nil && target.Config.HasSecureValue() {
-		for k, v := range target.Config {
+		for k, v := range target.Config.Map() {
 			if !v.Secure() {
 				continue
 			}
@@ -500,11 +500,11 @@ func (e *eventEmitter) resourcePreEvent(
 	})
 }
 
-func (e *eventEmitter) preludeEvent(isPreview bool, cfg config.Map) {
+func (e *eventEmitter) preludeEvent(isPreview bool, cfg config.FrozenMap) {
 	contract.Requiref(e != nil, "e", "!= nil")
 
-	configStringMap := make(map[string]string, len(cfg))
-	for k, v := range cfg {
+	configStringMap := make(map[string]string, cfg.Len())
+	for k, v := range cfg.Map() {
 		keyString := k.String()
 		valueString, err := v.Value(config.NewBlindingDecrypter())
 		contract.AssertNoError(err)
@@ -178,7 +178,7 @@ func (p *TestPlan) GetTarget(snapshot *deploy.Snapshot) deploy.Target {
 
 	return deploy.Target{
 		Name:      stack,
-		Config:    cfg,
+		Config:    cfg.Freeze(),
 		Decrypter: p.Decrypter,
 		Snapshot:  snapshot,
 	}
@@ -93,7 +93,7 @@ func (src *evalSource) Iterate(
 	tracingSpan := opentracing.SpanFromContext(ctx)
 
 	// Decrypt the configuration.
-	config, err := src.runinfo.Target.Config.Decrypt(src.runinfo.Target.Decrypter)
+	config, err := src.runinfo.Target.Config.DecryptAll(ctx, src.runinfo.Target.Decrypter)
 	if err != nil {
 		return nil, result.FromError(errors.Wrap(err, "failed to decrypt config"))
 	}
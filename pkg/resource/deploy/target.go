@@ -15,6 +15,8 @@
 package deploy
 
 import (
+	"strings"
+
 	"github.com/pulumi/pulumi/sdk/v2/go/common/resource"
 	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
 	"github.com/pulumi/pulumi/sdk/v2/go/common/tokens"
@@ -23,7 +25,7 @@ import (
 // Target represents information about a deployment target.
 type Target struct {
 	Name      tokens.QName     // the target stack name.
-	Config    config.Map       // optional configuration key/value pairs.
+	Config    config.FrozenMap // optional configuration key/value pairs, which may not be modified.
 	Decrypter config.Decrypter // decrypter for secret configuration values.
 	Snapshot  *Snapshot        // the last snapshot deployed to the target.
 }
@@ -35,10 +37,14 @@ func (t *Target) GetPackageConfig(pkg tokens.Package) (resource.PropertyMap, err
 		return result, nil
 	}
 
-	for k, c := range t.Config {
-		if tokens.Package(k.Namespace()) != pkg {
+	for k, c := range t.Config.Map() {
+		// The package is the first segment of the key's namespace. Any further segments (e.g. the `eks` of
+		// `aws:eks:clusterName`) are kept in the parameter's name.
+		segments := k.NamespaceSegments()
+		if tokens.Package(segments[0]) != pkg {
 			continue
 		}
+		name := strings.Join(append(segments[1:], k.Name()), ":")
 
 		v, err := c.Value(t.Decrypter)
 		if err != nil {
@@ -49,7 +55,7 @@ func (t *Target) GetPackageConfig(pkg tokens.Package) (resource.PropertyMap, err
 		if c.Secure() {
 			propertyValue = resource.MakeSecret(propertyValue)
 		}
-		result[resource.PropertyKey(k.Name())] = propertyValue
+		result[resource.PropertyKey(name)] = propertyValue
 	}
 	return result, nil
 }
@@ -0,0 +1,45 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package deploy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
+)
+
+func TestGetPackageConfig(t *testing.T) {
+	target := &Target{
+		Config: config.Map{
+			config.MustMakeKey("aws", "region"):          config.NewValue("us-west-2"),
+			config.MustMakeKey("aws:eks", "clusterName"): config.NewValue("prod"),
+			config.MustMakeKey("aws:eks", "token"):       config.NewSecureValue("sekret"),
+			config.MustMakeKey("awsx", "region"):         config.NewValue("us-east-1"),
+			config.MustMakeKey("gcp", "project"):         config.NewValue("my-project"),
+		}.Freeze(),
+		Decrypter: config.NewBlindingDecrypter(),
+	}
+
+	actual, err := target.GetPackageConfig("aws")
+	assert.NoError(t, err)
+	assert.Equal(t, resource.PropertyMap{
+		"region":          resource.NewStringProperty("us-west-2"),
+		"eks:clusterName": resource.NewStringProperty("prod"),
+		"eks:token":       resource.MakeSecret(resource.NewStringProperty("[secret]")),
+	}, actual)
+}
@@ -15,6 +15,7 @@
 package stack
 
 import (
+	"context"
 	"encoding/json"
 
 	"github.com/pkg/errors"
@@ -129,6 +130,13 @@ func (c *cachingCrypter) DecryptValue(ciphertext string) (string, error) {
 	return c.decrypter.DecryptValue(ciphertext)
 }
 
+// BulkDecrypt implements config.BulkDecrypter, so that wrapping a secrets manager in a caching secrets manager does not
+// hide its support for decrypting many values at once. Only encryptions are cached, so all of the ciphertexts are
+// passed to the underlying decrypter.
+func (c *cachingCrypter) BulkDecrypt(ctx context.Context, ciphertexts []string) (map[string]string, error) {
+	return config.DecryptValues(ctx, c.decrypter, ciphertexts)
+}
+
 // encryptSecret encrypts the plaintext associated with the given secret value.
 func (c *cachingCrypter) encryptSecret(secret *resource.Secret, plaintext string) (string, error) {
 	// If the cache has an entry for this secret and the plaintext has not changed, re-use the ciphertext.
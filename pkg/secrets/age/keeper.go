@@ -0,0 +1,218 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package age implements an `age://` secrets provider that encrypts to one or more age recipients, so that secrets
+// can be shared by a team without a cloud key management service.
+//
+// Recipients are given by the provider URL: its host may be a single age public key (`age1...`), and the `recipient`
+// query parameter, which may be repeated, may be an age public key or an SSH public key (`ssh-ed25519 ...` or
+// `ssh-rsa ...`). SSH keys must be percent-encoded. The `recipientsFile` query parameter names a file that holds one
+// recipient per line, in the format accepted by `age -R`. For example:
+//
+//	age://age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p?recipientsFile=team.txt
+//
+// Encrypting only requires recipients. Decrypting requires the identity of one of the recipients, which is read from
+// the files listed in $PULUMI_AGE_IDENTITY_FILE. Each may be an age identity file, as generated by `age-keygen`, or an
+// unencrypted SSH private key.
+package age
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"filippo.io/age"
+	"filippo.io/age/agessh"
+	"github.com/pkg/errors"
+	"gocloud.dev/gcerrors"
+	gosecrets "gocloud.dev/secrets"
+)
+
+// Scheme is the URL scheme of the age secrets provider.
+const Scheme = "age"
+
+// IdentityFileEnvVar is the environment variable that lists the files holding the identities used for decryption.
+// Multiple files are separated by the OS's path list separator.
+const IdentityFileEnvVar = "PULUMI_AGE_IDENTITY_FILE"
+
+func init() {
+	gosecrets.DefaultURLMux().RegisterKeeper(Scheme, &urlOpener{})
+}
+
+// ParseRecipients returns the recipients named by an `age://` provider URL.
+func ParseRecipients(u *url.URL) ([]age.Recipient, error) {
+	if u.Scheme != Scheme {
+		return nil, errors.Errorf("unsupported scheme %q; expected %q", u.Scheme, Scheme)
+	}
+
+	var recipients []age.Recipient
+	if u.Host != "" {
+		r, err := parseRecipient(u.Host)
+		if err != nil {
+			return nil, err
+		}
+		recipients = append(recipients, r)
+	}
+	for param, values := range u.Query() {
+		for _, value := range values {
+			switch param {
+			case "recipient":
+				r, err := parseRecipient(value)
+				if err != nil {
+					return nil, err
+				}
+				recipients = append(recipients, r)
+			case "recipientsFile":
+				rs, err := readRecipientsFile(value)
+				if err != nil {
+					return nil, err
+				}
+				recipients = append(recipients, rs...)
+			default:
+				return nil, errors.Errorf("invalid query parameter %q", param)
+			}
+		}
+	}
+
+	if len(recipients) == 0 {
+		return nil, errors.Errorf("%v does not name any recipients", u)
+	}
+	return recipients, nil
+}
+
+func parseRecipient(s string) (age.Recipient, error) {
+	switch {
+	case strings.HasPrefix(s, "age1"):
+		return age.ParseX25519Recipient(s)
+	case strings.HasPrefix(s, "ssh-"):
+		return agessh.ParseRecipient(s)
+	default:
+		return nil, errors.Errorf("unknown recipient type %q", s)
+	}
+}
+
+func readRecipientsFile(path string) ([]age.Recipient, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading recipients file")
+	}
+
+	var recipients []age.Recipient
+	for i, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r, err := parseRecipient(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s:%d", path, i+1)
+		}
+		recipients = append(recipients, r)
+	}
+	return recipients, nil
+}
+
+// LoadIdentities returns the identities held by the files listed in $PULUMI_AGE_IDENTITY_FILE.
+func LoadIdentities() ([]age.Identity, error) {
+	paths := os.Getenv(IdentityFileEnvVar)
+	if paths == "" {
+		return nil, errors.Errorf("%s must be set to decrypt secrets with age", IdentityFileEnvVar)
+	}
+
+	var identities []age.Identity
+	for _, path := range filepath.SplitList(paths) {
+		ids, err := readIdentityFile(path)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, ids...)
+	}
+	return identities, nil
+}
+
+func readIdentityFile(path string) ([]age.Identity, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "reading identity file")
+	}
+
+	// SSH private keys are PEM-encoded; anything else is treated as an age identity file.
+	if bytes.HasPrefix(bytes.TrimSpace(b), []byte("-----BEGIN")) {
+		id, err := agessh.ParseIdentity(b)
+		if err != nil {
+			return nil, errors.Wrapf(err, "parsing SSH identity %s", path)
+		}
+		return []age.Identity{id}, nil
+	}
+
+	ids, err := age.ParseIdentities(bytes.NewReader(b))
+	if err != nil {
+		return nil, errors.Wrapf(err, "parsing identity file %s", path)
+	}
+	return ids, nil
+}
+
+// urlOpener opens `age://` keepers for gocloud.dev/secrets, so that the provider can be used anywhere a cloud secrets
+// provider can.
+type urlOpener struct{}
+
+func (o *urlOpener) OpenKeeperURL(ctx context.Context, u *url.URL) (*gosecrets.Keeper, error) {
+	recipients, err := ParseRecipients(u)
+	if err != nil {
+		return nil, fmt.Errorf("open keeper %v: %v", u, err)
+	}
+	return gosecrets.NewKeeper(&keeper{recipients: recipients}), nil
+}
+
+// keeper implements gocloud.dev/secrets/driver.Keeper. Identities are only loaded when decrypting, so that
+// encrypting to a team's recipients does not require a private key.
+type keeper struct {
+	recipients []age.Recipient
+}
+
+func (k *keeper) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	w, err := age.Encrypt(&buf, k.recipients...)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = w.Write(plaintext); err != nil {
+		return nil, err
+	}
+	if err = w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (k *keeper) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	identities, err := LoadIdentities()
+	if err != nil {
+		return nil, err
+	}
+	r, err := age.Decrypt(bytes.NewReader(ciphertext), identities...)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
+
+func (k *keeper) Close() error                           { return nil }
+func (k *keeper) ErrorAs(err error, i interface{}) bool  { return false }
+func (k *keeper) ErrorCode(err error) gcerrors.ErrorCode { return gcerrors.Unknown }
@@ -0,0 +1,132 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package age
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/assert"
+	gosecrets "gocloud.dev/secrets"
+	"golang.org/x/crypto/ssh"
+)
+
+// setenv sets an environment variable for the duration of a test.
+func setenv(t *testing.T, key, value string) {
+	old, had := os.LookupEnv(key)
+	t.Cleanup(func() {
+		if had {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+	os.Setenv(key, value)
+}
+
+func writeFile(t *testing.T, dir, name, contents string) string {
+	path := filepath.Join(dir, name)
+	assert.NoError(t, ioutil.WriteFile(path, []byte(contents), 0600))
+	return path
+}
+
+func TestAgeKeeper(t *testing.T) {
+	dir, err := ioutil.TempDir("", "age-keeper")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	alice, err := age.GenerateX25519Identity()
+	assert.NoError(t, err)
+	bob, err := age.GenerateX25519Identity()
+	assert.NoError(t, err)
+
+	// Carol uses an SSH key.
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	sshPub, err := ssh.NewPublicKey(&priv.PublicKey)
+	assert.NoError(t, err)
+	carolKey := writeFile(t, dir, "id_rsa", string(pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(priv),
+	})))
+
+	recipients := writeFile(t, dir, "team.txt",
+		"# the team\n"+bob.Recipient().String()+"\n\n"+string(ssh.MarshalAuthorizedKey(sshPub)))
+	u := "age://" + alice.Recipient().String() + "?recipientsFile=" + url.QueryEscape(recipients)
+
+	// Encrypting requires no identity.
+	setenv(t, IdentityFileEnvVar, "")
+	ctx := context.Background()
+	keeper, err := gosecrets.OpenKeeper(ctx, u)
+	assert.NoError(t, err)
+	ciphertext, err := keeper.Encrypt(ctx, []byte("hunter2"))
+	assert.NoError(t, err)
+
+	_, err = keeper.Decrypt(ctx, ciphertext)
+	assert.Error(t, err)
+
+	// Any recipient can decrypt.
+	for _, identity := range []string{
+		writeFile(t, dir, "alice.txt", alice.String()+"\n"),
+		writeFile(t, dir, "bob.txt", "# bob\n"+bob.String()+"\n"),
+		carolKey,
+	} {
+		setenv(t, IdentityFileEnvVar, identity)
+		plaintext, err := keeper.Decrypt(ctx, ciphertext)
+		assert.NoError(t, err)
+		assert.Equal(t, "hunter2", string(plaintext))
+	}
+
+	// Others cannot.
+	mallory, err := age.GenerateX25519Identity()
+	assert.NoError(t, err)
+	setenv(t, IdentityFileEnvVar, writeFile(t, dir, "mallory.txt", mallory.String()))
+	_, err = keeper.Decrypt(ctx, ciphertext)
+	assert.Error(t, err)
+}
+
+func TestParseRecipients(t *testing.T) {
+	id, err := age.GenerateX25519Identity()
+	assert.NoError(t, err)
+	r := id.Recipient().String()
+
+	parse := func(s string) ([]age.Recipient, error) {
+		u, err := url.Parse(s)
+		assert.NoError(t, err)
+		return ParseRecipients(u)
+	}
+
+	rs, err := parse("age://" + r + "?recipient=" + r)
+	assert.NoError(t, err)
+	assert.Len(t, rs, 2)
+
+	_, err = parse("age://")
+	assert.Error(t, err)
+	_, err = parse("age://notakey")
+	assert.EqualError(t, err, `unknown recipient type "notakey"`)
+	_, err = parse("age://" + r + "?region=us-east-1")
+	assert.EqualError(t, err, `invalid query parameter "region"`)
+	_, err = parse("age://?recipientsFile=does-not-exist.txt")
+	assert.Error(t, err)
+}
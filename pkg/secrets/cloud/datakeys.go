@@ -0,0 +1,48 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloud
+
+import (
+	"sync"
+)
+
+// dataKeyID identifies a data key by the URL of the key management service that encrypted it and its ciphertext.
+type dataKeyID struct {
+	url          string
+	encryptedKey string
+}
+
+// dataKeys holds the plaintexts of the data keys that this process has generated or decrypted. The secrets managers
+// of a stack all share its data key, so caching it means that a command calls its key management service once,
+// rather than once for every secrets manager it creates.
+var dataKeys = struct {
+	sync.Mutex
+	m map[dataKeyID][]byte
+}{m: make(map[dataKeyID][]byte)}
+
+// cachedDataKey returns the plaintext of the data key encryptedKey, if it has already been generated or decrypted.
+func cachedDataKey(url string, encryptedKey []byte) ([]byte, bool) {
+	dataKeys.Lock()
+	defer dataKeys.Unlock()
+	plaintext, ok := dataKeys.m[dataKeyID{url: url, encryptedKey: string(encryptedKey)}]
+	return plaintext, ok
+}
+
+// cacheDataKey records that encryptedKey is the ciphertext of plaintext, as encrypted by the service at url.
+func cacheDataKey(url string, encryptedKey, plaintext []byte) {
+	dataKeys.Lock()
+	defer dataKeys.Unlock()
+	dataKeys.m[dataKeyID{url: url, encryptedKey: string(encryptedKey)}] = plaintext
+}
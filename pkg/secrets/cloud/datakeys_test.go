@@ -0,0 +1,96 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloud
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gocloud.dev/gcerrors"
+	gosecrets "gocloud.dev/secrets"
+)
+
+// countingKeeper "encrypts" by reversing its input, counting its calls.
+type countingKeeper struct {
+	encrypts, decrypts int
+}
+
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
+
+func (k *countingKeeper) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	k.encrypts++
+	return reverse(plaintext), nil
+}
+
+func (k *countingKeeper) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	k.decrypts++
+	return reverse(ciphertext), nil
+}
+
+func (k *countingKeeper) Close() error                           { return nil }
+func (k *countingKeeper) ErrorAs(err error, i interface{}) bool  { return false }
+func (k *countingKeeper) ErrorCode(err error) gcerrors.ErrorCode { return gcerrors.Unknown }
+
+func (k *countingKeeper) OpenKeeperURL(ctx context.Context, u *url.URL) (*gosecrets.Keeper, error) {
+	return gosecrets.NewKeeper(k), nil
+}
+
+func TestDataKeysAreCached(t *testing.T) {
+	k := &countingKeeper{}
+	gosecrets.DefaultURLMux().RegisterKeeper("counting", k)
+
+	// The data key is known from the moment it is generated.
+	dataKey, err := GenerateNewDataKey("counting://a")
+	assert.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = NewCloudSecretsManager("counting://a", dataKey)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, 1, k.encrypts)
+	assert.Equal(t, 0, k.decrypts)
+
+	// A data key generated elsewhere is decrypted once.
+	other := reverse([]byte("0123456789abcdef0123456789abcdef"))
+	for i := 0; i < 3; i++ {
+		sm, err := NewCloudSecretsManager("counting://a", other)
+		assert.NoError(t, err)
+		assert.Equal(t, other, sm.EncryptedKey())
+	}
+	assert.Equal(t, 1, k.decrypts)
+
+	// The cache is keyed by URL as well as ciphertext.
+	_, err = NewCloudSecretsManager("counting://b", other)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, k.decrypts)
+
+	// Managers created from the same data key can read each other's secrets.
+	a, err := NewCloudSecretsManager("counting://a", other)
+	assert.NoError(t, err)
+	b, err := NewCloudSecretsManager("counting://b", other)
+	assert.NoError(t, err)
+	ciphertext, err := a.crypter.EncryptValue("hunter2")
+	assert.NoError(t, err)
+	plaintext, err := b.crypter.DecryptValue(ciphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", plaintext)
+}
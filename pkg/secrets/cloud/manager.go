@@ -28,6 +28,10 @@ import (
 	_ "gocloud.dev/secrets/hashivault"    // support for hashivault://
 
 	"github.com/pulumi/pulumi/pkg/v2/secrets"
+	_ "github.com/pulumi/pulumi/pkg/v2/secrets/age"         // support for age://
+	_ "github.com/pulumi/pulumi/pkg/v2/secrets/onepassword" // support for onepassword://
+	_ "github.com/pulumi/pulumi/pkg/v2/secrets/pkcs11"      // support for pkcs11://
+	_ "github.com/pulumi/pulumi/pkg/v2/secrets/vault"       // support for vault://
 	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
 )
 
@@ -59,27 +63,38 @@ func GenerateNewDataKey(url string) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	keeper, err := gosecrets.OpenKeeper(context.Background(), url)
-	if err != nil {
+	if err = registerPluginScheme(url); err != nil {
 		return nil, err
 	}
-	return keeper.Encrypt(context.Background(), plaintextDataKey)
-}
-
-// NewCloudSecretsManager returns a secrets manager that uses the target cloud key management
-// service to encrypt/decrypt a data key used for envelope encryption of secrets values.
-func NewCloudSecretsManager(url string, encryptedDataKey []byte) (*Manager, error) {
 	keeper, err := gosecrets.OpenKeeper(context.Background(), url)
 	if err != nil {
 		return nil, err
 	}
-	plaintextDataKey, err := keeper.Decrypt(context.Background(), encryptedDataKey)
+	encryptedDataKey, err := keeper.Encrypt(context.Background(), plaintextDataKey)
 	if err != nil {
 		return nil, err
 	}
+	cacheDataKey(url, encryptedDataKey, plaintextDataKey)
+	return encryptedDataKey, nil
+}
+
+// NewCloudSecretsManager returns a secrets manager that uses the target cloud key management
+// service to encrypt/decrypt a data key used for envelope encryption of secrets values. The key
+// management service is only called to decrypt the data key, and only the first time this process
+// sees it; secrets values are encrypted with the data key locally.
+func NewCloudSecretsManager(url string, encryptedDataKey []byte) (*Manager, error) {
+	plaintextDataKey, ok := cachedDataKey(url, encryptedDataKey)
+	if !ok {
+		var err error
+		if plaintextDataKey, err = decryptDataKey(url, encryptedDataKey); err != nil {
+			return nil, err
+		}
+		cacheDataKey(url, encryptedDataKey, plaintextDataKey)
+	}
 	crypter := config.NewSymmetricCrypter(plaintextDataKey)
 	return &Manager{
 		crypter: crypter,
+		dataKey: plaintextDataKey,
 		state: cloudSecretsManagerState{
 			URL:          url,
 			EncryptedKey: encryptedDataKey,
@@ -87,10 +102,23 @@ func NewCloudSecretsManager(url string, encryptedDataKey []byte) (*Manager, erro
 	}, nil
 }
 
+// decryptDataKey decrypts a data key using the key management service at url.
+func decryptDataKey(url string, encryptedDataKey []byte) ([]byte, error) {
+	if err := registerPluginScheme(url); err != nil {
+		return nil, err
+	}
+	keeper, err := gosecrets.OpenKeeper(context.Background(), url)
+	if err != nil {
+		return nil, err
+	}
+	return keeper.Decrypt(context.Background(), encryptedDataKey)
+}
+
 // Manager is the secrets.Manager implementation for cloud key management services
 type Manager struct {
 	state   cloudSecretsManagerState
 	crypter config.Crypter
+	dataKey []byte
 }
 
 func (m *Manager) Type() string                         { return Type }
@@ -98,3 +126,22 @@ func (m *Manager) State() interface{}                   { return m.state }
 func (m *Manager) Encrypter() (config.Encrypter, error) { return m.crypter, nil }
 func (m *Manager) Decrypter() (config.Decrypter, error) { return m.crypter, nil }
 func (m *Manager) EncryptedKey() []byte                 { return m.state.EncryptedKey }
+
+// WrapDataKey encrypts the manager's data key using the cloud key management service at url. A manager created from
+// the result can decrypt the secrets encrypted by this manager, so it can stand in for it if this manager's service is
+// unavailable.
+func (m *Manager) WrapDataKey(url string) ([]byte, error) {
+	if err := registerPluginScheme(url); err != nil {
+		return nil, err
+	}
+	keeper, err := gosecrets.OpenKeeper(context.Background(), url)
+	if err != nil {
+		return nil, err
+	}
+	encryptedDataKey, err := keeper.Encrypt(context.Background(), m.dataKey)
+	if err != nil {
+		return nil, err
+	}
+	cacheDataKey(url, encryptedDataKey, m.dataKey)
+	return encryptedDataKey, nil
+}
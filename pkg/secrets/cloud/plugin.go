@@ -0,0 +1,113 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloud
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+	"gocloud.dev/gcerrors"
+	gosecrets "gocloud.dev/secrets"
+
+	"github.com/pulumi/pulumi/sdk/v2/go/common/diag"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/diag/colors"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/plugin"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/workspace"
+)
+
+var pluginSchemesMu sync.Mutex
+
+// HasSecretsPlugin returns true if a secrets provider plugin is installed for the given URL scheme.
+func HasSecretsPlugin(scheme string) bool {
+	_, path, err := workspace.GetPluginPath(workspace.SecretsPlugin, scheme, nil)
+	return err == nil && path != ""
+}
+
+// registerPluginScheme ensures that secrets provider URLs with the same scheme as rawurl can be opened. Schemes that
+// are not built in are served by the secrets provider plugin for the scheme, `pulumi-secrets-<scheme>`, if one is
+// installed.
+func registerPluginScheme(rawurl string) error {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return errors.Wrapf(err, "invalid secrets provider URL %q", rawurl)
+	}
+
+	pluginSchemesMu.Lock()
+	defer pluginSchemesMu.Unlock()
+	mux := gosecrets.DefaultURLMux()
+	if u.Scheme == "" || mux.ValidKeeperScheme(u.Scheme) || !HasSecretsPlugin(u.Scheme) {
+		// Leave OpenKeeper to report unknown schemes.
+		return nil
+	}
+	mux.RegisterKeeper(u.Scheme, pluginOpener{})
+	return nil
+}
+
+// pluginOpener opens keepers that are backed by a secrets provider plugin.
+type pluginOpener struct{}
+
+func (pluginOpener) OpenKeeperURL(ctx context.Context, u *url.URL) (*gosecrets.Keeper, error) {
+	return gosecrets.NewKeeper(&pluginKeeper{url: u.String(), scheme: u.Scheme}), nil
+}
+
+// pluginKeeper implements gocloud.dev/secrets/driver.Keeper with a secrets provider plugin. Data keys are rarely
+// encrypted or decrypted, so the plugin is launched for each operation rather than kept alive for the life of the
+// process.
+type pluginKeeper struct {
+	url    string
+	scheme string
+}
+
+func (k *pluginKeeper) withProvider(f func(p plugin.SecretsProvider) error) error {
+	pwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	sink := diag.DefaultSink(os.Stdout, os.Stderr, diag.FormatOptions{Color: colors.Never})
+	p, err := plugin.NewSecretsProvider(&plugin.Context{Diag: sink, StatusDiag: sink, Pwd: pwd}, k.scheme)
+	if err != nil {
+		return err
+	}
+	defer contract.IgnoreClose(p)
+	return f(p)
+}
+
+func (k *pluginKeeper) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	var ciphertext []byte
+	err := k.withProvider(func(p plugin.SecretsProvider) error {
+		ct, err := p.Encrypt(ctx, k.url, plaintext)
+		ciphertext = ct
+		return err
+	})
+	return ciphertext, err
+}
+
+func (k *pluginKeeper) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	var plaintext []byte
+	err := k.withProvider(func(p plugin.SecretsProvider) error {
+		pt, err := p.Decrypt(ctx, k.url, ciphertext)
+		plaintext = pt
+		return err
+	})
+	return plaintext, err
+}
+
+func (k *pluginKeeper) Close() error                           { return nil }
+func (k *pluginKeeper) ErrorAs(err error, i interface{}) bool  { return false }
+func (k *pluginKeeper) ErrorCode(err error) gcerrors.ErrorCode { return gcerrors.Unknown }
@@ -0,0 +1,70 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloud
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretsPlugin(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipped in short mode: builds a plugin")
+	}
+
+	dir, err := ioutil.TempDir("", "secrets-plugin")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	build := exec.Command("go", "build", "-o", filepath.Join(dir, "pulumi-secrets-xor"), "./testdata/pulumi-secrets-xor")
+	out, err := build.CombinedOutput()
+	if !assert.NoError(t, err, string(out)) {
+		return
+	}
+
+	path := os.Getenv("PATH")
+	defer os.Setenv("PATH", path)
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+path)
+
+	assert.True(t, HasSecretsPlugin("xor"))
+	assert.False(t, HasSecretsPlugin("rot13"))
+
+	url := "xor://mykey"
+	dataKey, err := GenerateNewDataKey(url)
+	assert.NoError(t, err)
+	assert.Len(t, dataKey, 32)
+
+	sm, err := NewCloudSecretsManager(url, dataKey)
+	assert.NoError(t, err)
+	enc, err := sm.Encrypter()
+	assert.NoError(t, err)
+	ciphertext, err := enc.EncryptValue("hunter2")
+	assert.NoError(t, err)
+	dec, err := sm.Decrypter()
+	assert.NoError(t, err)
+	plaintext, err := dec.DecryptValue(ciphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", plaintext)
+
+	_, err = NewCloudSecretsManager(url, nil)
+	assert.Error(t, err)
+	_, err = NewCloudSecretsManager("rot13://mykey", dataKey)
+	assert.Error(t, err)
+}
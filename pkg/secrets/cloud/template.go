@@ -0,0 +1,69 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloud
+
+import (
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// envVarPrefix marks a reference to an environment variable in a secrets provider URL template.
+const envVarPrefix = "env:"
+
+// IsURLTemplate returns true if url refers to any variables that must be expanded by ExpandURL.
+func IsURLTemplate(url string) bool {
+	return strings.Contains(url, "${")
+}
+
+// ExpandURL expands the references in a secrets provider URL template, so that many stacks can share a single
+// provider URL, e.g. `awskms://alias/pulumi-${stack}`. `${name}` is replaced by the value of name in vars, and
+// `${env:NAME}` by the value of the environment variable NAME. It is an error to refer to a variable that is not in
+// vars, or to an environment variable that is unset or empty.
+func ExpandURL(template string, vars map[string]string) (string, error) {
+	var b strings.Builder
+	rest := template
+	for {
+		start := strings.Index(rest, "${")
+		if start == -1 {
+			b.WriteString(rest)
+			return b.String(), nil
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end == -1 {
+			return "", errors.Errorf("invalid secrets provider URL %q: unterminated reference", template)
+		}
+		name := rest[start+2 : start+end]
+
+		var value string
+		if strings.HasPrefix(name, envVarPrefix) {
+			env := strings.TrimPrefix(name, envVarPrefix)
+			if value = os.Getenv(env); value == "" {
+				return "", errors.Errorf("secrets provider URL %q refers to %s, which is not set", template, env)
+			}
+		} else {
+			v, ok := vars[name]
+			if !ok {
+				return "", errors.Errorf("secrets provider URL %q refers to unknown variable %q", template, name)
+			}
+			value = v
+		}
+
+		b.WriteString(rest[:start])
+		b.WriteString(value)
+		rest = rest[start+end+1:]
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cloud
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandURL(t *testing.T) {
+	old, had := os.LookupEnv("PULUMI_TEST_KMS_REGION")
+	defer func() {
+		if had {
+			os.Setenv("PULUMI_TEST_KMS_REGION", old)
+		} else {
+			os.Unsetenv("PULUMI_TEST_KMS_REGION")
+		}
+	}()
+	os.Setenv("PULUMI_TEST_KMS_REGION", "us-west-2")
+	os.Unsetenv("PULUMI_TEST_KMS_UNSET")
+
+	vars := map[string]string{"stack": "dev", "project": "website"}
+	tests := []struct {
+		template string
+		expected string
+		err      bool
+	}{
+		{template: "awskms://alias/pulumi", expected: "awskms://alias/pulumi"},
+		{template: "awskms://alias/pulumi-${stack}", expected: "awskms://alias/pulumi-dev"},
+		{template: "awskms://alias/${project}-${stack}", expected: "awskms://alias/website-dev"},
+		{
+			template: "awskms://alias/pulumi-${stack}?region=${env:PULUMI_TEST_KMS_REGION}",
+			expected: "awskms://alias/pulumi-dev?region=us-west-2",
+		},
+		{template: "awskms://alias/$stack", expected: "awskms://alias/$stack"},
+		{template: "awskms://alias/pulumi-${organization}", err: true},
+		{template: "awskms://alias/pulumi-${env:PULUMI_TEST_KMS_UNSET}", err: true},
+		{template: "awskms://alias/pulumi-${stack", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			assert.Equal(t, tt.expected != tt.template || tt.err, IsURLTemplate(tt.template))
+			actual, err := ExpandURL(tt.template, vars)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+}
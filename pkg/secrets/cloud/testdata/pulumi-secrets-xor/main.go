@@ -0,0 +1,76 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// pulumi-secrets-xor is a secrets provider plugin for tests. It "encrypts" by XORing each byte of a plaintext with
+// the length of the secrets provider URL.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/plugin"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/rpcutil"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/workspace"
+	pulumirpc "github.com/pulumi/pulumi/sdk/v2/proto/go"
+)
+
+type xorProvider struct{}
+
+func (xorProvider) Close() error { return nil }
+
+func (xorProvider) Encrypt(ctx context.Context, url string, plaintext []byte) ([]byte, error) {
+	return xor(url, plaintext), nil
+}
+
+func (xorProvider) Decrypt(ctx context.Context, url string, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) == 0 {
+		return nil, errors.New("empty ciphertext")
+	}
+	return xor(url, ciphertext), nil
+}
+
+func (xorProvider) GetPluginInfo() (workspace.PluginInfo, error) {
+	return workspace.PluginInfo{}, nil
+}
+
+func xor(url string, b []byte) []byte {
+	result := make([]byte, len(b))
+	for i := range b {
+		result[i] = b[i] ^ byte(len(url))
+	}
+	return result
+}
+
+func main() {
+	port, done, err := rpcutil.Serve(0, nil, []func(*grpc.Server) error{
+		func(srv *grpc.Server) error {
+			pulumirpc.RegisterSecretsProviderServer(srv, plugin.NewSecretsProviderServer(xorProvider{}))
+			return nil
+		},
+	}, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "fatal: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("%d\n", port)
+	if err := <-done; err != nil {
+		fmt.Fprintf(os.Stderr, "fatal: %v\n", err)
+		os.Exit(1)
+	}
+}
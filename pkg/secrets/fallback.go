@@ -0,0 +1,72 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package secrets
+
+import (
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
+)
+
+// NewFallbackManager returns a Manager that encrypts with primary, and decrypts with primary and then each of
+// fallbacks in turn. primary may be nil if the stack's own secrets provider is unavailable, in which case secrets are
+// decrypted by the fallbacks alone and Encrypter returns unavailable, the error that made the primary unavailable.
+func NewFallbackManager(primary Manager, unavailable error, fallbacks ...Manager) Manager {
+	contract.Assert(primary != nil || (unavailable != nil && len(fallbacks) > 0))
+	return &fallbackManager{primary: primary, unavailable: unavailable, fallbacks: fallbacks}
+}
+
+type fallbackManager struct {
+	primary     Manager
+	unavailable error
+	fallbacks   []Manager
+}
+
+// first returns the manager that describes the stack's secrets: the primary if it is available, or otherwise the
+// first of the fallbacks.
+func (m *fallbackManager) first() Manager {
+	if m.primary != nil {
+		return m.primary
+	}
+	return m.fallbacks[0]
+}
+
+func (m *fallbackManager) Type() string       { return m.first().Type() }
+func (m *fallbackManager) State() interface{} { return m.first().State() }
+
+func (m *fallbackManager) Encrypter() (config.Encrypter, error) {
+	if m.primary == nil {
+		return nil, m.unavailable
+	}
+	return m.primary.Encrypter()
+}
+
+func (m *fallbackManager) Decrypter() (config.Decrypter, error) {
+	var decrypters []config.Decrypter
+	if m.primary != nil {
+		dec, err := m.primary.Decrypter()
+		if err != nil {
+			return nil, err
+		}
+		decrypters = append(decrypters, dec)
+	}
+	for _, fallback := range m.fallbacks {
+		dec, err := fallback.Decrypter()
+		if err != nil {
+			return nil, err
+		}
+		decrypters = append(decrypters, dec)
+	}
+	return config.NewFallbackDecrypter(decrypters...), nil
+}
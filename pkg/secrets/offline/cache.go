@@ -0,0 +1,242 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package offline implements an encrypted, on-disk cache of decrypted secrets. Secrets that have been decrypted once
+// can be read from the cache while their secrets provider is unreachable, e.g. during a key management service outage
+// or when working without a network connection.
+package offline
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
+)
+
+const (
+	// PassphraseEnvVar is the environment variable that holds the passphrase protecting the cache. The cache is only
+	// used if it is set.
+	PassphraseEnvVar = "PULUMI_SECRET_CACHE_PASSPHRASE"
+	// TTLEnvVar is the environment variable that overrides how long a cached secret may be used, e.g. "8h".
+	TTLEnvVar = "PULUMI_SECRET_CACHE_TTL"
+	// DefaultTTL is how long a cached secret may be used by default.
+	DefaultTTL = 24 * time.Hour
+)
+
+// ErrIncorrectPassphrase is returned when a cache cannot be opened with the given passphrase.
+var ErrIncorrectPassphrase = errors.New("incorrect secret cache passphrase")
+
+// Cache maps the ciphertexts of secrets to their plaintexts. Each entry expires once the cache's TTL has passed since
+// the secret was last decrypted by its secrets provider. Entries are keyed by a hash of their ciphertext, and the file
+// holding them is encrypted with a key derived from the cache's passphrase.
+type Cache struct {
+	path       string
+	passphrase string
+	salt       []byte
+	crypter    config.Crypter
+	ttl        time.Duration
+
+	m       sync.Mutex
+	entries map[string]entry
+	removed map[string]bool // the entries removed since the cache was last saved, which saving must not restore.
+	dirty   bool
+}
+
+type entry struct {
+	Plaintext string    `json:"plaintext"`
+	Decrypted time.Time `json:"decrypted"`
+}
+
+type cacheFile struct {
+	// Salt is the base64-encoded salt from which the cache's key is derived.
+	Salt string `json:"salt"`
+	// Entries is the JSON encoding of the cache's entries, encrypted with the cache's key.
+	Entries string `json:"entries"`
+}
+
+// Open opens the cache stored at path, creating it if it does not exist. Cached secrets expire after ttl.
+func Open(path, passphrase string, ttl time.Duration) (*Cache, error) {
+	c := &Cache{path: path, passphrase: passphrase, ttl: ttl, removed: make(map[string]bool)}
+
+	salt, crypter, entries, err := readCacheFile(path, passphrase)
+	switch {
+	case os.IsNotExist(err):
+		c.salt = make([]byte, 16)
+		if _, err = rand.Read(c.salt); err != nil {
+			return nil, err
+		}
+		c.crypter = config.NewSymmetricCrypterFromPassphrase(passphrase, c.salt)
+		c.entries = make(map[string]entry)
+		return c, nil
+	case err != nil:
+		return nil, err
+	}
+	c.salt, c.crypter, c.entries = salt, crypter, entries
+
+	// Expired entries are dropped the next time the cache is saved.
+	for k, e := range c.entries {
+		if c.expired(e) {
+			delete(c.entries, k)
+			c.dirty = true
+		}
+	}
+	return c, nil
+}
+
+// readCacheFile reads the cache stored at path, returning its salt, the crypter for its key and its entries. If there
+// is no cache at path, the error satisfies os.IsNotExist.
+func readCacheFile(path, passphrase string) ([]byte, config.Crypter, map[string]entry, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil, nil, err
+	} else if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "reading secret cache")
+	}
+
+	var f cacheFile
+	if err = json.Unmarshal(b, &f); err != nil {
+		return nil, nil, nil, errors.Wrap(err, "reading secret cache")
+	}
+	salt, err := base64.StdEncoding.DecodeString(f.Salt)
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "reading secret cache")
+	}
+	crypter := config.NewSymmetricCrypterFromPassphrase(passphrase, salt)
+	plaintext, err := crypter.DecryptValue(f.Entries)
+	if err != nil {
+		return nil, nil, nil, ErrIncorrectPassphrase
+	}
+	entries := make(map[string]entry)
+	if err = json.Unmarshal([]byte(plaintext), &entries); err != nil {
+		return nil, nil, nil, errors.Wrap(err, "reading secret cache")
+	}
+	return salt, crypter, entries, nil
+}
+
+// Clear removes the cache stored at path, revoking every secret in it. No passphrase is needed, so a cache whose
+// passphrase has been lost can still be removed.
+func Clear(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (c *Cache) expired(e entry) bool {
+	return !time.Now().Before(e.Decrypted.Add(c.ttl))
+}
+
+func hash(ciphertext string) string {
+	sum := sha256.Sum256([]byte(ciphertext))
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns the plaintext of ciphertext, if it is cached and has not expired.
+func (c *Cache) Get(ciphertext string) (string, bool) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	e, ok := c.entries[hash(ciphertext)]
+	if !ok || c.expired(e) {
+		return "", false
+	}
+	return e.Plaintext, true
+}
+
+// Put caches the plaintext of ciphertext, renewing its expiry if it is already cached.
+func (c *Cache) Put(ciphertext, plaintext string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	h := hash(ciphertext)
+	c.entries[h] = entry{Plaintext: plaintext, Decrypted: time.Now()}
+	delete(c.removed, h)
+	c.dirty = true
+}
+
+// Remove removes ciphertext's plaintext from the cache, e.g. because its secrets provider has refused to decrypt it.
+func (c *Cache) Remove(ciphertext string) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	h := hash(ciphertext)
+	delete(c.entries, h)
+	c.removed[h] = true
+	c.dirty = true
+}
+
+// Save writes the cache to disk if it has changed since it was opened or last saved. The entries that other processes
+// have saved in the meantime are kept, unless this cache has newer entries for the same secrets or has removed them.
+func (c *Cache) Save() error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if !c.dirty {
+		return nil
+	}
+
+	// A cache that can no longer be read with this cache's passphrase is replaced.
+	if salt, crypter, entries, err := readCacheFile(c.path, c.passphrase); err == nil {
+		c.salt, c.crypter = salt, crypter
+		for h, e := range entries {
+			if cur, ok := c.entries[h]; c.removed[h] || c.expired(e) || ok && !cur.Decrypted.Before(e.Decrypted) {
+				continue
+			}
+			c.entries[h] = e
+		}
+	}
+
+	entries, err := json.Marshal(c.entries)
+	if err != nil {
+		return err
+	}
+	ciphertext, err := c.crypter.EncryptValue(string(entries))
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(cacheFile{Salt: base64.StdEncoding.EncodeToString(c.salt), Entries: ciphertext})
+	if err != nil {
+		return err
+	}
+
+	// Write the cache to a temporary file and rename it into place, so that a reader never sees a partial cache.
+	if err = os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer func() { contract.IgnoreError(os.Remove(tmp.Name())) }()
+	if _, err = tmp.Write(b); err != nil {
+		contract.IgnoreClose(tmp)
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp.Name(), c.path); err != nil {
+		return err
+	}
+	c.removed = make(map[string]bool)
+	c.dirty = false
+	return nil
+}
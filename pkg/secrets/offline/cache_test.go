@@ -0,0 +1,150 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package offline
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
+)
+
+func TestCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "secret-cache.json")
+
+	c, err := Open(path, "hunter2", time.Hour)
+	assert.NoError(t, err)
+	_, ok := c.Get("ciphertext")
+	assert.False(t, ok)
+	c.Put("ciphertext", "plaintext")
+	assert.NoError(t, c.Save())
+
+	b, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(b), "plaintext")
+
+	c, err = Open(path, "hunter2", time.Hour)
+	assert.NoError(t, err)
+	pt, ok := c.Get("ciphertext")
+	assert.True(t, ok)
+	assert.Equal(t, "plaintext", pt)
+
+	_, err = Open(path, "wrong", time.Hour)
+	assert.Equal(t, ErrIncorrectPassphrase, err)
+
+	// A shorter TTL applies to secrets that were cached under a longer one.
+	c, err = Open(path, "hunter2", time.Nanosecond)
+	assert.NoError(t, err)
+	_, ok = c.Get("ciphertext")
+	assert.False(t, ok)
+
+	assert.NoError(t, Clear(path))
+	assert.NoError(t, Clear(path))
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestDecrypter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "secret-cache.json")
+	c, err := Open(path, "hunter2", time.Hour)
+	assert.NoError(t, err)
+
+	crypter := config.NewSymmetricCrypter(make([]byte, config.SymmetricCrypterKeyBytes))
+	ciphertext, err := crypter.EncryptValue("plaintext")
+	assert.NoError(t, err)
+
+	// Secrets are decrypted by their provider when it is available, and cached. Secrets decrypted one at a time are
+	// not written to disk until the cache is saved.
+	pt, err := NewDecrypter(crypter, c).DecryptValue(ciphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, "plaintext", pt)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+
+	// Once the provider is unreachable, cached secrets are still readable.
+	unavailable := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	dec := NewUnavailableDecrypter(unavailable, c)
+	pt, err = dec.DecryptValue(ciphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, "plaintext", pt)
+	_, err = dec.DecryptValue("uncached")
+	assert.Equal(t, unavailable, err)
+
+	// Bulk decryption saves the cache.
+	pts, err := dec.(config.BulkDecrypter).BulkDecrypt(context.Background(), []string{ciphertext})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{ciphertext: "plaintext"}, pts)
+	_, err = os.Stat(path)
+	assert.NoError(t, err)
+
+	// If the provider refuses to decrypt a secret, the secret is revoked from the cache.
+	denied := errors.New("permission denied")
+	_, err = NewUnavailableDecrypter(denied, c).DecryptValue(ciphertext)
+	assert.Equal(t, denied, err)
+	_, ok := c.Get(ciphertext)
+	assert.False(t, ok)
+	_, err = dec.DecryptValue(ciphertext)
+	assert.Equal(t, unavailable, err)
+}
+
+func TestCacheSaveMerges(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secret-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "secret-cache.json")
+
+	// Two processes that opened the cache at the same time each keep the other's entries when they save.
+	a, err := Open(path, "hunter2", time.Hour)
+	assert.NoError(t, err)
+	b, err := Open(path, "hunter2", time.Hour)
+	assert.NoError(t, err)
+	a.Put("a", "plain-a")
+	a.Put("shared", "plain-shared")
+	assert.NoError(t, a.Save())
+	b.Put("b", "plain-b")
+	assert.NoError(t, b.Save())
+
+	c, err := Open(path, "hunter2", time.Hour)
+	assert.NoError(t, err)
+	for ct, expected := range map[string]string{"a": "plain-a", "b": "plain-b", "shared": "plain-shared"} {
+		pt, ok := c.Get(ct)
+		assert.True(t, ok, ct)
+		assert.Equal(t, expected, pt)
+	}
+
+	// Entries that a process removes are not restored from disk.
+	b.Remove("shared")
+	assert.NoError(t, b.Save())
+	c, err = Open(path, "hunter2", time.Hour)
+	assert.NoError(t, err)
+	_, ok := c.Get("shared")
+	assert.False(t, ok)
+	_, ok = c.Get("a")
+	assert.True(t, ok)
+}
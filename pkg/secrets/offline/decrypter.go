@@ -0,0 +1,182 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package offline
+
+import (
+	"context"
+	"encoding/json"
+	stderrors "errors"
+	"net"
+	"net/http"
+
+	"gocloud.dev/gcerrors"
+
+	"github.com/pulumi/pulumi/pkg/v2/secrets"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/apitype"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/logging"
+)
+
+// NewDecrypter returns a Decrypter that decrypts with dec, caching each plaintext in c. Secrets that dec cannot decrypt
+// because its secrets provider is unreachable are read from c instead. If the provider refuses to decrypt a secret,
+// e.g. because access to it has been revoked, the secret is removed from c.
+//
+// The cache is saved after each bulk decryption. Secrets decrypted one at a time are saved when c is.
+func NewDecrypter(dec config.Decrypter, c *Cache) config.Decrypter {
+	return &decrypter{dec: dec, cache: c}
+}
+
+// NewUnavailableDecrypter returns a Decrypter that reads secrets from c alone, failing with unavailable, the reason
+// that their secrets provider cannot be used, for secrets that are not cached. Cached secrets are only read if
+// unavailable is the result of an outage or a network error.
+func NewUnavailableDecrypter(unavailable error, c *Cache) config.Decrypter {
+	return &decrypter{unavailable: unavailable, cache: c}
+}
+
+type decrypter struct {
+	dec         config.Decrypter
+	unavailable error
+	cache       *Cache
+}
+
+func (d *decrypter) save() {
+	if err := d.cache.Save(); err != nil {
+		logging.V(3).Infof("could not save secret cache: %v", err)
+	}
+}
+
+func (d *decrypter) DecryptValue(ciphertext string) (string, error) {
+	err := d.unavailable
+	if d.dec != nil {
+		var plaintext string
+		if plaintext, err = d.dec.DecryptValue(ciphertext); err == nil {
+			d.cache.Put(ciphertext, plaintext)
+			return plaintext, nil
+		}
+	}
+	if !isUnavailable(err) {
+		d.cache.Remove(ciphertext)
+		return "", err
+	}
+	if plaintext, ok := d.cache.Get(ciphertext); ok {
+		return plaintext, nil
+	}
+	return "", err
+}
+
+func (d *decrypter) BulkDecrypt(ctx context.Context, ciphertexts []string) (map[string]string, error) {
+	defer d.save()
+
+	if bulk, ok := d.dec.(config.BulkDecrypter); ok {
+		plaintexts, err := bulk.BulkDecrypt(ctx, ciphertexts)
+		switch {
+		case err == nil:
+			for ct, pt := range plaintexts {
+				d.cache.Put(ct, pt)
+			}
+			return plaintexts, nil
+		case !isUnavailable(err):
+			for _, ct := range ciphertexts {
+				d.cache.Remove(ct)
+			}
+			return nil, err
+		}
+	}
+
+	plaintexts := make(map[string]string, len(ciphertexts))
+	for _, ct := range ciphertexts {
+		pt, err := d.DecryptValue(ct)
+		if err != nil {
+			return nil, err
+		}
+		plaintexts[ct] = pt
+	}
+	return plaintexts, nil
+}
+
+// isUnavailable returns true if err means that a secrets provider could not be reached, rather than that it refused
+// to decrypt a secret.
+func isUnavailable(err error) bool {
+	var netErr net.Error
+	if stderrors.As(err, &netErr) || stderrors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var errResp *apitype.ErrorResponse
+	if stderrors.As(err, &errResp) {
+		return errResp.Code >= http.StatusInternalServerError || errResp.Code == http.StatusTooManyRequests
+	}
+	switch gcerrors.Code(err) {
+	case gcerrors.DeadlineExceeded, gcerrors.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+// NewManager returns a secrets manager that encrypts with sm and decrypts with sm, caching the plaintexts in c and
+// falling back to c for the secrets that sm cannot decrypt.
+func NewManager(sm secrets.Manager, c *Cache) secrets.Manager {
+	return &manager{sm: sm, cache: c}
+}
+
+type manager struct {
+	sm    secrets.Manager
+	cache *Cache
+}
+
+func (m *manager) Type() string                         { return m.sm.Type() }
+func (m *manager) State() interface{}                   { return m.sm.State() }
+func (m *manager) Encrypter() (config.Encrypter, error) { return m.sm.Encrypter() }
+
+func (m *manager) Decrypter() (config.Decrypter, error) {
+	dec, err := m.sm.Decrypter()
+	if err != nil {
+		return NewUnavailableDecrypter(err, m.cache), nil
+	}
+	return NewDecrypter(dec, m.cache), nil
+}
+
+// NewUnavailableManager returns a secrets manager with the given type and state for a secrets provider that cannot be
+// used, for the reason given by unavailable. Its secrets are read from c alone, and it fails to encrypt anything. As
+// it keeps the type and state of the secrets provider, a deployment that is read with it and then written again keeps
+// its secrets provider.
+func NewUnavailableManager(ty string, state json.RawMessage, unavailable error, c *Cache) secrets.Manager {
+	return &unavailableManager{ty: ty, state: state, unavailable: unavailable, cache: c}
+}
+
+type unavailableManager struct {
+	ty          string
+	state       json.RawMessage
+	unavailable error
+	cache       *Cache
+}
+
+func (m *unavailableManager) Type() string       { return m.ty }
+func (m *unavailableManager) State() interface{} { return m.state }
+
+func (m *unavailableManager) Encrypter() (config.Encrypter, error) {
+	return &unavailableEncrypter{unavailable: m.unavailable}, nil
+}
+
+func (m *unavailableManager) Decrypter() (config.Decrypter, error) {
+	return NewUnavailableDecrypter(m.unavailable, m.cache), nil
+}
+
+type unavailableEncrypter struct {
+	unavailable error
+}
+
+func (e *unavailableEncrypter) EncryptValue(plaintext string) (string, error) {
+	return "", e.unavailable
+}
@@ -0,0 +1,50 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package onepassword
+
+import (
+	"bytes"
+	"context"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// cliClient is a Client that uses the `op` CLI, which authenticates with the service account token in the
+// environment.
+type cliClient struct{}
+
+func (c *cliClient) Read(ctx context.Context, ref Ref) (string, error) {
+	opBin, err := exec.LookPath("op")
+	if err != nil {
+		return "", errors.Wrap(err, "the 1Password CLI (op) is required to use a service account")
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, opBin, "read", "--no-newline", ref.String())
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", errors.Errorf("reading %v: %s", ref, msg)
+		}
+		return "", errors.Wrapf(err, "reading %v", ref)
+	}
+	return stdout.String(), nil
+}
+
+func (c *cliClient) Create(ctx context.Context, vault, title, value string) (Ref, error) {
+	return Ref{}, errors.New("1Password service accounts cannot be used as a secrets provider; use a Connect server")
+}
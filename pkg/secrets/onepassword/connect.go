@@ -0,0 +1,214 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package onepassword
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ConnectClient is a Client for a 1Password Connect server.
+type ConnectClient struct {
+	host   string
+	token  string
+	client *http.Client
+}
+
+// NewConnectClient returns a client for the Connect server at host that authenticates with the given access token.
+func NewConnectClient(host, token string) *ConnectClient {
+	return &ConnectClient{host: strings.TrimSuffix(host, "/"), token: token, client: http.DefaultClient}
+}
+
+type connectVault struct {
+	ID   string `json:"id"`
+	Name string `json:"name,omitempty"`
+}
+
+type connectSection struct {
+	ID    string `json:"id"`
+	Label string `json:"label,omitempty"`
+}
+
+type connectField struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type,omitempty"`
+	Purpose string          `json:"purpose,omitempty"`
+	Label   string          `json:"label,omitempty"`
+	Value   string          `json:"value,omitempty"`
+	Section *connectSection `json:"section,omitempty"`
+}
+
+type connectItem struct {
+	ID       string           `json:"id,omitempty"`
+	Title    string           `json:"title"`
+	Vault    connectVault     `json:"vault"`
+	Category string           `json:"category"`
+	Sections []connectSection `json:"sections,omitempty"`
+	Fields   []connectField   `json:"fields,omitempty"`
+}
+
+// Read implements Client.
+func (c *ConnectClient) Read(ctx context.Context, ref Ref) (string, error) {
+	vaultID, err := c.vaultID(ctx, ref.Vault)
+	if err != nil {
+		return "", err
+	}
+	itemID, err := c.itemID(ctx, vaultID, ref.Item)
+	if err != nil {
+		return "", err
+	}
+
+	var item connectItem
+	if err := c.do(ctx, http.MethodGet, "/v1/vaults/"+vaultID+"/items/"+itemID, nil, &item); err != nil {
+		return "", err
+	}
+
+	var sectionID string
+	if ref.Section != "" {
+		for _, s := range item.Sections {
+			if s.ID == ref.Section || s.Label == ref.Section {
+				sectionID = s.ID
+				break
+			}
+		}
+		if sectionID == "" {
+			return "", errors.Errorf("%v: item has no section %q", ref, ref.Section)
+		}
+	}
+	for _, f := range item.Fields {
+		if sectionID != "" && (f.Section == nil || f.Section.ID != sectionID) {
+			continue
+		}
+		if f.ID == ref.Field || f.Label == ref.Field {
+			return f.Value, nil
+		}
+	}
+	return "", errors.Errorf("%v: item has no field %q", ref, ref.Field)
+}
+
+// Create implements Client.
+func (c *ConnectClient) Create(ctx context.Context, vault, title, value string) (Ref, error) {
+	vaultID, err := c.vaultID(ctx, vault)
+	if err != nil {
+		return Ref{}, err
+	}
+
+	item := connectItem{
+		Title:    title,
+		Vault:    connectVault{ID: vaultID},
+		Category: "PASSWORD",
+		Fields: []connectField{{
+			ID:      "password",
+			Type:    "CONCEALED",
+			Purpose: "PASSWORD",
+			Label:   "password",
+			Value:   value,
+		}},
+	}
+	var created connectItem
+	if err := c.do(ctx, http.MethodPost, "/v1/vaults/"+vaultID+"/items", item, &created); err != nil {
+		return Ref{}, err
+	}
+	return Ref{Vault: vaultID, Item: created.ID, Field: "password"}, nil
+}
+
+// vaultID returns the ID of the vault with the given name, or name itself if no vault has that name.
+func (c *ConnectClient) vaultID(ctx context.Context, name string) (string, error) {
+	var vaults []connectVault
+	if err := c.do(ctx, http.MethodGet, "/v1/vaults?filter="+filter("name", name), nil, &vaults); err != nil {
+		return "", err
+	}
+	switch len(vaults) {
+	case 0:
+		return name, nil
+	case 1:
+		return vaults[0].ID, nil
+	default:
+		return "", errors.Errorf("more than one vault is named %q", name)
+	}
+}
+
+// itemID returns the ID of the item in vaultID with the given title, or title itself if no item has that title.
+func (c *ConnectClient) itemID(ctx context.Context, vaultID, title string) (string, error) {
+	var items []connectItem
+	path := "/v1/vaults/" + vaultID + "/items?filter=" + filter("title", title)
+	if err := c.do(ctx, http.MethodGet, path, nil, &items); err != nil {
+		return "", err
+	}
+	switch len(items) {
+	case 0:
+		return title, nil
+	case 1:
+		return items[0].ID, nil
+	default:
+		return "", errors.Errorf("more than one item in vault %s is titled %q", vaultID, title)
+	}
+}
+
+// filter returns an encoded SCIM filter that matches resources whose attr equals value.
+func filter(attr, value string) string {
+	return url.QueryEscape(fmt.Sprintf("%s eq %q", attr, value))
+}
+
+func (c *ConnectClient) do(ctx context.Context, method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequest(method, c.host+path, reader)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "contacting 1Password Connect")
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "reading 1Password Connect response")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(b, &apiErr) == nil && apiErr.Message != "" {
+			return errors.Errorf("1Password Connect: %s", apiErr.Message)
+		}
+		return errors.Errorf("1Password Connect: %s", resp.Status)
+	}
+	return json.Unmarshal(b, result)
+}
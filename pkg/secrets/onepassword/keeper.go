@@ -0,0 +1,91 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package onepassword
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+
+	"github.com/pkg/errors"
+	"gocloud.dev/gcerrors"
+	gosecrets "gocloud.dev/secrets"
+)
+
+func init() {
+	gosecrets.DefaultURLMux().RegisterKeeper(Scheme, &urlOpener{})
+}
+
+// urlOpener opens `onepassword://` keepers for gocloud.dev/secrets, so that 1Password can be used anywhere a cloud
+// secrets provider can.
+type urlOpener struct{}
+
+func (o *urlOpener) OpenKeeperURL(ctx context.Context, u *url.URL) (*gosecrets.Keeper, error) {
+	if u.Host == "" {
+		return nil, fmt.Errorf("open keeper %v: a vault must be given", u)
+	}
+	for param := range u.Query() {
+		return nil, fmt.Errorf("open keeper %v: invalid query parameter %q", u, param)
+	}
+	client, err := NewClientFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("open keeper %v: %v", u, err)
+	}
+	return gosecrets.NewKeeper(&keeper{client: client, vault: u.Host}), nil
+}
+
+// keeper implements gocloud.dev/secrets/driver.Keeper. Rather than encrypting a plaintext, it stores the plaintext in
+// a new 1Password item and returns a reference to the item as the ciphertext.
+type keeper struct {
+	client Client
+	vault  string
+}
+
+func (k *keeper) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	title := "Pulumi data key " + hex.EncodeToString(id)
+
+	ref, err := k.client.Create(ctx, k.vault, title, base64.StdEncoding.EncodeToString(plaintext))
+	if err != nil {
+		return nil, err
+	}
+	return []byte(ref.String()), nil
+}
+
+func (k *keeper) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	uri, err := url.Parse(string(ciphertext))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid 1Password secret reference")
+	}
+	ref, err := ParseRef(uri)
+	if err != nil {
+		return nil, err
+	}
+	value, err := k.client.Read(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	return base64.StdEncoding.DecodeString(value)
+}
+
+func (k *keeper) Close() error                           { return nil }
+func (k *keeper) ErrorAs(err error, i interface{}) bool  { return false }
+func (k *keeper) ErrorCode(err error) gcerrors.ErrorCode { return gcerrors.Unknown }
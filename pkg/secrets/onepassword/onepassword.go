@@ -0,0 +1,128 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package onepassword integrates with 1Password, both as a secrets provider and as a store that config values may
+// refer to.
+//
+// As a secrets provider, `onepassword://<vault>` keeps each stack's data key in a new item in the given vault, and the
+// stack's encrypted key is the secret reference of that item. As a reference store, config values of the form
+// `op://<vault>/<item>[/<section>]/<field>` resolve to the value of the given field, using 1Password's own secret
+// reference syntax. Vaults and items may be named by title or by ID.
+//
+// 1Password is reached through a Connect server, located by $OP_CONNECT_HOST and authenticated by $OP_CONNECT_TOKEN.
+// Alternatively, references may be resolved with a service account by setting $OP_SERVICE_ACCOUNT_TOKEN, in which case
+// the `op` CLI is used to read them. Service accounts cannot yet be used as a secrets provider.
+package onepassword
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// Scheme is the URL scheme of the 1Password secrets provider.
+	Scheme = "onepassword"
+	// RefScheme is the URI scheme of 1Password secret references.
+	RefScheme = "op"
+)
+
+const (
+	envConnectHost         = "OP_CONNECT_HOST"
+	envConnectToken        = "OP_CONNECT_TOKEN"
+	envServiceAccountToken = "OP_SERVICE_ACCOUNT_TOKEN"
+)
+
+// Ref is a 1Password secret reference, which names a field of an item in a vault.
+type Ref struct {
+	Vault   string
+	Item    string
+	Section string
+	Field   string
+}
+
+// ParseRef parses a secret reference of the form `op://<vault>/<item>[/<section>]/<field>`.
+func ParseRef(uri *url.URL) (Ref, error) {
+	if uri.Scheme != RefScheme {
+		return Ref{}, errors.Errorf("unsupported scheme %q; expected %q", uri.Scheme, RefScheme)
+	}
+	parts := strings.Split(strings.TrimPrefix(uri.Path, "/"), "/")
+	if uri.Host == "" || len(parts) < 2 || len(parts) > 3 {
+		return Ref{}, errors.Errorf("%v must be of the form op://<vault>/<item>[/<section>]/<field>", uri)
+	}
+	for _, part := range parts {
+		if part == "" {
+			return Ref{}, errors.Errorf("%v must be of the form op://<vault>/<item>[/<section>]/<field>", uri)
+		}
+	}
+
+	ref := Ref{Vault: uri.Host, Item: parts[0], Field: parts[len(parts)-1]}
+	if len(parts) == 3 {
+		ref.Section = parts[1]
+	}
+	return ref, nil
+}
+
+func (r Ref) String() string {
+	parts := []string{r.Vault, r.Item}
+	if r.Section != "" {
+		parts = append(parts, r.Section)
+	}
+	parts = append(parts, r.Field)
+	return RefScheme + "://" + strings.Join(parts, "/")
+}
+
+// Client reads and writes secrets held in 1Password.
+type Client interface {
+	// Read returns the value of the field that ref names.
+	Read(ctx context.Context, ref Ref) (string, error)
+	// Create creates an item with the given title in vault that holds value in a concealed field, returning a reference
+	// to the field.
+	Create(ctx context.Context, vault, title, value string) (Ref, error)
+}
+
+// NewClientFromEnv returns a Client for the Connect server or service account configured by the environment.
+func NewClientFromEnv() (Client, error) {
+	if host := os.Getenv(envConnectHost); host != "" {
+		token := os.Getenv(envConnectToken)
+		if token == "" {
+			return nil, errors.Errorf("%s must be set to use the 1Password Connect server at %s", envConnectToken, host)
+		}
+		return NewConnectClient(host, token), nil
+	}
+	if os.Getenv(envServiceAccountToken) != "" {
+		return &cliClient{}, nil
+	}
+	return nil, errors.Errorf("either %s and %s or %s must be set to use 1Password",
+		envConnectHost, envConnectToken, envServiceAccountToken)
+}
+
+// RefResolver resolves `op://` secret references with the client configured by the environment. It implements
+// config.RefResolver.
+type RefResolver struct{}
+
+func (RefResolver) ResolveRef(ctx context.Context, uri *url.URL) (string, error) {
+	ref, err := ParseRef(uri)
+	if err != nil {
+		return "", err
+	}
+	client, err := NewClientFromEnv()
+	if err != nil {
+		return "", err
+	}
+	return client.Read(ctx, ref)
+}
@@ -0,0 +1,207 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package onepassword
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	gosecrets "gocloud.dev/secrets"
+)
+
+// setenv sets an environment variable for the duration of a test.
+func setenv(t *testing.T, key, value string) {
+	old, had := os.LookupEnv(key)
+	t.Cleanup(func() {
+		if had {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+	os.Setenv(key, value)
+}
+
+// newFakeConnect returns a server that implements enough of the 1Password Connect API for tests. The server starts
+// with a single vault, `prod`, holding a single item, `db`.
+func newFakeConnect(t *testing.T) *httptest.Server {
+	vaults := []connectVault{{ID: "vprod", Name: "prod"}}
+	items := map[string][]connectItem{
+		"vprod": {{
+			ID:       "idb",
+			Title:    "db",
+			Vault:    connectVault{ID: "vprod"},
+			Category: "LOGIN",
+			Sections: []connectSection{{ID: "s1", Label: "replica"}},
+			Fields: []connectField{
+				{ID: "password", Label: "password", Value: "hunter2"},
+				{ID: "f1", Label: "password", Value: "hunter3", Section: &connectSection{ID: "s1"}},
+			},
+		}},
+	}
+
+	// matches evaluates a filter of the form `attr eq "value"`.
+	matches := func(filter, attr, value string) bool {
+		return filter == "" || filter == fmt.Sprintf("%s eq %q", attr, value)
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			assert.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{"message": "Invalid token"}))
+			return
+		}
+
+		filter := r.URL.Query().Get("filter")
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/v1/"), "/")
+		var resp interface{}
+		switch {
+		case len(parts) == 1 && parts[0] == "vaults":
+			var result []connectVault
+			for _, v := range vaults {
+				if matches(filter, "name", v.Name) {
+					result = append(result, v)
+				}
+			}
+			resp = result
+		case len(parts) == 3 && parts[2] == "items" && r.Method == http.MethodGet:
+			var result []connectItem
+			for _, item := range items[parts[1]] {
+				if matches(filter, "title", item.Title) {
+					result = append(result, connectItem{ID: item.ID, Title: item.Title})
+				}
+			}
+			resp = result
+		case len(parts) == 3 && parts[2] == "items" && r.Method == http.MethodPost:
+			var item connectItem
+			assert.NoError(t, json.NewDecoder(r.Body).Decode(&item))
+			item.ID = fmt.Sprintf("i%d", len(items[parts[1]]))
+			items[parts[1]] = append(items[parts[1]], item)
+			resp = item
+		case len(parts) == 4 && parts[2] == "items":
+			for _, item := range items[parts[1]] {
+				if item.ID == parts[3] {
+					resp = item
+				}
+			}
+		}
+		if resp == nil {
+			w.WriteHeader(http.StatusNotFound)
+			assert.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{"message": "Not found"}))
+			return
+		}
+		assert.NoError(t, json.NewEncoder(w).Encode(resp))
+	}))
+}
+
+func mustParseRef(t *testing.T, s string) Ref {
+	uri, err := url.Parse(s)
+	assert.NoError(t, err)
+	ref, err := ParseRef(uri)
+	assert.NoError(t, err)
+	return ref
+}
+
+func TestParseRef(t *testing.T) {
+	assert.Equal(t, Ref{Vault: "prod", Item: "db", Field: "password"}, mustParseRef(t, "op://prod/db/password"))
+	assert.Equal(t, Ref{Vault: "prod", Item: "db", Section: "replica", Field: "password"},
+		mustParseRef(t, "op://prod/db/replica/password"))
+	assert.Equal(t, "op://prod/db/replica/password", mustParseRef(t, "op://prod/db/replica/password").String())
+
+	for _, s := range []string{"op://prod/db", "op://prod", "op:///db/password", "op://prod/db//password",
+		"op://a/b/c/d/e", "vault://prod/db/password"} {
+		uri, err := url.Parse(s)
+		assert.NoError(t, err)
+		_, err = ParseRef(uri)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestConnectRead(t *testing.T) {
+	server := newFakeConnect(t)
+	defer server.Close()
+
+	ctx := context.Background()
+	client := NewConnectClient(server.URL, "token")
+	for ref, expected := range map[string]string{
+		"op://prod/db/password":         "hunter2",
+		"op://vprod/idb/password":       "hunter2",
+		"op://prod/db/replica/password": "hunter3",
+		"op://prod/db/s1/f1":            "hunter3",
+	} {
+		v, err := client.Read(ctx, mustParseRef(t, ref))
+		assert.NoError(t, err, ref)
+		assert.Equal(t, expected, v, ref)
+	}
+
+	_, err := client.Read(ctx, mustParseRef(t, "op://prod/db/username"))
+	assert.EqualError(t, err, `op://prod/db/username: item has no field "username"`)
+	_, err = client.Read(ctx, mustParseRef(t, "op://prod/db/primary/password"))
+	assert.EqualError(t, err, `op://prod/db/primary/password: item has no section "primary"`)
+	_, err = client.Read(ctx, mustParseRef(t, "op://prod/web/password"))
+	assert.EqualError(t, err, "1Password Connect: Not found")
+
+	_, err = NewConnectClient(server.URL, "wrong").Read(ctx, mustParseRef(t, "op://prod/db/password"))
+	assert.EqualError(t, err, "1Password Connect: Invalid token")
+}
+
+func TestRefResolver(t *testing.T) {
+	server := newFakeConnect(t)
+	defer server.Close()
+	setenv(t, envConnectHost, server.URL)
+	setenv(t, envConnectToken, "token")
+
+	uri, err := url.Parse("op://prod/db/password")
+	assert.NoError(t, err)
+	v, err := RefResolver{}.ResolveRef(context.Background(), uri)
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", v)
+
+	setenv(t, envConnectToken, "")
+	_, err = RefResolver{}.ResolveRef(context.Background(), uri)
+	assert.Error(t, err)
+}
+
+func TestKeeper(t *testing.T) {
+	server := newFakeConnect(t)
+	defer server.Close()
+	setenv(t, envConnectHost, server.URL)
+	setenv(t, envConnectToken, "token")
+
+	ctx := context.Background()
+	keeper, err := gosecrets.OpenKeeper(ctx, "onepassword://prod")
+	assert.NoError(t, err)
+
+	ciphertext, err := keeper.Encrypt(ctx, []byte("data key"))
+	assert.NoError(t, err)
+	assert.Equal(t, "op://vprod/i1/password", string(ciphertext))
+
+	plaintext, err := keeper.Decrypt(ctx, ciphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, "data key", string(plaintext))
+
+	_, err = gosecrets.OpenKeeper(ctx, "onepassword://")
+	assert.Error(t, err)
+	_, err = gosecrets.OpenKeeper(ctx, "onepassword://prod?region=us-east-1")
+	assert.Error(t, err)
+}
@@ -0,0 +1,174 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package pkcs11 implements a `pkcs11://` secrets provider that encrypts a stack's data key with a key held on a
+// hardware token, such as a YubiKey or an HSM, using PKCS#11. The key never leaves the token.
+//
+// The provider URL names the key with query parameters: `object` is the key's label and `id` its hex-encoded ID,
+// of which one is required. `token` is the label of the token that holds the key and `slot` the number of its
+// slot; they may be omitted if only one token is present. `module` is the path of the token's PKCS#11 library, and
+// defaults to $PULUMI_PKCS11_MODULE. For example:
+//
+//	pkcs11://?module=/usr/lib/libykcs11.so&token=YubiKey%20PIV&object=Private%20key%20for%20Key%20Management
+//
+// AES keys encrypt with AES-GCM on the token. RSA keys encrypt with RSA-OAEP and SHA-256: encryption only needs the
+// public key, and decryption happens on the token. The user PIN is read from $PULUMI_PKCS11_PIN.
+//
+// The provider requires a build of the CLI with cgo enabled, as PKCS#11 libraries are loaded dynamically.
+package pkcs11
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"gocloud.dev/gcerrors"
+	gosecrets "gocloud.dev/secrets"
+)
+
+const (
+	// Scheme is the URL scheme of the PKCS#11 secrets provider.
+	Scheme = "pkcs11"
+	// ModuleEnvVar is the environment variable that holds the path of the default PKCS#11 library.
+	ModuleEnvVar = "PULUMI_PKCS11_MODULE"
+	// PINEnvVar is the environment variable that holds the PIN used to log in to the token.
+	PINEnvVar = "PULUMI_PKCS11_PIN"
+)
+
+func init() {
+	gosecrets.DefaultURLMux().RegisterKeeper(Scheme, &urlOpener{})
+}
+
+// KeyRef identifies a key held on a PKCS#11 token.
+type KeyRef struct {
+	// Module is the path of the token's PKCS#11 library.
+	Module string
+	// Token is the label of the token, if it is identified by label.
+	Token string
+	// Slot is the number of the token's slot, if it is identified by slot.
+	Slot *uint
+	// Object is the label of the key, if it is identified by label.
+	Object string
+	// ID is the ID of the key, if it is identified by ID.
+	ID []byte
+}
+
+// ParseKeyRef returns the key named by a `pkcs11://` provider URL.
+func ParseKeyRef(u *url.URL) (KeyRef, error) {
+	if u.Scheme != Scheme {
+		return KeyRef{}, errors.Errorf("unsupported scheme %q; expected %q", u.Scheme, Scheme)
+	}
+
+	ref := KeyRef{Module: os.Getenv(ModuleEnvVar)}
+	for param, values := range u.Query() {
+		value := values[len(values)-1]
+		switch param {
+		case "module":
+			ref.Module = value
+		case "token":
+			ref.Token = value
+		case "slot":
+			slot, err := strconv.ParseUint(value, 10, 0)
+			if err != nil {
+				return KeyRef{}, errors.Errorf("invalid slot %q", value)
+			}
+			s := uint(slot)
+			ref.Slot = &s
+		case "object":
+			ref.Object = value
+		case "id":
+			id, err := hex.DecodeString(value)
+			if err != nil {
+				return KeyRef{}, errors.Errorf("invalid key ID %q: must be hex-encoded", value)
+			}
+			ref.ID = id
+		default:
+			return KeyRef{}, errors.Errorf("invalid query parameter %q", param)
+		}
+	}
+
+	switch {
+	case ref.Module == "":
+		return KeyRef{}, errors.Errorf("%v does not name a PKCS#11 module; set the module parameter or %s", u,
+			ModuleEnvVar)
+	case ref.Object == "" && len(ref.ID) == 0:
+		return KeyRef{}, errors.Errorf("%v does not name a key; set the object or id parameter", u)
+	case ref.Token != "" && ref.Slot != nil:
+		return KeyRef{}, errors.Errorf("%v names both a token and a slot", u)
+	}
+	return ref, nil
+}
+
+// key is a key on a token, in an open session.
+type key interface {
+	encrypt(plaintext []byte) ([]byte, error)
+	decrypt(ciphertext []byte) ([]byte, error)
+	close()
+}
+
+// withKey opens a session with the token that holds the referenced key, calls f with the key and closes the session.
+func withKey(ref KeyRef, f func(k key) error) error {
+	k, err := openKey(ref, os.Getenv(PINEnvVar))
+	if err != nil {
+		return err
+	}
+	defer k.close()
+	return f(k)
+}
+
+// urlOpener opens `pkcs11://` keepers for gocloud.dev/secrets, so that the provider can be used anywhere a cloud
+// secrets provider can.
+type urlOpener struct{}
+
+func (o *urlOpener) OpenKeeperURL(ctx context.Context, u *url.URL) (*gosecrets.Keeper, error) {
+	ref, err := ParseKeyRef(u)
+	if err != nil {
+		return nil, fmt.Errorf("open keeper %v: %v", u, err)
+	}
+	return gosecrets.NewKeeper(&keeper{ref: ref}), nil
+}
+
+// keeper implements gocloud.dev/secrets/driver.Keeper. The token is only opened for the duration of each operation,
+// so that it may be removed between them.
+type keeper struct {
+	ref KeyRef
+}
+
+func (k *keeper) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	var ciphertext []byte
+	err := withKey(k.ref, func(key key) error {
+		var err error
+		ciphertext, err = key.encrypt(plaintext)
+		return err
+	})
+	return ciphertext, err
+}
+
+func (k *keeper) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	var plaintext []byte
+	err := withKey(k.ref, func(key key) error {
+		var err error
+		plaintext, err = key.decrypt(ciphertext)
+		return err
+	})
+	return plaintext, err
+}
+
+func (k *keeper) Close() error                           { return nil }
+func (k *keeper) ErrorAs(err error, i interface{}) bool  { return false }
+func (k *keeper) ErrorCode(err error) gcerrors.ErrorCode { return gcerrors.Unknown }
@@ -0,0 +1,96 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkcs11
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	gosecrets "gocloud.dev/secrets"
+)
+
+func TestParseKeyRef(t *testing.T) {
+	old, had := os.LookupEnv(ModuleEnvVar)
+	defer func() {
+		if had {
+			os.Setenv(ModuleEnvVar, old)
+		} else {
+			os.Unsetenv(ModuleEnvVar)
+		}
+	}()
+	os.Setenv(ModuleEnvVar, "/usr/lib/default.so")
+
+	slot := uint(2)
+	tests := []struct {
+		url      string
+		expected KeyRef
+		err      bool
+	}{
+		{
+			url:      "pkcs11://?object=mykey",
+			expected: KeyRef{Module: "/usr/lib/default.so", Object: "mykey"},
+		},
+		{
+			url:      "pkcs11://?module=/usr/lib/libykcs11.so&token=YubiKey%20PIV&object=Private%20key",
+			expected: KeyRef{Module: "/usr/lib/libykcs11.so", Token: "YubiKey PIV", Object: "Private key"},
+		},
+		{
+			url:      "pkcs11://?slot=2&id=0a0b",
+			expected: KeyRef{Module: "/usr/lib/default.so", Slot: &slot, ID: []byte{0x0a, 0x0b}},
+		},
+		{url: "pkcs11://?module=/usr/lib/libykcs11.so", err: true},
+		{url: "pkcs11://?object=mykey&slot=one", err: true},
+		{url: "pkcs11://?id=xyz", err: true},
+		{url: "pkcs11://?object=mykey&token=t&slot=1", err: true},
+		{url: "pkcs11://?object=mykey&pin=1234", err: true},
+		{url: "age://?object=mykey", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			u, err := url.Parse(tt.url)
+			assert.NoError(t, err)
+			ref, err := ParseKeyRef(u)
+			if tt.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, ref)
+		})
+	}
+
+	os.Unsetenv(ModuleEnvVar)
+	u, err := url.Parse("pkcs11://?object=mykey")
+	assert.NoError(t, err)
+	_, err = ParseKeyRef(u)
+	assert.Error(t, err)
+}
+
+func TestPKCS11KeeperMissingModule(t *testing.T) {
+	ctx := context.Background()
+	keeper, err := gosecrets.OpenKeeper(ctx, "pkcs11://?module=/nonexistent/libpkcs11.so&object=mykey")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer keeper.Close()
+
+	_, err = keeper.Encrypt(ctx, []byte("hunter2"))
+	assert.Error(t, err)
+	_, err = keeper.Decrypt(ctx, []byte("hunter2"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,262 @@
+// +build cgo
+
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package pkcs11
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"math/big"
+	"strings"
+
+	"github.com/miekg/pkcs11"
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
+)
+
+// gcmIVSize and gcmTagBits are the sizes of the IV and tag used with AES-GCM keys.
+const (
+	gcmIVSize  = 12
+	gcmTagBits = 128
+)
+
+// tokenKey is a key on a token. An AES key is a single secret key object; an RSA key is a private key object, a public
+// key object, or both, matched by ID.
+type tokenKey struct {
+	ctx     *pkcs11.Ctx
+	session pkcs11.SessionHandle
+	open    bool
+
+	secret, private, public          pkcs11.ObjectHandle
+	hasSecret, hasPrivate, hasPublic bool
+}
+
+func openKey(ref KeyRef, pin string) (key, error) {
+	ctx := pkcs11.New(ref.Module)
+	if ctx == nil {
+		return nil, errors.Errorf("could not load PKCS#11 module %s", ref.Module)
+	}
+	if err := ctx.Initialize(); err != nil && err != pkcs11.Error(pkcs11.CKR_CRYPTOKI_ALREADY_INITIALIZED) {
+		ctx.Destroy()
+		return nil, errors.Wrapf(err, "initializing PKCS#11 module %s", ref.Module)
+	}
+
+	k := &tokenKey{ctx: ctx}
+	if err := k.find(ref, pin); err != nil {
+		k.close()
+		return nil, err
+	}
+	return k, nil
+}
+
+// find opens a session with the referenced token and finds the referenced key.
+func (k *tokenKey) find(ref KeyRef, pin string) error {
+	slot, err := k.findSlot(ref)
+	if err != nil {
+		return err
+	}
+	if k.session, err = k.ctx.OpenSession(slot, pkcs11.CKF_SERIAL_SESSION); err != nil {
+		return errors.Wrap(err, "opening PKCS#11 session")
+	}
+	k.open = true
+	if pin != "" {
+		err = k.ctx.Login(k.session, pkcs11.CKU_USER, pin)
+		if err != nil && err != pkcs11.Error(pkcs11.CKR_USER_ALREADY_LOGGED_IN) {
+			return errors.Wrap(err, "logging in to PKCS#11 token")
+		}
+	}
+
+	var template []*pkcs11.Attribute
+	if ref.Object != "" {
+		template = append(template, pkcs11.NewAttribute(pkcs11.CKA_LABEL, ref.Object))
+	}
+	if len(ref.ID) != 0 {
+		template = append(template, pkcs11.NewAttribute(pkcs11.CKA_ID, ref.ID))
+	}
+
+	if k.secret, k.hasSecret, err = k.findObject(template, pkcs11.CKO_SECRET_KEY); err != nil || k.hasSecret {
+		return err
+	}
+	if k.private, k.hasPrivate, err = k.findObject(template, pkcs11.CKO_PRIVATE_KEY); err != nil {
+		return err
+	}
+	if k.public, k.hasPublic, err = k.findObject(template, pkcs11.CKO_PUBLIC_KEY); err != nil {
+		return err
+	}
+
+	// The halves of a key pair often have different labels, but share an ID.
+	switch {
+	case k.hasPrivate && !k.hasPublic:
+		k.public, k.hasPublic, err = k.findPair(k.private, pkcs11.CKO_PUBLIC_KEY)
+	case k.hasPublic && !k.hasPrivate:
+		k.private, k.hasPrivate, err = k.findPair(k.public, pkcs11.CKO_PRIVATE_KEY)
+	case !k.hasPrivate && !k.hasPublic:
+		err = errors.New("no matching key was found on the PKCS#11 token")
+	}
+	return err
+}
+
+func (k *tokenKey) findSlot(ref KeyRef) (uint, error) {
+	if ref.Slot != nil {
+		return *ref.Slot, nil
+	}
+
+	slots, err := k.ctx.GetSlotList(true)
+	if err != nil {
+		return 0, errors.Wrap(err, "listing PKCS#11 slots")
+	}
+	if ref.Token == "" {
+		if len(slots) != 1 {
+			return 0, errors.Errorf("%d PKCS#11 tokens are present; set the token or slot parameter", len(slots))
+		}
+		return slots[0], nil
+	}
+	for _, slot := range slots {
+		info, err := k.ctx.GetTokenInfo(slot)
+		if err != nil {
+			return 0, errors.Wrap(err, "reading PKCS#11 token info")
+		}
+		if strings.TrimSpace(info.Label) == ref.Token {
+			return slot, nil
+		}
+	}
+	return 0, errors.Errorf("no PKCS#11 token labeled %q is present", ref.Token)
+}
+
+func (k *tokenKey) findObject(template []*pkcs11.Attribute, class uint) (pkcs11.ObjectHandle, bool, error) {
+	template = append([]*pkcs11.Attribute{pkcs11.NewAttribute(pkcs11.CKA_CLASS, class)}, template...)
+	if err := k.ctx.FindObjectsInit(k.session, template); err != nil {
+		return 0, false, errors.Wrap(err, "finding PKCS#11 key")
+	}
+	objects, _, err := k.ctx.FindObjects(k.session, 1)
+	contract.IgnoreError(k.ctx.FindObjectsFinal(k.session))
+	if err != nil {
+		return 0, false, errors.Wrap(err, "finding PKCS#11 key")
+	}
+	if len(objects) == 0 {
+		return 0, false, nil
+	}
+	return objects[0], true, nil
+}
+
+// findPair finds the other half of the key pair that includes object.
+func (k *tokenKey) findPair(object pkcs11.ObjectHandle, class uint) (pkcs11.ObjectHandle, bool, error) {
+	attrs, err := k.ctx.GetAttributeValue(k.session, object, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_ID, nil),
+	})
+	if err != nil || len(attrs) == 0 || len(attrs[0].Value) == 0 {
+		return 0, false, nil
+	}
+	return k.findObject([]*pkcs11.Attribute{attrs[0]}, class)
+}
+
+// publicKey reads the RSA public key from the public key object, or from the private key object if there is none.
+func (k *tokenKey) publicKey() (*rsa.PublicKey, error) {
+	object := k.private
+	if k.hasPublic {
+		object = k.public
+	}
+	attrs, err := k.ctx.GetAttributeValue(k.session, object, []*pkcs11.Attribute{
+		pkcs11.NewAttribute(pkcs11.CKA_MODULUS, nil),
+		pkcs11.NewAttribute(pkcs11.CKA_PUBLIC_EXPONENT, nil),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "reading PKCS#11 public key")
+	}
+	var n, e *big.Int
+	for _, a := range attrs {
+		switch a.Type {
+		case pkcs11.CKA_MODULUS:
+			n = new(big.Int).SetBytes(a.Value)
+		case pkcs11.CKA_PUBLIC_EXPONENT:
+			e = new(big.Int).SetBytes(a.Value)
+		}
+	}
+	if n == nil || e == nil || !e.IsInt64() {
+		return nil, errors.New("the PKCS#11 key is not an RSA key")
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (k *tokenKey) encrypt(plaintext []byte) ([]byte, error) {
+	if !k.hasSecret {
+		pub, err := k.publicKey()
+		if err != nil {
+			return nil, err
+		}
+		return rsa.EncryptOAEP(sha256.New(), rand.Reader, pub, plaintext, nil)
+	}
+
+	iv := make([]byte, gcmIVSize)
+	if _, err := rand.Read(iv); err != nil {
+		return nil, err
+	}
+	params := pkcs11.NewGCMParams(iv, nil, gcmTagBits)
+	defer params.Free()
+	mech := []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_GCM, params)}
+	if err := k.ctx.EncryptInit(k.session, mech, k.secret); err != nil {
+		return nil, errors.Wrap(err, "encrypting with PKCS#11 key")
+	}
+	ciphertext, err := k.ctx.Encrypt(k.session, plaintext)
+	if err != nil {
+		return nil, errors.Wrap(err, "encrypting with PKCS#11 key")
+	}
+	// Some tokens choose their own IV, which they write back to the parameters.
+	if tokenIV := params.IV(); len(tokenIV) == gcmIVSize {
+		iv = tokenIV
+	}
+	return append(iv, ciphertext...), nil
+}
+
+func (k *tokenKey) decrypt(ciphertext []byte) ([]byte, error) {
+	var mech []*pkcs11.Mechanism
+	object := k.private
+	if k.hasSecret {
+		if len(ciphertext) < gcmIVSize {
+			return nil, errors.New("ciphertext is too short")
+		}
+		params := pkcs11.NewGCMParams(ciphertext[:gcmIVSize], nil, gcmTagBits)
+		defer params.Free()
+		mech = []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_AES_GCM, params)}
+		object, ciphertext = k.secret, ciphertext[gcmIVSize:]
+	} else {
+		if !k.hasPrivate {
+			return nil, errors.New("the PKCS#11 token does not hold the private key needed for decryption")
+		}
+		params := pkcs11.NewOAEPParams(pkcs11.CKM_SHA256, pkcs11.CKG_MGF1_SHA256, pkcs11.CKZ_DATA_SPECIFIED, nil)
+		mech = []*pkcs11.Mechanism{pkcs11.NewMechanism(pkcs11.CKM_RSA_PKCS_OAEP, params)}
+	}
+
+	if err := k.ctx.DecryptInit(k.session, mech, object); err != nil {
+		return nil, errors.Wrap(err, "decrypting with PKCS#11 key")
+	}
+	plaintext, err := k.ctx.Decrypt(k.session, ciphertext)
+	if err != nil {
+		return nil, errors.Wrap(err, "decrypting with PKCS#11 key")
+	}
+	return plaintext, nil
+}
+
+func (k *tokenKey) close() {
+	if k.open {
+		contract.IgnoreError(k.ctx.Logout(k.session))
+		contract.IgnoreError(k.ctx.CloseSession(k.session))
+	}
+	contract.IgnoreError(k.ctx.Finalize())
+	k.ctx.Destroy()
+}
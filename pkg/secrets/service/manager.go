@@ -25,6 +25,7 @@ import (
 
 	"github.com/pulumi/pulumi/pkg/v2/backend/httpstate/client"
 	"github.com/pulumi/pulumi/pkg/v2/secrets"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/apitype"
 	"github.com/pulumi/pulumi/sdk/v2/go/common/diag"
 	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
 	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
@@ -39,6 +40,8 @@ type serviceCrypter struct {
 	stack  client.StackIdentifier
 }
 
+var _ config.BulkDecrypter = &serviceCrypter{}
+
 func newServiceCrypter(client *client.Client, stack client.StackIdentifier) config.Crypter {
 	return &serviceCrypter{client: client, stack: stack}
 }
@@ -63,6 +66,40 @@ func (c *serviceCrypter) DecryptValue(cipherstring string) (string, error) {
 	return string(plaintext), nil
 }
 
+func (c *serviceCrypter) BulkDecrypt(ctx context.Context, cipherstrings []string) (map[string]string, error) {
+	ciphertexts := make([][]byte, len(cipherstrings))
+	for i, cs := range cipherstrings {
+		ct, err := base64.StdEncoding.DecodeString(cs)
+		if err != nil {
+			return nil, err
+		}
+		ciphertexts[i] = ct
+	}
+
+	plaintexts, err := c.client.BatchDecryptValue(ctx, c.stack, ciphertexts)
+	if err != nil {
+		// Older versions of the service do not support batch decryption, so fall back to decrypting each value.
+		if errResp, ok := err.(*apitype.ErrorResponse); ok && errResp.Code == 404 {
+			result := make(map[string]string, len(cipherstrings))
+			for _, cs := range cipherstrings {
+				pt, err := c.DecryptValue(cs)
+				if err != nil {
+					return nil, err
+				}
+				result[cs] = pt
+			}
+			return result, nil
+		}
+		return nil, err
+	}
+
+	result := make(map[string]string, len(plaintexts))
+	for cs, pt := range plaintexts {
+		result[cs] = string(pt)
+	}
+	return result, nil
+}
+
 type serviceSecretsManagerState struct {
 	URL     string `json:"url,omitempty"`
 	Owner   string `json:"owner"`
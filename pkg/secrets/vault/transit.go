@@ -0,0 +1,298 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package vault implements a `vault://` secrets provider that encrypts and decrypts through the transit secrets
+// engine of a HashiCorp Vault server.
+//
+// A provider URL names the transit key to use, and may set the following query parameters:
+//
+//   - `address`: the address of the Vault server. Defaults to $VAULT_ADDR.
+//   - `namespace`: the Vault Enterprise namespace that holds the transit engine. Defaults to $VAULT_NAMESPACE.
+//   - `mount`: the path at which the transit engine is mounted. Defaults to `transit`.
+//   - `auth`: how to authenticate, either `token` (the default), which uses $VAULT_TOKEN, or `approle`, which logs in
+//     with the role ID and secret ID in $VAULT_ROLE_ID and $VAULT_SECRET_ID.
+//   - `approleMount`: the path at which the AppRole auth method is mounted. Defaults to `approle`.
+//
+// For example, `vault://pulumi?namespace=platform&auth=approle` uses the key `pulumi` of the transit engine in the
+// `platform` namespace.
+package vault
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/vault/api"
+	"github.com/pkg/errors"
+	"gocloud.dev/gcerrors"
+	gosecrets "gocloud.dev/secrets"
+)
+
+// Scheme is the URL scheme of the Vault transit secrets provider.
+const Scheme = "vault"
+
+const (
+	envRoleID   = "VAULT_ROLE_ID"
+	envSecretID = "VAULT_SECRET_ID"
+)
+
+func init() {
+	gosecrets.DefaultURLMux().RegisterKeeper(Scheme, &urlOpener{})
+}
+
+// Transit encrypts and decrypts with a single key of a Vault transit secrets engine.
+type Transit struct {
+	client *api.Client
+	mount  string
+	key    string
+}
+
+// NewTransit returns a Transit that uses the given key of the transit engine mounted at mount.
+func NewTransit(client *api.Client, mount, key string) *Transit {
+	return &Transit{client: client, mount: strings.Trim(mount, "/"), key: key}
+}
+
+// OpenTransit returns a Transit for a `vault://` provider URL, authenticating with the Vault server as the URL
+// directs.
+func OpenTransit(u *url.URL) (*Transit, error) {
+	if u.Scheme != Scheme {
+		return nil, errors.Errorf("unsupported scheme %q; expected %q", u.Scheme, Scheme)
+	}
+	key := strings.Trim(path.Join(u.Host, u.Path), "/")
+	if key == "" {
+		return nil, errors.Errorf("%v does not name a transit key", u)
+	}
+
+	cfg := api.DefaultConfig()
+	if cfg.Error != nil {
+		return nil, cfg.Error
+	}
+	mount, auth, approleMount := "transit", "token", "approle"
+	var namespace string
+	for param, values := range u.Query() {
+		value := values[len(values)-1]
+		switch param {
+		case "address":
+			cfg.Address = value
+		case "namespace":
+			namespace = value
+		case "mount":
+			mount = value
+		case "auth":
+			auth = value
+		case "approleMount":
+			approleMount = value
+		default:
+			return nil, errors.Errorf("invalid query parameter %q", param)
+		}
+	}
+
+	client, err := api.NewClient(cfg)
+	if err != nil {
+		return nil, errors.Wrap(err, "creating Vault client")
+	}
+	if namespace != "" {
+		client.SetNamespace(namespace)
+	}
+
+	switch auth {
+	case "token":
+		if client.Token() == "" {
+			return nil, errors.Errorf("%s must be set to authenticate with Vault", api.EnvVaultToken)
+		}
+	case "approle":
+		if err := loginAppRole(client, approleMount); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.Errorf("unsupported auth method %q; expected \"token\" or \"approle\"", auth)
+	}
+
+	return NewTransit(client, mount, key), nil
+}
+
+// loginAppRole logs in with the AppRole auth method mounted at mount and sets the client's token on success.
+func loginAppRole(client *api.Client, mount string) error {
+	roleID, secretID := os.Getenv(envRoleID), os.Getenv(envSecretID)
+	if roleID == "" {
+		return errors.Errorf("%s must be set to authenticate with Vault using AppRole", envRoleID)
+	}
+
+	secret, err := client.Logical().Write(path.Join("auth", strings.Trim(mount, "/"), "login"),
+		map[string]interface{}{
+			"role_id":   roleID,
+			"secret_id": secretID,
+		})
+	if err != nil {
+		return errors.Wrap(err, "logging in to Vault with AppRole")
+	}
+	token, err := secret.TokenID()
+	if err != nil || token == "" {
+		return errors.New("logging in to Vault with AppRole: no token was returned")
+	}
+	client.SetToken(token)
+	return nil
+}
+
+// Encrypt encrypts plaintext, returning a Vault ciphertext of the form `vault:v<N>:...`.
+func (t *Transit) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	result, err := t.write("encrypt", map[string]interface{}{
+		"plaintext": base64.StdEncoding.EncodeToString(plaintext),
+	})
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, ok := result["ciphertext"].(string)
+	if !ok {
+		return nil, errors.New("Vault did not return a ciphertext")
+	}
+	return []byte(ciphertext), nil
+}
+
+// Decrypt decrypts a ciphertext returned by Encrypt.
+func (t *Transit) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	result, err := t.write("decrypt", map[string]interface{}{
+		"ciphertext": string(ciphertext),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decodePlaintext(result)
+}
+
+// EncryptBatch encrypts each of plaintexts in a single request to Vault. The ciphertexts are returned in the same
+// order as the plaintexts.
+func (t *Transit) EncryptBatch(ctx context.Context, plaintexts [][]byte) ([][]byte, error) {
+	inputs := make([]interface{}, len(plaintexts))
+	for i, pt := range plaintexts {
+		inputs[i] = map[string]interface{}{"plaintext": base64.StdEncoding.EncodeToString(pt)}
+	}
+	results, err := t.writeBatch("encrypt", inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	ciphertexts := make([][]byte, len(results))
+	for i, result := range results {
+		ciphertext, ok := result["ciphertext"].(string)
+		if !ok {
+			return nil, errors.Errorf("Vault did not return a ciphertext for batch item %d", i)
+		}
+		ciphertexts[i] = []byte(ciphertext)
+	}
+	return ciphertexts, nil
+}
+
+// DecryptBatch decrypts each of ciphertexts in a single request to Vault. The plaintexts are returned in the same
+// order as the ciphertexts.
+func (t *Transit) DecryptBatch(ctx context.Context, ciphertexts [][]byte) ([][]byte, error) {
+	inputs := make([]interface{}, len(ciphertexts))
+	for i, ct := range ciphertexts {
+		inputs[i] = map[string]interface{}{"ciphertext": string(ct)}
+	}
+	results, err := t.writeBatch("decrypt", inputs)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintexts := make([][]byte, len(results))
+	for i, result := range results {
+		pt, err := decodePlaintext(result)
+		if err != nil {
+			return nil, errors.Wrapf(err, "batch item %d", i)
+		}
+		plaintexts[i] = pt
+	}
+	return plaintexts, nil
+}
+
+func (t *Transit) write(op string, data map[string]interface{}) (map[string]interface{}, error) {
+	secret, err := t.client.Logical().Write(path.Join(t.mount, op, t.key), data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Vault transit %s with key %q", op, t.key)
+	}
+	if secret == nil || secret.Data == nil {
+		return nil, errors.Errorf("Vault transit %s with key %q returned no data", op, t.key)
+	}
+	return secret.Data, nil
+}
+
+func (t *Transit) writeBatch(op string, inputs []interface{}) ([]map[string]interface{}, error) {
+	if len(inputs) == 0 {
+		return nil, nil
+	}
+	data, err := t.write(op, map[string]interface{}{"batch_input": inputs})
+	if err != nil {
+		return nil, err
+	}
+
+	raw, ok := data["batch_results"].([]interface{})
+	if !ok || len(raw) != len(inputs) {
+		return nil, errors.Errorf("Vault transit %s with key %q returned %d results for %d inputs",
+			op, t.key, len(raw), len(inputs))
+	}
+	results := make([]map[string]interface{}, len(raw))
+	for i, r := range raw {
+		result, ok := r.(map[string]interface{})
+		if !ok {
+			return nil, errors.Errorf("Vault transit %s returned an invalid result for batch item %d", op, i)
+		}
+		if msg, ok := result["error"].(string); ok && msg != "" {
+			return nil, errors.Errorf("Vault transit %s failed for batch item %d: %s", op, i, msg)
+		}
+		results[i] = result
+	}
+	return results, nil
+}
+
+func decodePlaintext(result map[string]interface{}) ([]byte, error) {
+	encoded, ok := result["plaintext"].(string)
+	if !ok {
+		return nil, errors.New("Vault did not return a plaintext")
+	}
+	return base64.StdEncoding.DecodeString(encoded)
+}
+
+// urlOpener opens `vault://` keepers for gocloud.dev/secrets, so that the provider can be used anywhere a cloud
+// secrets provider can.
+type urlOpener struct{}
+
+func (o *urlOpener) OpenKeeperURL(ctx context.Context, u *url.URL) (*gosecrets.Keeper, error) {
+	t, err := OpenTransit(u)
+	if err != nil {
+		return nil, fmt.Errorf("open keeper %v: %v", u, err)
+	}
+	return gosecrets.NewKeeper(&keeper{transit: t}), nil
+}
+
+// keeper implements gocloud.dev/secrets/driver.Keeper.
+type keeper struct {
+	transit *Transit
+}
+
+func (k *keeper) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	return k.transit.Decrypt(ctx, ciphertext)
+}
+
+func (k *keeper) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	return k.transit.Encrypt(ctx, plaintext)
+}
+
+func (k *keeper) Close() error                           { return nil }
+func (k *keeper) ErrorAs(err error, i interface{}) bool  { return false }
+func (k *keeper) ErrorCode(err error) gcerrors.ErrorCode { return gcerrors.Unknown }
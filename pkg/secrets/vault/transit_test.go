@@ -0,0 +1,192 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	gosecrets "gocloud.dev/secrets"
+)
+
+// newFakeVault returns a server that implements enough of the transit engine and the AppRole auth method for tests.
+// Ciphertexts are the base64-encoded plaintexts prefixed with `vault:v1:` and the key's name.
+func newFakeVault(t *testing.T, namespace string) *httptest.Server {
+	transform := func(op, key string, item map[string]interface{}) map[string]interface{} {
+		if op == "encrypt" {
+			return map[string]interface{}{"ciphertext": "vault:v1:" + key + ":" + item["plaintext"].(string)}
+		}
+		ct := item["ciphertext"].(string)
+		prefix := "vault:v1:" + key + ":"
+		if !strings.HasPrefix(ct, prefix) {
+			return map[string]interface{}{"error": "invalid ciphertext"}
+		}
+		return map[string]interface{}{"plaintext": strings.TrimPrefix(ct, prefix)}
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if r.Header.Get("X-Vault-Namespace") != namespace {
+			http.Error(w, `{"errors":["wrong namespace"]}`, http.StatusForbidden)
+			return
+		}
+
+		var resp map[string]interface{}
+		parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/v1/"), "/")
+		switch {
+		case r.URL.Path == "/v1/auth/approle/login":
+			if body["role_id"] != "role" || body["secret_id"] != "secret" {
+				http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+				return
+			}
+			resp = map[string]interface{}{"auth": map[string]interface{}{"client_token": "approle-token"}}
+		case len(parts) == 3 && parts[0] == "transit":
+			if tok := r.Header.Get("X-Vault-Token"); tok != "token" && tok != "approle-token" {
+				http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+				return
+			}
+			op, key := parts[1], parts[2]
+			if batch, ok := body["batch_input"].([]interface{}); ok {
+				results := make([]interface{}, len(batch))
+				for i, item := range batch {
+					results[i] = transform(op, key, item.(map[string]interface{}))
+				}
+				resp = map[string]interface{}{"data": map[string]interface{}{"batch_results": results}}
+			} else {
+				result := transform(op, key, body)
+				if msg, ok := result["error"]; ok {
+					http.Error(w, `{"errors":["`+msg.(string)+`"]}`, http.StatusBadRequest)
+					return
+				}
+				resp = map[string]interface{}{"data": result}
+			}
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		assert.NoError(t, json.NewEncoder(w).Encode(resp))
+	}))
+}
+
+// setenv sets an environment variable for the duration of a test.
+func setenv(t *testing.T, key, value string) {
+	old, had := os.LookupEnv(key)
+	t.Cleanup(func() {
+		if had {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+	os.Setenv(key, value)
+}
+
+func mustParseURL(t *testing.T, s string) *url.URL {
+	u, err := url.Parse(s)
+	assert.NoError(t, err)
+	return u
+}
+
+func TestTransitToken(t *testing.T) {
+	server := newFakeVault(t, "")
+	defer server.Close()
+	setenv(t, "VAULT_TOKEN", "token")
+
+	transit, err := OpenTransit(mustParseURL(t, "vault://mykey?address="+url.QueryEscape(server.URL)))
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	ct, err := transit.Encrypt(ctx, []byte("hunter2"))
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(ct), "vault:v1:mykey:"))
+	pt, err := transit.Decrypt(ctx, ct)
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", string(pt))
+
+	_, err = transit.Decrypt(ctx, []byte("vault:v1:otherkey:aGk="))
+	assert.Error(t, err)
+}
+
+func TestTransitBatch(t *testing.T) {
+	server := newFakeVault(t, "")
+	defer server.Close()
+	setenv(t, "VAULT_TOKEN", "token")
+
+	transit, err := OpenTransit(mustParseURL(t, "vault://mykey?address="+url.QueryEscape(server.URL)))
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	cts, err := transit.EncryptBatch(ctx, [][]byte{[]byte("a"), []byte("b"), []byte("c")})
+	assert.NoError(t, err)
+	assert.Len(t, cts, 3)
+	pts, err := transit.DecryptBatch(ctx, cts)
+	assert.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("a"), []byte("b"), []byte("c")}, pts)
+
+	_, err = transit.DecryptBatch(ctx, [][]byte{cts[0], []byte("vault:v1:otherkey:aGk=")})
+	assert.EqualError(t, err, "Vault transit decrypt failed for batch item 1: invalid ciphertext")
+
+	pts, err = transit.DecryptBatch(ctx, nil)
+	assert.NoError(t, err)
+	assert.Empty(t, pts)
+}
+
+func TestTransitAppRoleNamespace(t *testing.T) {
+	server := newFakeVault(t, "platform")
+	defer server.Close()
+	setenv(t, "VAULT_TOKEN", "")
+	setenv(t, "VAULT_ROLE_ID", "role")
+	setenv(t, "VAULT_SECRET_ID", "secret")
+
+	u := "vault://mykey?auth=approle&namespace=platform&address=" + url.QueryEscape(server.URL)
+	ctx := context.Background()
+	keeper, err := gosecrets.OpenKeeper(ctx, u)
+	assert.NoError(t, err)
+	ct, err := keeper.Encrypt(ctx, []byte("hunter2"))
+	assert.NoError(t, err)
+	pt, err := keeper.Decrypt(ctx, ct)
+	assert.NoError(t, err)
+	assert.Equal(t, "hunter2", string(pt))
+
+	setenv(t, "VAULT_SECRET_ID", "wrong")
+	_, err = OpenTransit(mustParseURL(t, u))
+	assert.Error(t, err)
+}
+
+func TestOpenTransitErrors(t *testing.T) {
+	setenv(t, "VAULT_TOKEN", "token")
+
+	_, err := OpenTransit(mustParseURL(t, "vault://"))
+	assert.Error(t, err)
+	_, err = OpenTransit(mustParseURL(t, "vault://mykey?region=us-east-1"))
+	assert.EqualError(t, err, `invalid query parameter "region"`)
+	_, err = OpenTransit(mustParseURL(t, "vault://mykey?auth=ldap"))
+	assert.Error(t, err)
+
+	setenv(t, "VAULT_TOKEN", "")
+	_, err = OpenTransit(mustParseURL(t, "vault://mykey"))
+	assert.EqualError(t, err, "VAULT_TOKEN must be set to authenticate with Vault")
+}
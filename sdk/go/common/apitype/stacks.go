@@ -71,6 +71,18 @@ type DecryptValueResponse struct {
 	Plaintext []byte `json:"plaintext"`
 }
 
+// BatchDecryptRequest defines the request body for decrypting several values at once.
+type BatchDecryptRequest struct {
+	// The values to decrypt.
+	Ciphertexts [][]byte `json:"ciphertexts"`
+}
+
+// BatchDecryptResponse defines the response body for a batch of decrypted values.
+type BatchDecryptResponse struct {
+	// The decrypted values, keyed by the base64-encoded ciphertext of each value.
+	Plaintexts map[string][]byte `json:"plaintexts"`
+}
+
 // ExportStackResponse defines the response body for exporting a Stack.
 type ExportStackResponse UntypedDeployment
 
@@ -0,0 +1,162 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// BlobStore stores the content of configuration values that are too large to keep in a stack file.
+type BlobStore interface {
+	// Read returns the content of the blob at uri.
+	Read(uri string) ([]byte, error)
+	// Write stores content as a blob with the given name, returning the URI from which it can be read.
+	Write(name string, content []byte) (string, error)
+}
+
+// fileBlobScheme is the URI scheme used by the blobs in a file blob store.
+const fileBlobScheme = "file:"
+
+// NewFileBlobStore returns a BlobStore that keeps each blob in a file within dir. Blob URIs are of the form
+// `file:<name>`, relative to dir, so the directory may be moved along with the stack file that refers to it.
+func NewFileBlobStore(dir string) BlobStore {
+	return &fileBlobStore{dir: dir}
+}
+
+type fileBlobStore struct {
+	dir string
+}
+
+func (s *fileBlobStore) path(name string) (string, error) {
+	// Blob names become file names, so they must not be able to escape the store's directory.
+	if name == "" || strings.ContainsAny(name, `/\:`) || name == "." || name == ".." {
+		return "", errors.Errorf("invalid blob name %q", name)
+	}
+	return filepath.Join(s.dir, name), nil
+}
+
+func (s *fileBlobStore) Read(uri string) ([]byte, error) {
+	if !strings.HasPrefix(uri, fileBlobScheme) {
+		return nil, errors.Errorf("unsupported blob URI %q", uri)
+	}
+	path, err := s.path(strings.TrimPrefix(uri, fileBlobScheme))
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadFile(path)
+}
+
+func (s *fileBlobStore) Write(name string, content []byte) (string, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return "", err
+	}
+	if err = os.MkdirAll(s.dir, 0700); err != nil {
+		return "", err
+	}
+	if err = ioutil.WriteFile(path, content, 0600); err != nil {
+		return "", err
+	}
+	return fileBlobScheme + name, nil
+}
+
+// BlobName returns a name suitable for storing the value of k in a BlobStore.
+func BlobName(k Key) string {
+	return strings.Replace(k.String(), ":", "_", -1)
+}
+
+// NewBlobRefValue returns a value whose content is held by the blob at uri, which must have the given hex-encoded
+// SHA-256 hash. If secure is true, the blob holds ciphertext. In a stack file, the value is written as a map with
+// `$blob`, `sha256` and (for secrets) `secure` properties.
+func NewBlobRefValue(uri, hash string, secure bool) Value {
+	return Value{value: hash, secure: secure, ref: uri}
+}
+
+// NewBlobValue stores content in the given store and returns a reference to it. If encrypter is non-nil, the content
+// is encrypted before it is stored and the result is a secure value.
+func NewBlobValue(store BlobStore, name, content string, encrypter Encrypter) (Value, error) {
+	secure := encrypter != nil
+	if secure {
+		ct, err := encrypter.EncryptValue(content)
+		if err != nil {
+			return Value{}, err
+		}
+		content = ct
+	}
+
+	uri, err := store.Write(name, []byte(content))
+	if err != nil {
+		return Value{}, errors.Wrap(err, "storing config value")
+	}
+	return NewBlobRefValue(uri, hashBlob([]byte(content)), secure), nil
+}
+
+// BlobRef returns the URI and hash of the blob that holds the value's content, if the value is a reference.
+func (c Value) BlobRef() (string, string, bool) {
+	return c.ref, c.value, c.isBlob()
+}
+
+// isBlob returns true if the value is a reference to an external blob.
+func (c Value) isBlob() bool {
+	return c.ref != "" && c.value != ""
+}
+
+// HasBlobRef returns true if the map contains a reference to an external blob.
+func (m Map) HasBlobRef() bool {
+	for _, v := range m {
+		if v.isBlob() {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveBlobs returns a copy of m in which every reference to an external blob is replaced by the blob's content,
+// read from store. It is an error for the content of a blob not to match the hash recorded in its reference.
+func (m Map) ResolveBlobs(store BlobStore) (Map, error) {
+	result := make(Map, len(m))
+	for k, v := range m {
+		if !v.isBlob() {
+			result[k] = v
+			continue
+		}
+
+		content, err := store.Read(v.ref)
+		if err != nil {
+			return nil, errors.Wrapf(err, "reading config key %v from %s", k, v.ref)
+		}
+		if hashBlob(content) != v.value {
+			return nil, errors.Errorf("config key %v: the content of %s does not match its recorded hash", k, v.ref)
+		}
+		if v.secure {
+			result[k] = NewSecureValue(string(content))
+		} else {
+			result[k] = NewValue(string(content))
+		}
+	}
+	return result, nil
+}
+
+func hashBlob(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,102 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestBlobValues(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config-blobs")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	store := NewFileBlobStore(dir)
+	cert := MustMakeKey("my", "cert")
+	kubeconfig := MustMakeKey("my", "kubeconfig")
+
+	certValue, err := NewBlobValue(store, BlobName(cert), "-----BEGIN CERTIFICATE-----", nil)
+	assert.NoError(t, err)
+	assert.False(t, certValue.Secure())
+	uri, hash, ok := certValue.BlobRef()
+	assert.True(t, ok)
+	assert.Equal(t, "file:my_cert", uri)
+	assert.Len(t, hash, 64)
+
+	kubeValue, err := NewBlobValue(store, BlobName(kubeconfig), "apiVersion: v1", newPrefixCrypter("enc-"))
+	assert.NoError(t, err)
+	assert.True(t, kubeValue.Secure())
+
+	m := Map{
+		cert:                      certValue,
+		kubeconfig:                kubeValue,
+		MustMakeKey("my", "name"): NewValue("inline"),
+	}
+	assert.True(t, m.HasBlobRef())
+
+	// References survive a round trip through a stack file.
+	b, err := yaml.Marshal(m)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "$blob: file:my_cert")
+	var roundtripped Map
+	assert.NoError(t, yaml.Unmarshal(b, &roundtripped))
+	assert.Equal(t, m, roundtripped)
+
+	// Reading an unresolved reference fails.
+	_, err = certValue.Value(NopDecrypter)
+	assert.Error(t, err)
+
+	resolved, err := roundtripped.ResolveBlobs(store)
+	assert.NoError(t, err)
+	assert.False(t, resolved.HasBlobRef())
+	assert.Equal(t, Map{
+		cert:                      NewValue("-----BEGIN CERTIFICATE-----"),
+		kubeconfig:                NewSecureValue("enc-apiVersion: v1"),
+		MustMakeKey("my", "name"): NewValue("inline"),
+	}, resolved)
+
+	// Tampered content is detected.
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "my_cert"), []byte("tampered"), 0600))
+	_, err = m.ResolveBlobs(store)
+	assert.Error(t, err)
+
+	_, err = store.Read("file:../escape")
+	assert.Error(t, err)
+}
+
+func TestPlainBlobObjects(t *testing.T) {
+	image := MustMakeKey("my", "image")
+
+	// Objects with ordinary `ref` and `sha256` properties are not references to external blobs.
+	var m Map
+	assert.NoError(t, yaml.Unmarshal([]byte("my:image:\n  ref: nginx\n  sha256: abc\n"), &m))
+	assert.False(t, m.HasBlobRef())
+	assert.True(t, m[image].Object())
+	v, err := m[image].Value(NopDecrypter)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"ref":"nginx","sha256":"abc"}`, v)
+
+	b, err := yaml.Marshal(m)
+	assert.NoError(t, err)
+	var loaded Map
+	assert.NoError(t, yaml.Unmarshal(b, &loaded))
+	assert.Equal(t, m, loaded)
+}
@@ -0,0 +1,125 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"github.com/pkg/errors"
+)
+
+// BoolSyntax selects which literals are recognized as booleans.
+type BoolSyntax int
+
+const (
+	// BoolSyntaxYAML12 recognizes only the booleans of YAML 1.2: `true` and `false`, optionally capitalized or in
+	// upper case. This is the default.
+	BoolSyntaxYAML12 BoolSyntax = iota
+	// BoolSyntaxYAML11 additionally recognizes the booleans of YAML 1.1: `y`, `yes`, `on`, `n`, `no` and `off`,
+	// optionally capitalized or in upper case.
+	BoolSyntaxYAML11
+)
+
+var boolSyntaxNames = map[BoolSyntax]string{
+	BoolSyntaxYAML12: "yaml1.2",
+	BoolSyntaxYAML11: "yaml1.1",
+}
+
+func (s BoolSyntax) String() string {
+	if name, ok := boolSyntaxNames[s]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// ParseBoolSyntax parses the name of a boolean syntax, as returned by BoolSyntax.String.
+func ParseBoolSyntax(s string) (BoolSyntax, error) {
+	for syntax, name := range boolSyntaxNames {
+		if name == s {
+			return syntax, nil
+		}
+	}
+	return 0, errors.Errorf("unknown boolean syntax %q; expected yaml1.1 or yaml1.2", s)
+}
+
+func (s BoolSyntax) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *BoolSyntax) UnmarshalText(b []byte) error {
+	syntax, err := ParseBoolSyntax(string(b))
+	if err != nil {
+		return err
+	}
+	*s = syntax
+	return nil
+}
+
+var yaml12Bools = map[string]bool{
+	"true": true, "True": true, "TRUE": true,
+	"false": false, "False": false, "FALSE": false,
+}
+
+var yaml11Bools = map[string]bool{
+	"y": true, "Y": true, "yes": true, "Yes": true, "YES": true, "on": true, "On": true, "ON": true,
+	"n": false, "N": false, "no": false, "No": false, "NO": false, "off": false, "Off": false, "OFF": false,
+}
+
+// ParseBool parses s as a boolean literal of the given syntax.
+func ParseBool(s string, syntax BoolSyntax) (bool, error) {
+	if b, ok := yaml12Bools[s]; ok {
+		return b, nil
+	}
+	if syntax == BoolSyntaxYAML11 {
+		if b, ok := yaml11Bools[s]; ok {
+			return b, nil
+		}
+	}
+	return false, errors.Errorf("%q is not a valid %v bool", s, syntax)
+}
+
+// AsBoolSyntax returns the value as a bool, using decrypter to decrypt it if necessary. Unlike AsBool, which accepts
+// the literals of strconv.ParseBool, only the literals of the given syntax are accepted.
+func (c Value) AsBoolSyntax(decrypter Decrypter, syntax BoolSyntax) (bool, error) {
+	v, err := c.scalar(decrypter)
+	if err != nil {
+		return false, err
+	}
+	b, err := ParseBool(v, syntax)
+	if err != nil {
+		return false, errors.Wrap(err, "invalid config value")
+	}
+	return b, nil
+}
+
+// NormalizeBools returns a copy of m in which every top-level plaintext value that was written in a stack file as an
+// unquoted boolean literal of the given syntax is replaced by the boolean it denotes, so that programs and providers
+// see `true` or `false` regardless of how the literal was written. Under BoolSyntaxYAML12, the default, m is returned
+// unchanged. Quoted strings, values within objects and secure values are never rewritten, as they may be required to
+// be strings (e.g. Kubernetes labels).
+func (m Map) NormalizeBools(syntax BoolSyntax) (Map, error) {
+	if syntax != BoolSyntaxYAML11 {
+		return m, nil
+	}
+	result := make(Map, len(m))
+	for k, v := range m {
+		result[k] = v
+		if !v.unquotedBool || v.secure || v.object || v.ref != "" {
+			continue
+		}
+		if b, err := ParseBool(v.value, syntax); err == nil {
+			result[k] = NewBoolValue(b)
+		}
+	}
+	return result, nil
+}
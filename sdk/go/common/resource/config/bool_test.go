@@ -0,0 +1,97 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
+)
+
+func TestParseBool(t *testing.T) {
+	for _, s := range []string{"true", "True", "TRUE"} {
+		for _, syntax := range []BoolSyntax{BoolSyntaxYAML12, BoolSyntaxYAML11} {
+			b, err := ParseBool(s, syntax)
+			assert.NoError(t, err)
+			assert.True(t, b)
+		}
+	}
+	for _, s := range []string{"yes", "On", "Y"} {
+		_, err := ParseBool(s, BoolSyntaxYAML12)
+		assert.Error(t, err)
+		b, err := ParseBool(s, BoolSyntaxYAML11)
+		assert.NoError(t, err)
+		assert.True(t, b)
+	}
+	for _, s := range []string{"no", "OFF", "n", "False"} {
+		b, err := ParseBool(s, BoolSyntaxYAML11)
+		assert.NoError(t, err)
+		assert.False(t, b)
+	}
+	for _, s := range []string{"1", "t", "yEs", ""} {
+		_, err := ParseBool(s, BoolSyntaxYAML11)
+		assert.Error(t, err)
+	}
+
+	b, err := NewSecureValue("enc-on").AsBoolSyntax(newPrefixCrypter("enc-"), BoolSyntaxYAML11)
+	assert.NoError(t, err)
+	assert.True(t, b)
+	_, err = NewValue("on").AsBoolSyntax(NopDecrypter, BoolSyntaxYAML12)
+	assert.Error(t, err)
+
+	syntax, err := ParseBoolSyntax("yaml1.1")
+	assert.NoError(t, err)
+	assert.Equal(t, BoolSyntaxYAML11, syntax)
+	_, err = ParseBoolSyntax("yaml")
+	assert.Error(t, err)
+}
+
+func TestNormalizeBools(t *testing.T) {
+	var m Map
+	assert.NoError(t, yaml.Unmarshal([]byte(`my:enabled: yes
+my:debug: False
+my:quoted: "yes"
+my:flag: "True"
+my:name: yesterday
+my:count: 1
+my:secret:
+  secure: on
+my:labels:
+  enabled: "true"
+  name: "TRUE"
+`), &m))
+
+	r, err := m.NormalizeBools(BoolSyntaxYAML11)
+	assert.NoError(t, err)
+	assert.Equal(t, NewBoolValue(true), r[MustMakeKey("my", "enabled")])
+	assert.Equal(t, NewBoolValue(false), r[MustMakeKey("my", "debug")])
+	// Quoted strings, other strings and values within objects are left as they are.
+	for _, name := range []string{"quoted", "flag", "name", "count", "secret", "labels"} {
+		k := MustMakeKey("my", name)
+		assert.Equal(t, m[k], r[k], name)
+	}
+	v, err := r[MustMakeKey("my", "labels")].Value(NopDecrypter)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"enabled":"true","name":"TRUE"}`, v)
+
+	// YAML 1.2, the default, leaves every value as it is written.
+	r, err = m.NormalizeBools(BoolSyntaxYAML12)
+	assert.NoError(t, err)
+	assert.Equal(t, m, r)
+	v, err = r[MustMakeKey("my", "debug")].Value(NopDecrypter)
+	assert.NoError(t, err)
+	assert.Equal(t, "False", v)
+}
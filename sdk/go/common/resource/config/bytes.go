@@ -0,0 +1,83 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/base64"
+
+	"github.com/pkg/errors"
+)
+
+// NewBytesValue returns a binary value holding b, for small binary payloads such as DER-encoded certificates. The
+// value's text (as returned by Value) is the base64 encoding of b. In a stack file, the value is written as a map
+// with a single `$binary` property holding that text.
+func NewBytesValue(b []byte) Value {
+	return Value{value: base64.StdEncoding.EncodeToString(b), typ: TypeBytes}
+}
+
+// NewSecureBytesValue returns a secret binary value holding b, encrypted using encrypter. The base64 encoding of b is
+// encrypted, so decrypting the value yields the same text as Value does for NewBytesValue. In a stack file, the value
+// is written as a map with `secure` and `$binary: true` properties.
+func NewSecureBytesValue(b []byte, encrypter Encrypter) (Value, error) {
+	ct, err := encrypter.EncryptValue(base64.StdEncoding.EncodeToString(b))
+	if err != nil {
+		return Value{}, err
+	}
+	return Value{value: ct, secure: true, typ: TypeBytes}, nil
+}
+
+// AsBytes returns the contents of a binary value, using decrypter to decrypt it if necessary. Other scalar values
+// are decoded from base64.
+func (c Value) AsBytes(decrypter Decrypter) ([]byte, error) {
+	v, err := c.scalar(decrypter)
+	if err != nil {
+		return nil, err
+	}
+	b, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return nil, errors.New("config value is not valid base64")
+	}
+	return b, nil
+}
+
+// binaryKey is the reserved property that marks a binary value in a stack file.
+const binaryKey = "$binary"
+
+func (c Value) marshalBinaryValue() interface{} {
+	if c.secure {
+		return map[string]interface{}{"secure": c.value, binaryKey: true}
+	}
+	return map[string]string{binaryKey: c.value}
+}
+
+// isBinaryValue returns true if v is the stack file representation of a binary value, along with the value's base64
+// text (or its ciphertext, for a secret) and whether it is secret.
+func isBinaryValue(v interface{}) (bool, string, bool) {
+	m, isMap := v.(map[string]interface{})
+	if !isMap {
+		return false, "", false
+	}
+	switch len(m) {
+	case 1:
+		text, ok := m[binaryKey].(string)
+		return ok, text, false
+	case 2:
+		ct, ctOK := m["secure"].(string)
+		binary, binaryOK := m[binaryKey].(bool)
+		return ctOK && binaryOK && binary, ct, true
+	default:
+		return false, "", false
+	}
+}
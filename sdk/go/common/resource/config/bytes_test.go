@@ -0,0 +1,101 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestBytesValues(t *testing.T) {
+	der := []byte{0x30, 0x82, 0x01, 0x0a, 0x00, 0xff}
+	crypter := newPrefixCrypter("enc-")
+
+	plain := NewBytesValue(der)
+	assert.Equal(t, TypeBytes, plain.Type())
+	text, err := plain.Value(NopDecrypter)
+	assert.NoError(t, err)
+	assert.Equal(t, "MIIBCgD/", text)
+
+	secret, err := NewSecureBytesValue(der, crypter)
+	assert.NoError(t, err)
+	assert.True(t, secret.Secure())
+	assert.Equal(t, TypeBytes, secret.Type())
+
+	for _, v := range []Value{plain, secret} {
+		b, err := v.AsBytes(crypter)
+		assert.NoError(t, err)
+		assert.Equal(t, der, b)
+	}
+
+	m := Map{MustMakeKey("my", "cert"): plain, MustMakeKey("my", "key"): secret}
+
+	b, err := yaml.Marshal(m)
+	assert.NoError(t, err)
+	assert.Equal(t, "my:cert:\n  $binary: MIIBCgD/\nmy:key:\n  $binary: true\n  secure: enc-MIIBCgD/\n", string(b))
+	var fromYAML Map
+	assert.NoError(t, yaml.Unmarshal(b, &fromYAML))
+	assert.Equal(t, m, fromYAML)
+
+	b, err = json.Marshal(m)
+	assert.NoError(t, err)
+	var fromJSON Map
+	assert.NoError(t, json.Unmarshal(b, &fromJSON))
+	assert.Equal(t, m, fromJSON)
+
+	// Secret binary values stay binary when they are re-encrypted.
+	copied, err := secret.Copy(crypter, newPrefixCrypter("new-"))
+	assert.NoError(t, err)
+	assert.Equal(t, TypeBytes, copied.Type())
+	b, err = copied.AsBytes(newPrefixCrypter("new-"))
+	assert.NoError(t, err)
+	assert.Equal(t, der, b)
+
+	typed, err := NewTypedValue("MIIBCgD/", TypeBytes)
+	assert.NoError(t, err)
+	assert.Equal(t, plain, typed)
+	_, err = NewTypedValue("not base64!", TypeBytes)
+	assert.Error(t, err)
+	_, err = NewValue("not base64!").AsBytes(NopDecrypter)
+	assert.Error(t, err)
+}
+
+func TestPlainBinaryObjects(t *testing.T) {
+	// Objects with an ordinary `binary` property are not binary values, and keep their shape.
+	tool, key := MustMakeKey("my", "tool"), MustMakeKey("my", "key")
+	var m Map
+	assert.NoError(t, yaml.Unmarshal([]byte(`
+my:tool:
+  binary: hello
+my:key:
+  binary: true
+  secure: enc-hello
+`), &m))
+	assert.True(t, m[tool].Object())
+	assert.NotEqual(t, TypeBytes, m[tool].Type())
+	v, err := m[tool].Value(NopDecrypter)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"binary":"hello"}`, v)
+	assert.True(t, m[key].Object())
+
+	b, err := yaml.Marshal(m)
+	assert.NoError(t, err)
+	var loaded Map
+	assert.NoError(t, yaml.Unmarshal(b, &loaded))
+	assert.Equal(t, m, loaded)
+}
@@ -0,0 +1,127 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+)
+
+// PlaintextCache holds previously decrypted plaintexts, keyed by the hex-encoded SHA-256 hash of their ciphertexts.
+// Keying by hash means that a cache never holds ciphertexts, and that a plaintext is shared by every value with the
+// same ciphertext, whichever key or stack it belongs to.
+//
+// Implementations must be safe for concurrent use. NewPlaintextCache returns one that lives in memory for the life of
+// the process; implementations that persist plaintexts must protect them, e.g. with a key held in an OS keychain.
+type PlaintextCache interface {
+	// Get returns the plaintext whose ciphertext has the given hash, if it is cached.
+	Get(hash string) (string, bool)
+	// Put records the plaintext whose ciphertext has the given hash.
+	Put(hash, plaintext string)
+}
+
+// NewPlaintextCache returns an empty in-memory PlaintextCache.
+func NewPlaintextCache() PlaintextCache {
+	return &memoryPlaintextCache{plaintexts: make(map[string]string)}
+}
+
+type memoryPlaintextCache struct {
+	mu         sync.RWMutex
+	plaintexts map[string]string
+}
+
+func (c *memoryPlaintextCache) Get(hash string) (string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	pt, ok := c.plaintexts[hash]
+	return pt, ok
+}
+
+func (c *memoryPlaintextCache) Put(hash, plaintext string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.plaintexts[hash] = plaintext
+}
+
+// NewCachingDecrypter returns a Decrypter that serves plaintexts from cache where it can, and otherwise decrypts with
+// decrypter and caches the result. Repeated reads of the same secret, e.g. during a preview and then an update, only
+// reach the secrets provider once. If decrypter is a BulkDecrypter, so is the result, and only the ciphertexts that
+// are not cached are passed on.
+func NewCachingDecrypter(decrypter Decrypter, cache PlaintextCache) Decrypter {
+	return &cachingDecrypter{decrypter: decrypter, cache: cache}
+}
+
+type cachingDecrypter struct {
+	decrypter Decrypter
+	cache     PlaintextCache
+}
+
+func (c *cachingDecrypter) DecryptValue(ciphertext string) (string, error) {
+	hash := hashCiphertext(ciphertext)
+	if pt, ok := c.cache.Get(hash); ok {
+		return pt, nil
+	}
+
+	pt, err := c.decrypter.DecryptValue(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	c.cache.Put(hash, pt)
+	return pt, nil
+}
+
+func (c *cachingDecrypter) BulkDecrypt(ctx context.Context, ciphertexts []string) (map[string]string, error) {
+	result := make(map[string]string, len(ciphertexts))
+	var missing []string
+	for _, ct := range ciphertexts {
+		if pt, ok := c.cache.Get(hashCiphertext(ct)); ok {
+			result[ct] = pt
+		} else {
+			missing = append(missing, ct)
+		}
+	}
+	if len(missing) == 0 {
+		return result, nil
+	}
+
+	bulk, ok := c.decrypter.(BulkDecrypter)
+	if !ok {
+		for _, ct := range missing {
+			pt, err := c.DecryptValue(ct)
+			if err != nil {
+				return nil, err
+			}
+			result[ct] = pt
+		}
+		return result, nil
+	}
+
+	plaintexts, err := bulk.BulkDecrypt(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+	for ct, pt := range plaintexts {
+		c.cache.Put(hashCiphertext(ct), pt)
+		result[ct] = pt
+	}
+	return result, nil
+}
+
+func hashCiphertext(ciphertext string) string {
+	sum := sha256.Sum256([]byte(ciphertext))
+	return hex.EncodeToString(sum[:])
+}
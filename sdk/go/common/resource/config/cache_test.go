@@ -0,0 +1,66 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachingDecrypter(t *testing.T) {
+	cache := NewPlaintextCache()
+
+	inner := &countingDecrypter{}
+	d := NewCachingDecrypter(inner, cache)
+	for i := 0; i < 2; i++ {
+		pt, err := d.DecryptValue("a")
+		assert.NoError(t, err)
+		assert.Equal(t, "plain-a", pt)
+	}
+	assert.Equal(t, []string{"a"}, inner.decrypted)
+
+	// The cache is keyed by the hash of each ciphertext, so it can be shared by decrypters.
+	_, ok := cache.Get("a")
+	assert.False(t, ok)
+	pt, ok := cache.Get(hashCiphertext("a"))
+	assert.True(t, ok)
+	assert.Equal(t, "plain-a", pt)
+
+	// Only the ciphertexts that are not cached reach a bulk decrypter, and cached plaintexts are reused when a whole
+	// map is decrypted again.
+	bulk := &bulkDecrypter{}
+	m := Map{
+		MustMakeKey("my", "a"): NewSecureValue("a"),
+		MustMakeKey("my", "b"): NewSecureValue("b"),
+	}
+	for i := 0; i < 2; i++ {
+		r, err := m.DecryptAll(context.Background(), NewCachingDecrypter(bulk, cache))
+		assert.NoError(t, err)
+		assert.Equal(t, map[Key]string{
+			MustMakeKey("my", "a"): "plain-a",
+			MustMakeKey("my", "b"): "plain-b",
+		}, r)
+	}
+	assert.Equal(t, [][]string{{"b"}}, bulk.batches)
+
+	// A decrypter that cannot bulk decrypt is called once for each missing ciphertext.
+	r, err := NewCachingDecrypter(inner, cache).(BulkDecrypter).BulkDecrypt(context.Background(),
+		[]string{"a", "b", "c"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"a": "plain-a", "b": "plain-b", "c": "plain-c"}, r)
+	assert.Equal(t, []string{"a", "c"}, inner.decrypted)
+}
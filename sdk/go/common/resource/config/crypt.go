@@ -15,6 +15,7 @@
 package config
 
 import (
+	"context"
 	"crypto/aes"
 	"crypto/cipher"
 	cryptorand "crypto/rand"
@@ -38,6 +39,32 @@ type Decrypter interface {
 	DecryptValue(ciphertext string) (string, error)
 }
 
+// BulkDecrypter is a Decrypter that can decrypt many values in a single operation, which is much cheaper than
+// decrypting them one at a time when each decryption involves a network round trip.
+type BulkDecrypter interface {
+	Decrypter
+
+	// BulkDecrypt decrypts the given ciphertexts, returning a map from each ciphertext to its plaintext.
+	BulkDecrypt(ctx context.Context, ciphertexts []string) (map[string]string, error)
+}
+
+// DecryptValues decrypts the given ciphertexts using decrypter, in a single operation if it is a BulkDecrypter and
+// one at a time otherwise, returning a map from each ciphertext to its plaintext.
+func DecryptValues(ctx context.Context, decrypter Decrypter, ciphertexts []string) (map[string]string, error) {
+	if bulk, ok := decrypter.(BulkDecrypter); ok {
+		return bulk.BulkDecrypt(ctx, ciphertexts)
+	}
+	result := make(map[string]string, len(ciphertexts))
+	for _, ct := range ciphertexts {
+		pt, err := decrypter.DecryptValue(ct)
+		if err != nil {
+			return nil, err
+		}
+		result[ct] = pt
+	}
+	return result, nil
+}
+
 // Crypter can both encrypt and decrypt values.
 type Crypter interface {
 	Encrypter
@@ -58,6 +85,17 @@ func (nopCrypter) EncryptValue(plaintext string) (string, error) {
 	return plaintext, nil
 }
 
+// cachedDecrypter decrypts values using a map of previously decrypted ciphertexts.
+type cachedDecrypter map[string]string
+
+func (c cachedDecrypter) DecryptValue(ciphertext string) (string, error) {
+	plaintext, ok := c[ciphertext]
+	if !ok {
+		return "", errors.New("ciphertext was not decrypted")
+	}
+	return plaintext, nil
+}
+
 // TrackingDecrypter is a Decrypter that keeps track if decrypted values, which
 // can be retrieved via SecureValues().
 type TrackingDecrypter interface {
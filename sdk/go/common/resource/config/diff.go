@@ -0,0 +1,62 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"sort"
+)
+
+// MapDiff describes the differences between two configuration maps. Each list of keys is sorted.
+type MapDiff struct {
+	// Added holds the keys that are only set in the new map.
+	Added []Key
+	// Removed holds the keys that are only set in the old map.
+	Removed []Key
+	// Changed holds the keys that are set to different values in each map.
+	Changed []Key
+}
+
+// Empty returns true if the maps are identical.
+func (d MapDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// Diff returns the changes needed to turn m into other.
+//
+// Values are compared with Equal, so secure values are compared by their ciphertexts and no decryption is required.
+// Because encryption is not deterministic, a secret that has been re-encrypted is reported as changed even if its
+// plaintext is the same.
+func (m Map) Diff(other Map) MapDiff {
+	var added, removed, changed KeyArray
+	for k, v := range m {
+		o, ok := other[k]
+		switch {
+		case !ok:
+			removed = append(removed, k)
+		case !o.Equal(v):
+			changed = append(changed, k)
+		}
+	}
+	for k := range other {
+		if _, ok := m[k]; !ok {
+			added = append(added, k)
+		}
+	}
+
+	sort.Sort(added)
+	sort.Sort(removed)
+	sort.Sort(changed)
+	return MapDiff{Added: added, Removed: removed, Changed: changed}
+}
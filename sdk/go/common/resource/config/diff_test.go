@@ -0,0 +1,52 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	before := Map{
+		MustMakeKey("my", "same"):    NewValue("value"),
+		MustMakeKey("my", "changed"): NewValue("before"),
+		MustMakeKey("my", "secret"):  NewSecureValue("ciphertext1"),
+		MustMakeKey("my", "stable"):  NewSecureValue("ciphertext2"),
+		MustMakeKey("my", "removed"): NewValue("gone"),
+		MustMakeKey("my", "object"):  NewObjectValue(`{"a":"b"}`),
+	}
+	after := Map{
+		MustMakeKey("my", "same"):    NewValue("value"),
+		MustMakeKey("my", "changed"): NewValue("after"),
+		MustMakeKey("my", "secret"):  NewSecureValue("ciphertext3"),
+		MustMakeKey("my", "stable"):  NewSecureValue("ciphertext2"),
+		MustMakeKey("my", "added"):   NewValue("new"),
+		MustMakeKey("my", "object"):  NewObjectValue(`{"a":"c"}`),
+	}
+
+	d := before.Diff(after)
+	assert.False(t, d.Empty())
+	assert.Equal(t, []Key{MustMakeKey("my", "added")}, d.Added)
+	assert.Equal(t, []Key{MustMakeKey("my", "removed")}, d.Removed)
+	assert.Equal(t, []Key{
+		MustMakeKey("my", "changed"),
+		MustMakeKey("my", "object"),
+		MustMakeKey("my", "secret"),
+	}, d.Changed)
+
+	assert.True(t, before.Diff(before).Empty())
+}
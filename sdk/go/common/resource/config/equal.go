@@ -0,0 +1,95 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
+)
+
+// Equal returns true if c and other are the same value. Objects are compared by their content, so the order of their
+// properties is insignificant, and typed scalars by their normalized content, so the bool `true` is equal to the string
+// "true", and the int 42 to the string "42". Binary values are only equal to other binary values. Secure values are
+// compared by their ciphertexts, so no decryption is required, and in constant time, so the comparison does not reveal
+// how much of a ciphertext matched. Because encryption is not deterministic, a secret that has been re-encrypted is not
+// equal to the original; callers that hold the plaintexts of both values should compare them with EqualPlaintext
+// instead.
+func (c Value) Equal(other Value) bool {
+	if c.secure != other.secure || c.object != other.object || c.isBytes() != other.isBytes() || c.ref != other.ref {
+		return false
+	}
+	a, b := c.canonical(), other.canonical()
+	if c.secure {
+		return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+	}
+	return a == b
+}
+
+// EqualPlaintext returns true if two decrypted secrets are the same, comparing them in constant time.
+func EqualPlaintext(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
+
+// Hash returns a stable, hex-encoded hash of the value, suitable for use as a cache key. Values that are Equal have the
+// same hash. The hash of a secure value is derived from its ciphertext, so computing it requires no decryption and
+// reveals nothing about the plaintext.
+func (c Value) Hash() string {
+	h := sha256.New()
+	for _, field := range []string{
+		strconv.FormatBool(c.isBytes()),
+		strconv.FormatBool(c.secure),
+		strconv.FormatBool(c.object),
+		c.ref,
+		c.canonical(),
+	} {
+		// Prefix each field with its length so that no two sets of fields share an encoding.
+		_, err := h.Write([]byte(strconv.Itoa(len(field)) + ":" + field))
+		contract.AssertNoError(err)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// isBytes returns true if c is a binary value, which Equal never considers equal to a value of any other type.
+func (c Value) isBytes() bool {
+	return c.typ == TypeBytes
+}
+
+// canonical returns the value's raw content in a canonical form. Typed scalars are re-formatted as their type's
+// constructor would format them (e.g. a bool written as `True` becomes `true`), and objects are re-encoded with their
+// properties sorted.
+func (c Value) canonical() string {
+	if !c.object {
+		if !c.secure && c.ref == "" && c.typ != TypeString {
+			if v, err := NewTypedValue(c.value, c.typ); err == nil {
+				return v.value
+			}
+		}
+		return c.value
+	}
+	var obj interface{}
+	if err := json.Unmarshal([]byte(c.value), &obj); err != nil {
+		return c.value
+	}
+	b, err := json.Marshal(obj)
+	if err != nil {
+		return c.value
+	}
+	return string(b)
+}
@@ -0,0 +1,67 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValueEqual(t *testing.T) {
+	tests := []struct {
+		a, b  Value
+		equal bool
+	}{
+		{NewValue("a"), NewValue("a"), true},
+		{NewValue("a"), NewValue("b"), false},
+		{NewValue("42"), NewIntValue(42), true},
+		{NewValue("43"), NewIntValue(42), false},
+		{NewValue("true"), NewBoolValue(true), true},
+		{NewValue("false"), NewBoolValue(true), false},
+		{Value{value: "True", typ: TypeBool}, NewBoolValue(true), true},
+		{Value{value: "1.50", typ: TypeFloat}, NewValue("1.5"), true},
+		{NewBytesValue([]byte("a")), NewValue(base64.StdEncoding.EncodeToString([]byte("a"))), false},
+		{NewBytesValue([]byte("a")), NewBytesValue([]byte("a")), true},
+		{NewIntValue(42), NewIntValue(42), true},
+		{NewSecureValue("ciphertext"), NewSecureValue("ciphertext"), true},
+		{NewSecureValue("ciphertext"), NewSecureValue("ciphertexts"), false},
+		{NewSecureValue("ciphertext"), NewValue("ciphertext"), false},
+		{NewObjectValue(`{"a":1,"b":2}`), NewObjectValue(`{"b":2,"a":1}`), true},
+		{NewObjectValue(`{"a":1}`), NewObjectValue(`{"a":2}`), false},
+		{NewSecureObjectValue(`{"a":{"secure":"x"}}`), NewSecureObjectValue(`{ "a": {"secure": "x"} }`), true},
+		{NewObjectValue(`"a"`), NewValue(`"a"`), false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.equal, tt.a.Equal(tt.b), "%v == %v", tt.a, tt.b)
+		assert.Equal(t, tt.equal, tt.b.Equal(tt.a), "%v == %v", tt.b, tt.a)
+		assert.Equal(t, tt.equal, tt.a.Hash() == tt.b.Hash(), "hash(%v) == hash(%v)", tt.a, tt.b)
+	}
+}
+
+func TestValueHashStable(t *testing.T) {
+	v := NewSecureValue("ciphertext")
+	assert.Equal(t, v.Hash(), NewSecureValue("ciphertext").Hash())
+	assert.Len(t, v.Hash(), 64)
+	assert.NotContains(t, v.Hash(), "ciphertext")
+}
+
+func TestEqualPlaintext(t *testing.T) {
+	assert.True(t, EqualPlaintext("hunter2", "hunter2"))
+	assert.False(t, EqualPlaintext("hunter2", "hunter3"))
+	assert.False(t, EqualPlaintext("hunter2", "hunter"))
+	assert.True(t, EqualPlaintext("", ""))
+}
@@ -0,0 +1,115 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// NewFallbackDecrypter returns a Decrypter that tries each of decrypters in order, returning the plaintext from the
+// first that succeeds. It lets a stack read values that were encrypted by a previous secrets provider while it
+// migrates to a new one, and keep reading them while one of its providers is unavailable.
+//
+// If every decrypter fails, the error from the first is returned, as the first is normally the primary provider.
+func NewFallbackDecrypter(decrypters ...Decrypter) Decrypter {
+	if len(decrypters) == 1 {
+		return decrypters[0]
+	}
+	return &fallbackDecrypter{decrypters: decrypters}
+}
+
+type fallbackDecrypter struct {
+	decrypters []Decrypter
+}
+
+func (f *fallbackDecrypter) DecryptValue(ciphertext string) (string, error) {
+	var firstErr error
+	for _, d := range f.decrypters {
+		plaintext, err := d.DecryptValue(ciphertext)
+		if err == nil {
+			return plaintext, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr == nil {
+		firstErr = errors.New("no secrets providers are available")
+	}
+	return "", firstErr
+}
+
+// BulkDecrypt passes the ciphertexts to each decrypter in turn, giving each only the ciphertexts that its predecessors
+// failed to decrypt. A BulkDecrypter that fails is retried one ciphertext at a time, so that the ciphertexts it can
+// decrypt are not passed on.
+func (f *fallbackDecrypter) BulkDecrypt(ctx context.Context, ciphertexts []string) (map[string]string, error) {
+	result := make(map[string]string, len(ciphertexts))
+	remaining := ciphertexts
+	var firstErr error
+	for _, d := range f.decrypters {
+		if len(remaining) == 0 {
+			break
+		}
+
+		if bulk, ok := d.(BulkDecrypter); ok {
+			plaintexts, err := bulk.BulkDecrypt(ctx, remaining)
+			if err == nil {
+				for ct, pt := range plaintexts {
+					result[ct] = pt
+				}
+				remaining = undecrypted(remaining, result)
+				continue
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+
+		var failed []string
+		for _, ct := range remaining {
+			pt, err := d.DecryptValue(ct)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				failed = append(failed, ct)
+				continue
+			}
+			result[ct] = pt
+		}
+		remaining = failed
+	}
+
+	if len(remaining) != 0 {
+		if firstErr == nil {
+			firstErr = errors.New("no secrets providers are available")
+		}
+		return nil, firstErr
+	}
+	return result, nil
+}
+
+// undecrypted returns the ciphertexts that have no plaintext in plaintexts.
+func undecrypted(ciphertexts []string, plaintexts map[string]string) []string {
+	var result []string
+	for _, ct := range ciphertexts {
+		if _, ok := plaintexts[ct]; !ok {
+			result = append(result, ct)
+		}
+	}
+	return result
+}
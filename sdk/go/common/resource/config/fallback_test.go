@@ -0,0 +1,93 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// strictDecrypter decrypts only the ciphertexts that carry its prefix, counting its bulk decryptions.
+type strictDecrypter struct {
+	prefix string
+	bulk   int
+}
+
+func (d *strictDecrypter) DecryptValue(ciphertext string) (string, error) {
+	if !strings.HasPrefix(ciphertext, d.prefix) {
+		return "", errors.Errorf("%s cannot decrypt %q", d.prefix, ciphertext)
+	}
+	return strings.TrimPrefix(ciphertext, d.prefix), nil
+}
+
+func (d *strictDecrypter) BulkDecrypt(ctx context.Context, ciphertexts []string) (map[string]string, error) {
+	d.bulk++
+	plaintexts := make(map[string]string, len(ciphertexts))
+	for _, ct := range ciphertexts {
+		pt, err := d.DecryptValue(ct)
+		if err != nil {
+			return nil, err
+		}
+		plaintexts[ct] = pt
+	}
+	return plaintexts, nil
+}
+
+func TestFallbackDecrypter(t *testing.T) {
+	primary, old := &strictDecrypter{prefix: "new:"}, &strictDecrypter{prefix: "old:"}
+	d := NewFallbackDecrypter(primary, old)
+
+	pt, err := d.DecryptValue("new:a")
+	assert.NoError(t, err)
+	assert.Equal(t, "a", pt)
+
+	pt, err = d.DecryptValue("old:b")
+	assert.NoError(t, err)
+	assert.Equal(t, "b", pt)
+
+	_, err = d.DecryptValue("other:c")
+	assert.EqualError(t, err, `new: cannot decrypt "other:c"`)
+
+	m := Map{
+		MustMakeKey("my", "new"):    NewSecureValue("new:a"),
+		MustMakeKey("my", "old"):    NewSecureValue("old:b"),
+		MustMakeKey("my", "object"): NewSecureObjectValue(`{"inner":{"secure":"old:c"}}`),
+	}
+	r, err := m.Decrypt(d)
+	assert.NoError(t, err)
+	assert.Equal(t, map[Key]string{
+		MustMakeKey("my", "new"):    "a",
+		MustMakeKey("my", "old"):    "b",
+		MustMakeKey("my", "object"): `{"inner":"c"}`,
+	}, r)
+
+	// Each decrypter is asked once, for the ciphertexts its predecessors could not decrypt.
+	primary.bulk, old.bulk = 0, 0
+	pts, err := d.(BulkDecrypter).BulkDecrypt(context.Background(), []string{"new:a", "old:b"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"new:a": "a", "old:b": "b"}, pts)
+	assert.Equal(t, 1, primary.bulk)
+	assert.Equal(t, 1, old.bulk)
+
+	_, err = d.(BulkDecrypter).BulkDecrypt(context.Background(), []string{"new:a", "other:c"})
+	assert.Error(t, err)
+
+	// A single decrypter needs no wrapping.
+	assert.Equal(t, Decrypter(primary), NewFallbackDecrypter(primary))
+}
@@ -0,0 +1,119 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// ErrFrozen is returned when attempting to modify a FrozenMap.
+var ErrFrozen = errors.New("configuration is frozen and may not be modified")
+
+// FrozenMap is an immutable configuration map. It offers the read-only operations of Map, and its mutating operations
+// always fail with ErrFrozen. The zero value is an empty FrozenMap.
+type FrozenMap struct {
+	m Map
+}
+
+// Freeze returns an immutable copy of m. Later changes to m are not reflected in the result.
+func (m Map) Freeze() FrozenMap {
+	return FrozenMap{m: m.Clone()}
+}
+
+// Map returns a mutable copy of the frozen map.
+func (f FrozenMap) Map() Map {
+	return f.m.Clone()
+}
+
+// Len returns the number of keys in the map.
+func (f FrozenMap) Len() int {
+	return len(f.m)
+}
+
+// Keys returns the keys in the map, in sorted order.
+func (f FrozenMap) Keys() []Key {
+	keys := make(KeyArray, 0, len(f.m))
+	for k := range f.m {
+		keys = append(keys, k)
+	}
+	sort.Sort(keys)
+	return keys
+}
+
+// Find is the read-only equivalent of Map.Find.
+func (f FrozenMap) Find(pattern string) []Key {
+	return f.m.Find(pattern)
+}
+
+// Get is the read-only equivalent of Map.Get.
+func (f FrozenMap) Get(k Key, path bool) (Value, bool, error) {
+	return f.m.Get(k, path)
+}
+
+// Lookup is the read-only equivalent of Map.Lookup.
+func (f FrozenMap) Lookup(k Key, opts LookupOptions) (Key, Value, bool, error) {
+	return f.m.Lookup(k, opts)
+}
+
+// GetPath is the read-only equivalent of Map.GetPath.
+func (f FrozenMap) GetPath(k Key, path string) (Value, bool, error) {
+	return f.m.GetPath(k, path)
+}
+
+// HasSecureValue is the read-only equivalent of Map.HasSecureValue.
+func (f FrozenMap) HasSecureValue() bool {
+	return f.m.HasSecureValue()
+}
+
+// Decrypt is the read-only equivalent of Map.Decrypt.
+func (f FrozenMap) Decrypt(decrypter Decrypter) (map[Key]string, error) {
+	return f.m.Decrypt(decrypter)
+}
+
+// DecryptAll is the read-only equivalent of Map.DecryptAll.
+func (f FrozenMap) DecryptAll(ctx context.Context, decrypter Decrypter) (map[Key]string, error) {
+	return f.m.DecryptAll(ctx, decrypter)
+}
+
+// Copy is the read-only equivalent of Map.Copy. The result is a new, mutable map.
+func (f FrozenMap) Copy(decrypter Decrypter, encrypter Encrypter) (Map, error) {
+	return f.m.Copy(decrypter, encrypter)
+}
+
+// Set always fails with ErrFrozen.
+func (f FrozenMap) Set(k Key, v Value, path bool) error {
+	return ErrFrozen
+}
+
+// SetPath always fails with ErrFrozen.
+func (f FrozenMap) SetPath(k Key, path string, v Value) error {
+	return ErrFrozen
+}
+
+// Remove always fails with ErrFrozen.
+func (f FrozenMap) Remove(k Key, path bool) error {
+	return ErrFrozen
+}
+
+func (f FrozenMap) MarshalJSON() ([]byte, error) {
+	return f.m.MarshalJSON()
+}
+
+func (f FrozenMap) MarshalYAML() (interface{}, error) {
+	return f.m.MarshalYAML()
+}
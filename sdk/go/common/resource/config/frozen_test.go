@@ -0,0 +1,60 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFreeze(t *testing.T) {
+	k := MustMakeKey("my", "key")
+	m := Map{
+		k:                          NewValue("value"),
+		MustMakeKey("my", "outer"): NewObjectValue(`{"inner":"nested"}`),
+	}
+
+	f := m.Freeze()
+	m[k] = NewValue("changed")
+	delete(m, MustMakeKey("my", "outer"))
+
+	v, ok, err := f.Get(k, false)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, NewValue("value"), v)
+	v, ok, err = f.GetPath(MustMakeKey("my", "outer"), "inner")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, NewValue("nested"), v)
+	assert.Equal(t, 2, f.Len())
+	assert.Equal(t, []Key{k, MustMakeKey("my", "outer")}, f.Keys())
+
+	assert.Equal(t, ErrFrozen, f.Set(k, NewValue("other"), false))
+	assert.Equal(t, ErrFrozen, f.SetPath(k, "a", NewValue("other")))
+	assert.Equal(t, ErrFrozen, f.Remove(k, false))
+
+	thawed := f.Map()
+	thawed[k] = NewValue("thawed")
+	v, _, err = f.Get(k, false)
+	assert.NoError(t, err)
+	assert.Equal(t, NewValue("value"), v)
+
+	var empty FrozenMap
+	assert.Equal(t, 0, empty.Len())
+	_, ok, err = empty.Get(k, false)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,340 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Interpolate returns the configuration as a map from module member to decrypted value, like Decrypt, but with any
+// references of the form `${<namespace>:<name>}` replaced by the (decrypted) value of the referenced key. References
+// may be nested through other keys, but must not form a cycle. A literal `${` may be written as `$${`.
+//
+// To avoid accidentally revealing secrets, a value that is not secure may not reference a secure value.
+func (m Map) Interpolate(decrypter Decrypter) (map[Key]string, error) {
+	return m.InterpolateWithOptions(decrypter, InterpolateOptions{})
+}
+
+// EnvNamespace is the namespace of references that are resolved from environment variables when
+// InterpolateOptions.LookupEnv is set, e.g. `${env:DATABASE_URL}`.
+const EnvNamespace = "env"
+
+// InterpolateOptions controls the behavior of InterpolateWithOptions.
+type InterpolateOptions struct {
+	// LookupEnv, if non-nil, enables references of the form `${env:<NAME>}`, which are resolved by calling LookupEnv
+	// with the name of the variable (typically os.LookupEnv). Referencing a variable that is not set is an error.
+	// When enabled, these references take precedence over any config keys in the "env" namespace.
+	LookupEnv func(name string) (string, bool)
+}
+
+// InterpolateWithOptions is like Interpolate, but allows the caller to control how references are resolved.
+func (m Map) InterpolateWithOptions(decrypter Decrypter, opts InterpolateOptions) (map[Key]string, error) {
+	in := newInterpolator(m, decrypter, opts)
+	r := make(map[Key]string, len(m))
+	for k := range m {
+		v, err := in.resolve(k, nil)
+		if err != nil {
+			return nil, err
+		}
+		r[k] = v
+	}
+	return r, nil
+}
+
+// Interpolated returns a copy of m in which references are replaced as by InterpolateWithOptions, along with a
+// Decrypter for the copy's secure values. Values that are not secure are interpolated immediately. Secure values keep
+// their ciphertexts, and are interpolated as the returned Decrypter decrypts them, so that Interpolated itself never
+// decrypts anything and the copy can be passed to a program like any other configuration.
+func (m Map) Interpolated(decrypter Decrypter, opts InterpolateOptions) (Map, Decrypter, error) {
+	in := newInterpolator(m, decrypter, opts)
+	result := make(Map, len(m))
+	routes := make(map[string]Key)
+	for k, c := range m {
+		cts, err := c.ciphertexts()
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, ct := range cts {
+			routes[ct] = k
+		}
+
+		switch {
+		case c.ref != "" || !strings.Contains(c.value, "${"):
+			// Nothing to interpolate, or a secure scalar, which is interpolated when it is decrypted.
+		case !c.secure:
+			if c.value, err = in.resolve(k, nil); err != nil {
+				return nil, nil, err
+			}
+		case c.object:
+			// The plaintext parts of a partially-secure object are interpolated now, and may not reference secrets.
+			var obj interface{}
+			if err = json.Unmarshal([]byte(c.value), &obj); err != nil {
+				return nil, nil, err
+			}
+			if obj, err = in.expandPlaintext(obj, []Key{k}); err != nil {
+				return nil, nil, err
+			}
+			b, err := json.Marshal(obj)
+			if err != nil {
+				return nil, nil, err
+			}
+			c.value = string(b)
+		}
+		result[k] = c
+	}
+	if len(routes) == 0 {
+		return result, decrypter, nil
+	}
+	return result, &interpolatingDecrypter{in: in, routes: routes}, nil
+}
+
+// interpolatingDecrypter interpolates the plaintexts of the secure values of a map as it decrypts them.
+type interpolatingDecrypter struct {
+	mu     sync.Mutex // guards in, which is not safe for concurrent use.
+	in     *interpolator
+	routes map[string]Key // the key of the value that holds each ciphertext.
+}
+
+func (d *interpolatingDecrypter) DecryptValue(ciphertext string) (string, error) {
+	pt, err := d.in.decrypter.DecryptValue(ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.expand(ciphertext, pt)
+}
+
+// BulkDecrypt implements BulkDecrypter. The secrets that the decrypted values reference are served from the same
+// batch where they can be.
+func (d *interpolatingDecrypter) BulkDecrypt(ctx context.Context, ciphertexts []string) (map[string]string, error) {
+	plaintexts, err := DecryptValues(ctx, d.in.decrypter, ciphertexts)
+	if err != nil {
+		return nil, err
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	inner := d.in.decrypter
+	d.in.decrypter = &refDecrypter{resolved: plaintexts, decrypter: inner}
+	defer func() { d.in.decrypter = inner }()
+
+	result := make(map[string]string, len(plaintexts))
+	for ct, pt := range plaintexts {
+		if result[ct], err = d.expand(ct, pt); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// expand interpolates pt, the plaintext of ciphertext. d.mu must be held.
+func (d *interpolatingDecrypter) expand(ciphertext, pt string) (string, error) {
+	k, ok := d.routes[ciphertext]
+	if !ok || !strings.Contains(pt, "${") {
+		return pt, nil
+	}
+	d.in.visiting[k] = true
+	defer delete(d.in.visiting, k)
+	return d.in.expand(pt, d.in.config[k], []Key{k})
+}
+
+func newInterpolator(m Map, decrypter Decrypter, opts InterpolateOptions) *interpolator {
+	return &interpolator{
+		config:    m,
+		decrypter: decrypter,
+		lookupEnv: opts.LookupEnv,
+		resolved:  make(map[Key]string),
+		visiting:  make(map[Key]bool),
+	}
+}
+
+type interpolator struct {
+	config    Map
+	decrypter Decrypter
+	lookupEnv func(name string) (string, bool)
+
+	resolved map[Key]string // the fully interpolated values of keys that have been resolved.
+	visiting map[Key]bool   // the keys that are currently being resolved, used to detect cycles.
+}
+
+// resolve returns the interpolated value of key k. stack holds the chain of keys whose resolution led to k.
+func (in *interpolator) resolve(k Key, stack []Key) (string, error) {
+	if v, ok := in.resolved[k]; ok {
+		return v, nil
+	}
+
+	stack = append(stack, k)
+	if in.visiting[k] {
+		names := make([]string, len(stack))
+		for i, sk := range stack {
+			names[i] = sk.String()
+		}
+		return "", errors.Errorf("config interpolation cycle detected: %s", strings.Join(names, " -> "))
+	}
+	in.visiting[k] = true
+	defer delete(in.visiting, k)
+
+	c := in.config[k]
+	raw, err := c.Value(in.decrypter)
+	if err != nil {
+		return "", err
+	}
+
+	var v string
+	if c.Object() {
+		var obj interface{}
+		if err = json.Unmarshal([]byte(raw), &obj); err != nil {
+			return "", err
+		}
+		if obj, err = in.expandObject(obj, c, stack); err != nil {
+			return "", err
+		}
+		b, err := json.Marshal(obj)
+		if err != nil {
+			return "", err
+		}
+		v = string(b)
+	} else if v, err = in.expand(raw, c, stack); err != nil {
+		return "", err
+	}
+
+	in.resolved[k] = v
+	return v, nil
+}
+
+// expandObject interpolates every string within obj.
+func (in *interpolator) expandObject(obj interface{}, c Value, stack []Key) (interface{}, error) {
+	switch t := obj.(type) {
+	case string:
+		return in.expand(t, c, stack)
+	case []interface{}:
+		for i, e := range t {
+			v, err := in.expandObject(e, c, stack)
+			if err != nil {
+				return nil, err
+			}
+			t[i] = v
+		}
+	case map[string]interface{}:
+		for key, e := range t {
+			v, err := in.expandObject(e, c, stack)
+			if err != nil {
+				return nil, err
+			}
+			t[key] = v
+		}
+	}
+	return obj, nil
+}
+
+// expandPlaintext interpolates every string within obj that is not a secure value. The strings may not reference
+// secure values, as they are not themselves secret.
+func (in *interpolator) expandPlaintext(obj interface{}, stack []Key) (interface{}, error) {
+	switch t := obj.(type) {
+	case string:
+		return in.expand(t, Value{}, stack)
+	case []interface{}:
+		for i, e := range t {
+			v, err := in.expandPlaintext(e, stack)
+			if err != nil {
+				return nil, err
+			}
+			t[i] = v
+		}
+	case map[string]interface{}:
+		if is, _ := isSecureValue(t); is {
+			return t, nil
+		}
+		for key, e := range t {
+			v, err := in.expandPlaintext(e, stack)
+			if err != nil {
+				return nil, err
+			}
+			t[key] = v
+		}
+	}
+	return obj, nil
+}
+
+// expand replaces the references in s, which belongs to the value c of the key at the top of stack.
+func (in *interpolator) expand(s string, c Value, stack []Key) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i == -1 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+
+		// `$${` is an escaped `${`.
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i])
+			b.WriteString("{")
+			s = s[i+2:]
+			continue
+		}
+
+		b.WriteString(s[:i])
+		end := strings.Index(s[i:], "}")
+		if end == -1 {
+			return "", errors.Errorf("config value for %v has an unterminated reference %q", stack[len(stack)-1], s[i:])
+		}
+		ref := s[i+2 : i+end]
+		s = s[i+end+1:]
+
+		v, err := in.reference(ref, c, stack)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(v)
+	}
+}
+
+// reference returns the value of the key named by ref.
+func (in *interpolator) reference(ref string, c Value, stack []Key) (string, error) {
+	from := stack[len(stack)-1]
+
+	k, err := ParseKey(ref)
+	if err != nil {
+		return "", errors.Wrapf(err, "config value for %v has an invalid reference", from)
+	}
+	if in.lookupEnv != nil && k.Namespace() == EnvNamespace {
+		v, ok := in.lookupEnv(k.Name())
+		if !ok {
+			return "", errors.Errorf("config value for %v references environment variable %s, which is not set",
+				from, k.Name())
+		}
+		return v, nil
+	}
+	target, ok := in.config[k]
+	if !ok {
+		return "", errors.Errorf("config value for %v references %v, which is not set", from, k)
+	}
+	if target.Secure() && !c.Secure() {
+		return "", errors.Errorf("config value for %v references secret %v, so it must also be secret", from, k)
+	}
+	return in.resolve(k, stack)
+}
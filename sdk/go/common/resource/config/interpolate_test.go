@@ -0,0 +1,167 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpolate(t *testing.T) {
+	m := Map{
+		MustMakeKey("aws", "region"):    NewValue("us-west-2"),
+		MustMakeKey("my", "prefix"):     NewValue("app-${aws:region}"),
+		MustMakeKey("my", "bucket"):     NewValue("${my:prefix}-bucket"),
+		MustMakeKey("my", "escaped"):    NewValue("$${aws:region}"),
+		MustMakeKey("my", "servers"):    NewObjectValue(`[{"host":"${my:prefix}.example.com"}]`),
+		MustMakeKey("my", "password"):   NewSecureValue("sekret"),
+		MustMakeKey("my", "connection"): NewSecureValue("postgres://admin:${my:password}@db"),
+	}
+
+	actual, err := m.Interpolate(passThroughDecrypter{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[Key]string{
+		MustMakeKey("aws", "region"):    "us-west-2",
+		MustMakeKey("my", "prefix"):     "app-us-west-2",
+		MustMakeKey("my", "bucket"):     "app-us-west-2-bucket",
+		MustMakeKey("my", "escaped"):    "${aws:region}",
+		MustMakeKey("my", "servers"):    `[{"host":"app-us-west-2.example.com"}]`,
+		MustMakeKey("my", "password"):   "sekret",
+		MustMakeKey("my", "connection"): "postgres://admin:sekret@db",
+	}, actual)
+}
+
+func TestInterpolateEnv(t *testing.T) {
+	m := Map{
+		MustMakeKey("my", "url"):     NewValue("${env:DATABASE_URL}/app"),
+		MustMakeKey("my", "escaped"): NewValue("$${env:DATABASE_URL}"),
+	}
+	env := map[string]string{"DATABASE_URL": "postgres://db"}
+	lookupEnv := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	actual, err := m.InterpolateWithOptions(passThroughDecrypter{}, InterpolateOptions{LookupEnv: lookupEnv})
+	assert.NoError(t, err)
+	assert.Equal(t, map[Key]string{
+		MustMakeKey("my", "url"):     "postgres://db/app",
+		MustMakeKey("my", "escaped"): "${env:DATABASE_URL}",
+	}, actual)
+
+	// Environment references are only resolved when enabled.
+	_, err = m.Interpolate(passThroughDecrypter{})
+	assert.Error(t, err)
+
+	delete(env, "DATABASE_URL")
+	_, err = m.InterpolateWithOptions(passThroughDecrypter{}, InterpolateOptions{LookupEnv: lookupEnv})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "environment variable DATABASE_URL, which is not set")
+	}
+}
+
+func TestInterpolateFail(t *testing.T) {
+	tests := []struct {
+		Config   Map
+		Expected string
+	}{
+		{
+			Config: Map{
+				MustMakeKey("my", "a"): NewValue("${my:b}"),
+				MustMakeKey("my", "b"): NewValue("${my:a}"),
+			},
+			Expected: "config interpolation cycle detected",
+		},
+		{
+			Config: Map{
+				MustMakeKey("my", "a"): NewValue("${my:missing}"),
+			},
+			Expected: "references my:missing, which is not set",
+		},
+		{
+			Config: Map{
+				MustMakeKey("my", "a"): NewValue("${nocolon}"),
+			},
+			Expected: "invalid reference",
+		},
+		{
+			Config: Map{
+				MustMakeKey("my", "a"): NewValue("${my:b"),
+			},
+			Expected: "unterminated reference",
+		},
+		{
+			Config: Map{
+				MustMakeKey("my", "a"):      NewValue("${my:secret}"),
+				MustMakeKey("my", "secret"): NewSecureValue("sekret"),
+			},
+			Expected: "so it must also be secret",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Expected, func(t *testing.T) {
+			_, err := test.Config.Interpolate(passThroughDecrypter{})
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), test.Expected)
+			}
+		})
+	}
+}
+
+func TestInterpolated(t *testing.T) {
+	m := Map{
+		MustMakeKey("aws", "region"):    NewValue("us-west-2"),
+		MustMakeKey("my", "bucket"):     NewValue("app-${aws:region}"),
+		MustMakeKey("my", "servers"):    NewObjectValue(`[{"host":"${aws:region}.example.com"}]`),
+		MustMakeKey("my", "password"):   NewSecureValue("pw"),
+		MustMakeKey("my", "connection"): NewSecureValue("admin:${my:password}@${aws:region}"),
+		MustMakeKey("my", "database"):   NewSecureObjectValue(`{"region":"${aws:region}","password":{"secure":"pw"}}`),
+	}
+
+	d := &bulkDecrypter{}
+	actual, dec, err := m.Interpolated(d, InterpolateOptions{})
+	assert.NoError(t, err)
+
+	// Plaintext values are interpolated without decrypting anything, and secrets keep their ciphertexts.
+	assert.Empty(t, d.decrypted)
+	assert.Empty(t, d.batches)
+	assert.Equal(t, NewValue("app-us-west-2"), actual[MustMakeKey("my", "bucket")])
+	assert.Equal(t, NewObjectValue(`[{"host":"us-west-2.example.com"}]`), actual[MustMakeKey("my", "servers")])
+	assert.Equal(t, m[MustMakeKey("my", "connection")], actual[MustMakeKey("my", "connection")])
+	assert.Equal(t, NewSecureObjectValue(`{"password":{"secure":"pw"},"region":"us-west-2"}`),
+		actual[MustMakeKey("my", "database")])
+
+	// Secrets are interpolated as they are decrypted, in a single batch.
+	decrypted, err := actual.DecryptAll(context.Background(), dec)
+	assert.NoError(t, err)
+	assert.Len(t, d.batches, 1)
+	assert.Empty(t, d.decrypted)
+	assert.Equal(t, "plain-admin:plain-pw@us-west-2", decrypted[MustMakeKey("my", "connection")])
+	assert.Equal(t, `{"password":"plain-pw","region":"us-west-2"}`, decrypted[MustMakeKey("my", "database")])
+
+	v, err := actual[MustMakeKey("my", "connection")].Value(dec)
+	assert.NoError(t, err)
+	assert.Equal(t, "plain-admin:plain-pw@us-west-2", v)
+
+	// The plaintext parts of a secure object may not reference secrets.
+	m[MustMakeKey("my", "database")] = NewSecureObjectValue(`{"user":"${my:password}","password":{"secure":"pw"}}`)
+	_, _, err = m.Interpolated(d, InterpolateOptions{})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "so it must also be secret")
+	}
+}
@@ -29,39 +29,62 @@ type Key struct {
 	name      string
 }
 
-// MustMakeKey constructs a config.Key for a given namespace and name. The namespace may not contain a `:`
+// MustMakeKey constructs a config.Key for a given namespace and name. The namespace may consist of several segments
+// separated by `:` (e.g. `team:service`), none of which may be empty.
 func MustMakeKey(namespace string, name string) Key {
-	contract.Requiref(!strings.Contains(":", namespace), "namespace", "may not contain a colon")
+	contract.Requiref(!strings.Contains(namespace, ":") || validNamespaceSegments(namespace), "namespace",
+		"may not contain empty segments")
 	return Key{namespace: namespace, name: name}
 }
 
 func ParseKey(s string) (Key, error) {
-	// Keys can take on of two forms:
+	// Keys can take on of three forms:
 	//
 	// - <namespace>:<name> (the preferred form)
 	// - <namespace>:config:<name> (compat with an old requirement that every config value be in the "config" module)
+	// - <segment>:<segment>:...:<name> (a hierarchical namespace, e.g. `team:service:db:password`)
 	//
-	// Where <namespace> and <name> may be any string of characters, excluding ':'.
+	// Where <namespace>, <segment> and <name> may be any string of characters, excluding ':'. In a hierarchical key,
+	// every segment before the last `:` belongs to the namespace, and none may be empty. Because of the compatibility
+	// form, a key whose second of three segments is `config` is never treated as hierarchical.
 
 	switch strings.Count(s, ":") {
+	case 0:
 	case 1:
 		idx := strings.Index(s, ":")
 		return Key{namespace: s[:idx], name: s[idx+1:]}, nil
-	case 2:
-		if mm, err := tokens.ParseModuleMember(s); err == nil {
-			if mm.Module().Name() == tokens.ModuleName("config") {
-				return Key{
-					namespace: mm.Module().Package().String(),
-					name:      mm.Name().String(),
-				}, nil
+	default:
+		if strings.Count(s, ":") == 2 {
+			if mm, err := tokens.ParseModuleMember(s); err == nil {
+				if mm.Module().Name() == tokens.ModuleName("config") {
+					return Key{
+						namespace: mm.Module().Package().String(),
+						name:      mm.Name().String(),
+					}, nil
+				}
 			}
 		}
+
+		idx := strings.LastIndex(s, ":")
+		if namespace := s[:idx]; validNamespaceSegments(namespace) {
+			return Key{namespace: namespace, name: s[idx+1:]}, nil
+		}
 	}
 
 	return Key{}, errors.Errorf("could not parse %s as a configuration key "+
 		"(configuration keys should be of the form `<namespace>:<name>`)", s)
 }
 
+// validNamespaceSegments returns true if none of the `:`-separated segments of a hierarchical namespace are empty.
+func validNamespaceSegments(namespace string) bool {
+	for _, segment := range strings.Split(namespace, ":") {
+		if segment == "" {
+			return false
+		}
+	}
+	return true
+}
+
 func (k Key) Namespace() string {
 	return k.namespace
 }
@@ -70,6 +93,12 @@ func (k Key) Name() string {
 	return k.name
 }
 
+// NamespaceSegments returns the `:`-separated segments of the key's namespace. For most keys, this is just the
+// namespace itself.
+func (k Key) NamespaceSegments() []string {
+	return strings.Split(k.namespace, ":")
+}
+
 func (k Key) MarshalJSON() ([]byte, error) {
 	return json.Marshal(k.String())
 }
@@ -36,7 +36,23 @@ func TestParseKey(t *testing.T) {
 	_, err = ParseKey("foo")
 	assert.Error(t, err)
 
-	_, err = ParseKey("test:data:key")
+	k, err = ParseKey("test:data:key")
+	assert.NoError(t, err)
+	assert.Equal(t, "test:data", k.namespace)
+	assert.Equal(t, "key", k.name)
+
+	k, err = ParseKey("team:service:db:password")
+	assert.NoError(t, err)
+	assert.Equal(t, "team:service:db", k.namespace)
+	assert.Equal(t, "password", k.name)
+	assert.Equal(t, []string{"team", "service", "db"}, k.NamespaceSegments())
+	assert.Equal(t, "team:service:db:password", k.String())
+	assert.Equal(t, MustMakeKey("team:service:db", "password"), k)
+
+	_, err = ParseKey("team::db:password")
+	assert.Error(t, err)
+
+	_, err = ParseKey(":db:password")
 	assert.Error(t, err)
 }
 
@@ -0,0 +1,90 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+// NewKeyedDecrypter returns a Decrypter for the secure values in m, which may have been encrypted by different
+// secrets providers. The secure values of each key in decrypters are decrypted using that key's decrypter, and all
+// other secure values are decrypted using def.
+//
+// Decrypting a value does not identify the key it belongs to, so the returned Decrypter routes each ciphertext to the
+// decrypter for the key that holds it. It is an error for the same ciphertext to be held by keys that use different
+// decrypters.
+func NewKeyedDecrypter(m Map, def Decrypter, decrypters map[Key]Decrypter) (Decrypter, error) {
+	routes := make(map[string]Decrypter)
+	for k, d := range decrypters {
+		v, ok := m[k]
+		if !ok {
+			continue
+		}
+		cts, err := v.ciphertexts()
+		if err != nil {
+			return nil, errors.Wrapf(err, "config key %v", k)
+		}
+		for _, ct := range cts {
+			if other, has := routes[ct]; has && other != d {
+				return nil, errors.Errorf("config key %v shares a ciphertext with a key that uses a different "+
+					"secrets provider", k)
+			}
+			routes[ct] = d
+		}
+	}
+	return &keyedDecrypter{def: def, routes: routes}, nil
+}
+
+type keyedDecrypter struct {
+	def    Decrypter
+	routes map[string]Decrypter
+}
+
+func (k *keyedDecrypter) DecryptValue(ciphertext string) (string, error) {
+	if d, ok := k.routes[ciphertext]; ok {
+		return d.DecryptValue(ciphertext)
+	}
+	return k.def.DecryptValue(ciphertext)
+}
+
+// BulkDecrypt implements BulkDecrypter, passing the ciphertexts that share a decrypter to that decrypter together.
+func (k *keyedDecrypter) BulkDecrypt(ctx context.Context, ciphertexts []string) (map[string]string, error) {
+	groups := make(map[Decrypter][]string)
+	var order []Decrypter
+	for _, ct := range ciphertexts {
+		d, ok := k.routes[ct]
+		if !ok {
+			d = k.def
+		}
+		if _, has := groups[d]; !has {
+			order = append(order, d)
+		}
+		groups[d] = append(groups[d], ct)
+	}
+
+	result := make(map[string]string, len(ciphertexts))
+	for _, d := range order {
+		plaintexts, err := DecryptValues(ctx, d, groups[d])
+		if err != nil {
+			return nil, err
+		}
+		for ct, pt := range plaintexts {
+			result[ct] = pt
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyedDecrypter(t *testing.T) {
+	passphrase := newPrefixCrypter("pass:")
+	kms := newPrefixCrypter("kms:")
+
+	m := Map{
+		MustMakeKey("my", "plain"):  NewValue("value"),
+		MustMakeKey("my", "local"):  NewSecureValue("pass:local"),
+		MustMakeKey("my", "remote"): NewSecureValue("kms:remote"),
+		MustMakeKey("my", "object"): NewSecureObjectValue(`{"inner":{"secure":"kms:nested"}}`),
+	}
+
+	d, err := NewKeyedDecrypter(m, passphrase, map[Key]Decrypter{
+		MustMakeKey("my", "remote"):  kms,
+		MustMakeKey("my", "object"):  kms,
+		MustMakeKey("my", "missing"): kms,
+	})
+	assert.NoError(t, err)
+
+	r, err := m.Decrypt(d)
+	assert.NoError(t, err)
+	assert.Equal(t, map[Key]string{
+		MustMakeKey("my", "plain"):  "value",
+		MustMakeKey("my", "local"):  "local",
+		MustMakeKey("my", "remote"): "remote",
+		MustMakeKey("my", "object"): `{"inner":"nested"}`,
+	}, r)
+
+	shared := Map{
+		MustMakeKey("my", "a"): NewSecureValue("kms:same"),
+		MustMakeKey("my", "b"): NewSecureValue("kms:same"),
+	}
+	_, err = NewKeyedDecrypter(shared, passphrase, map[Key]Decrypter{
+		MustMakeKey("my", "a"): kms,
+		MustMakeKey("my", "b"): newPrefixCrypter("other:"),
+	})
+	assert.Error(t, err)
+}
+
+func TestKeyedDecrypterBulk(t *testing.T) {
+	def, kms := &bulkDecrypter{}, &bulkDecrypter{}
+	m := Map{
+		MustMakeKey("my", "local"):  NewSecureValue("l1"),
+		MustMakeKey("my", "other"):  NewSecureValue("l2"),
+		MustMakeKey("my", "remote"): NewSecureValue("r1"),
+		MustMakeKey("my", "object"): NewSecureObjectValue(`{"inner":{"secure":"r2"}}`),
+	}
+	d, err := NewKeyedDecrypter(m, def, map[Key]Decrypter{
+		MustMakeKey("my", "remote"): kms,
+		MustMakeKey("my", "object"): kms,
+	})
+	assert.NoError(t, err)
+
+	// Each decrypter is asked for all of its ciphertexts at once.
+	r, err := m.DecryptAll(context.Background(), d)
+	assert.NoError(t, err)
+	assert.Equal(t, "plain-r1", r[MustMakeKey("my", "remote")])
+	assert.Equal(t, `{"inner":"plain-r2"}`, r[MustMakeKey("my", "object")])
+	if assert.Len(t, def.batches, 1) {
+		assert.ElementsMatch(t, []string{"l1", "l2"}, def.batches[0])
+	}
+	if assert.Len(t, kms.batches, 1) {
+		assert.ElementsMatch(t, []string{"r1", "r2"}, kms.batches[0])
+	}
+	assert.Empty(t, def.decrypted)
+	assert.Empty(t, kms.decrypted)
+}
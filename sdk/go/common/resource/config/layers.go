@@ -0,0 +1,114 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+// The names of the standard configuration layers, in order of increasing precedence.
+const (
+	// ProjectDefaultsLayer holds default values declared by the project.
+	ProjectDefaultsLayer = "project-defaults"
+	// OrganizationLayer holds values from a configuration document shared by every project in an organization.
+	OrganizationLayer = "organization"
+	// ProjectLayer holds values from a configuration document shared by every stack of a project.
+	ProjectLayer = "project"
+	// StackLayer holds the values from the stack's configuration file.
+	StackLayer = "stack"
+)
+
+// Layer is a named bag of configuration values.
+type Layer struct {
+	// Name is the name of the layer, e.g. StackLayer.
+	Name string
+	// Source optionally identifies where the layer's values were loaded from, e.g. the path of a file.
+	Source string
+	// Config holds the layer's values.
+	Config Map
+}
+
+// Provenance records that a layer sets a value for a key.
+type Provenance struct {
+	Layer  string
+	Source string
+	Value  Value
+}
+
+// Layers is a list of configuration layers, ordered from lowest to highest precedence. A value in a later layer
+// overrides the value for the same key in any earlier layer.
+type Layers []Layer
+
+// WithDefaults returns the layers for a stack's configuration m placed on top of the given default layers, which must
+// be ordered from lowest to highest precedence.
+func WithDefaults(m Map, defaults ...Layer) Layers {
+	layers := make(Layers, 0, len(defaults)+1)
+	layers = append(layers, defaults...)
+	return append(layers, Layer{Name: StackLayer, Config: m})
+}
+
+// Get returns the effective value for k along with the name of the layer it came from.
+func (l Layers) Get(k Key) (Value, string, bool) {
+	for i := len(l) - 1; i >= 0; i-- {
+		if v, ok := l[i].Config[k]; ok {
+			return v, l[i].Name, true
+		}
+	}
+	return Value{}, "", false
+}
+
+// Explain returns the provenance of every value set for k, ordered from highest to lowest precedence. If k is set, the
+// first entry holds its effective value, and the remaining entries describe the values it overrides.
+func (l Layers) Explain(k Key) []Provenance {
+	var result []Provenance
+	for i := len(l) - 1; i >= 0; i-- {
+		if v, ok := l[i].Config[k]; ok {
+			result = append(result, Provenance{Layer: l[i].Name, Source: l[i].Source, Value: v})
+		}
+	}
+	return result
+}
+
+// Origins returns the name of the layer each effective value came from.
+func (l Layers) Origins() map[Key]string {
+	origins := make(map[Key]string)
+	for _, layer := range l {
+		for k := range layer.Config {
+			origins[k] = layer.Name
+		}
+	}
+	return origins
+}
+
+// Effective returns the effective configuration, in which each key has the value from the highest-precedence layer
+// that sets it.
+func (l Layers) Effective() Map {
+	m := make(Map)
+	for _, layer := range l {
+		for k, v := range layer.Config {
+			m[k] = v
+		}
+	}
+	return m
+}
+
+// Resolve returns the effective configuration along with the provenance of each of its values.
+func (l Layers) Resolve() (Map, map[Key]Provenance) {
+	m := make(Map)
+	provenance := make(map[Key]Provenance)
+	for _, layer := range l {
+		for k, v := range layer.Config {
+			m[k] = v
+			provenance[k] = Provenance{Layer: layer.Name, Source: layer.Source, Value: v}
+		}
+	}
+	return m, provenance
+}
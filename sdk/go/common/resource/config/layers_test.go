@@ -0,0 +1,93 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLayers(t *testing.T) {
+	region := MustMakeKey("aws", "region")
+	profile := MustMakeKey("aws", "profile")
+	name := MustMakeKey("my", "name")
+
+	layers := WithDefaults(
+		Map{name: NewValue("stack-name")},
+		Layer{Name: ProjectDefaultsLayer, Config: Map{region: NewValue("us-east-1"), profile: NewValue("default")}},
+		Layer{Name: OrganizationLayer, Config: Map{region: NewValue("us-west-2"), name: NewValue("org-name")}})
+
+	v, origin, ok := layers.Get(region)
+	assert.True(t, ok)
+	assert.Equal(t, NewValue("us-west-2"), v)
+	assert.Equal(t, OrganizationLayer, origin)
+
+	v, origin, ok = layers.Get(name)
+	assert.True(t, ok)
+	assert.Equal(t, NewValue("stack-name"), v)
+	assert.Equal(t, StackLayer, origin)
+
+	_, _, ok = layers.Get(MustMakeKey("my", "missing"))
+	assert.False(t, ok)
+
+	assert.Equal(t, Map{
+		region:  NewValue("us-west-2"),
+		profile: NewValue("default"),
+		name:    NewValue("stack-name"),
+	}, layers.Effective())
+	assert.Equal(t, map[Key]string{
+		region:  OrganizationLayer,
+		profile: ProjectDefaultsLayer,
+		name:    StackLayer,
+	}, layers.Origins())
+}
+
+func TestExplain(t *testing.T) {
+	region := MustMakeKey("aws", "region")
+
+	layers := Layers{
+		{Name: ProjectDefaultsLayer, Config: Map{region: NewValue("us-east-1")}},
+		{Name: OrganizationLayer, Config: Map{}},
+		{Name: StackLayer, Source: "Pulumi.dev.yaml", Config: Map{region: NewValue("us-west-2")}},
+	}
+
+	assert.Equal(t, []Provenance{
+		{Layer: StackLayer, Source: "Pulumi.dev.yaml", Value: NewValue("us-west-2")},
+		{Layer: ProjectDefaultsLayer, Value: NewValue("us-east-1")},
+	}, layers.Explain(region))
+	assert.Empty(t, layers.Explain(MustMakeKey("aws", "profile")))
+}
+
+func TestResolve(t *testing.T) {
+	region := MustMakeKey("aws", "region")
+	profile := MustMakeKey("aws", "profile")
+	name := MustMakeKey("my", "name")
+
+	layers := WithDefaults(
+		Map{name: NewValue("stack-name")},
+		Layer{Name: OrganizationLayer, Source: "org.yaml", Config: Map{region: NewValue("us-east-1"),
+			profile: NewValue("shared")}},
+		Layer{Name: ProjectLayer, Source: "project.yaml", Config: Map{region: NewValue("us-west-2"),
+			name: NewValue("project-name")}})
+
+	m, provenance := layers.Resolve()
+	assert.Equal(t, layers.Effective(), m)
+	assert.Equal(t, map[Key]Provenance{
+		region:  {Layer: ProjectLayer, Source: "project.yaml", Value: NewValue("us-west-2")},
+		profile: {Layer: OrganizationLayer, Source: "org.yaml", Value: NewValue("shared")},
+		name:    {Layer: StackLayer, Value: NewValue("stack-name")},
+	}, provenance)
+}
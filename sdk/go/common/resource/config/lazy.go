@@ -0,0 +1,124 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// NewLazyDecrypter returns a Decrypter that calls newDecrypter to construct the underlying decrypter the first time a
+// value is decrypted. Callers that never decrypt a value never pay the cost of constructing one, which may involve
+// prompting for a passphrase or contacting a key management service. If newDecrypter fails, every call to
+// DecryptValue returns its error.
+func NewLazyDecrypter(newDecrypter func() (Decrypter, error)) Decrypter {
+	return &lazyDecrypter{newDecrypter: newDecrypter}
+}
+
+type lazyDecrypter struct {
+	once         sync.Once
+	newDecrypter func() (Decrypter, error)
+	decrypter    Decrypter
+	err          error
+}
+
+func (l *lazyDecrypter) get() (Decrypter, error) {
+	l.once.Do(func() {
+		l.decrypter, l.err = l.newDecrypter()
+	})
+	return l.decrypter, l.err
+}
+
+func (l *lazyDecrypter) DecryptValue(ciphertext string) (string, error) {
+	d, err := l.get()
+	if err != nil {
+		return "", err
+	}
+	return d.DecryptValue(ciphertext)
+}
+
+// BulkDecrypt implements BulkDecrypter, deferring to the underlying decrypter if it supports bulk decryption.
+func (l *lazyDecrypter) BulkDecrypt(ctx context.Context, ciphertexts []string) (map[string]string, error) {
+	d, err := l.get()
+	if err != nil {
+		return nil, err
+	}
+	if bulk, ok := d.(BulkDecrypter); ok {
+		return bulk.BulkDecrypt(ctx, ciphertexts)
+	}
+	result := make(map[string]string, len(ciphertexts))
+	for _, ct := range ciphertexts {
+		pt, err := d.DecryptValue(ct)
+		if err != nil {
+			return nil, err
+		}
+		result[ct] = pt
+	}
+	return result, nil
+}
+
+// LazyMap provides access to the plaintext values of a Map. Each secure value is decrypted the first time it is
+// read, and the result is cached, so secrets that are never read are never decrypted. A LazyMap is safe for
+// concurrent use.
+type LazyMap struct {
+	config    Map
+	decrypter Decrypter
+
+	m     sync.Mutex
+	cache map[Key]lazyResult
+}
+
+type lazyResult struct {
+	plaintext string
+	err       error
+}
+
+// NewLazyMap returns a LazyMap that reads values from m, using decrypter to decrypt secure values on demand.
+func NewLazyMap(m Map, decrypter Decrypter) *LazyMap {
+	return &LazyMap{config: m, decrypter: decrypter, cache: make(map[Key]lazyResult)}
+}
+
+// Keys returns the keys of the underlying map, in sorted order.
+func (l *LazyMap) Keys() []Key {
+	keys := make(KeyArray, 0, len(l.config))
+	for k := range l.config {
+		keys = append(keys, k)
+	}
+	sort.Sort(keys)
+	return keys
+}
+
+// Get returns the plaintext value for k, decrypting it if necessary.
+func (l *LazyMap) Get(k Key) (string, bool, error) {
+	v, ok := l.config[k]
+	if !ok {
+		return "", false, nil
+	}
+	if !v.Secure() {
+		s, err := v.Value(l.decrypter)
+		return s, true, err
+	}
+
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	r, ok := l.cache[k]
+	if !ok {
+		r.plaintext, r.err = v.Value(l.decrypter)
+		l.cache[k] = r
+	}
+	return r.plaintext, true, r.err
+}
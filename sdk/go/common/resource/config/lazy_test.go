@@ -0,0 +1,88 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingDecrypter records the ciphertexts it is asked to decrypt.
+type countingDecrypter struct {
+	decrypted []string
+}
+
+func (d *countingDecrypter) DecryptValue(ciphertext string) (string, error) {
+	d.decrypted = append(d.decrypted, ciphertext)
+	return "plain-" + ciphertext, nil
+}
+
+func TestLazyDecrypter(t *testing.T) {
+	created := 0
+	inner := &countingDecrypter{}
+	d := NewLazyDecrypter(func() (Decrypter, error) {
+		created++
+		return inner, nil
+	})
+	assert.Equal(t, 0, created)
+
+	v, err := d.DecryptValue("a")
+	assert.NoError(t, err)
+	assert.Equal(t, "plain-a", v)
+	_, err = d.DecryptValue("b")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, created)
+
+	failing := NewLazyDecrypter(func() (Decrypter, error) {
+		return nil, errors.New("no passphrase")
+	})
+	_, err = failing.DecryptValue("a")
+	assert.EqualError(t, err, "no passphrase")
+}
+
+func TestLazyMap(t *testing.T) {
+	d := &countingDecrypter{}
+	m := NewLazyMap(Map{
+		MustMakeKey("my", "plain"):  NewValue("value"),
+		MustMakeKey("my", "secret"): NewSecureValue("s1"),
+		MustMakeKey("my", "unused"): NewSecureValue("s2"),
+	}, d)
+
+	assert.Equal(t, []Key{
+		MustMakeKey("my", "plain"),
+		MustMakeKey("my", "secret"),
+		MustMakeKey("my", "unused"),
+	}, m.Keys())
+
+	v, ok, err := m.Get(MustMakeKey("my", "plain"))
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "value", v)
+	assert.Empty(t, d.decrypted)
+
+	for i := 0; i < 2; i++ {
+		v, ok, err = m.Get(MustMakeKey("my", "secret"))
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, "plain-s1", v)
+	}
+	assert.Equal(t, []string{"s1"}, d.decrypted)
+
+	_, ok, err = m.Get(MustMakeKey("my", "missing"))
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
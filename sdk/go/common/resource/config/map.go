@@ -15,9 +15,12 @@
 package config
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 
@@ -43,6 +46,138 @@ func (m Map) Decrypt(decrypter Decrypter) (map[Key]string, error) {
 	return r, nil
 }
 
+// DecryptAll is like Decrypt, but first gathers the ciphertexts of every secure value in the map (including those
+// nested inside objects) and decrypts them together. If decrypter is a BulkDecrypter, this requires a single call to
+// BulkDecrypt; otherwise, each distinct ciphertext is decrypted exactly once.
+func (m Map) DecryptAll(ctx context.Context, decrypter Decrypter) (map[Key]string, error) {
+	cache, err := m.decryptCiphertexts(ctx, decrypter)
+	if err != nil {
+		return nil, err
+	}
+	return m.Decrypt(cache)
+}
+
+// decryptCiphertexts decrypts each distinct ciphertext in m, in a single operation if decrypter is a BulkDecrypter,
+// and returns a decrypter that serves the results.
+func (m Map) decryptCiphertexts(ctx context.Context, decrypter Decrypter) (cachedDecrypter, error) {
+	var ciphertexts []string
+	seen := make(map[string]bool)
+	for _, c := range m {
+		cts, err := c.ciphertexts()
+		if err != nil {
+			return nil, err
+		}
+		for _, ct := range cts {
+			if !seen[ct] {
+				seen[ct] = true
+				ciphertexts = append(ciphertexts, ct)
+			}
+		}
+	}
+
+	cache := make(cachedDecrypter, len(ciphertexts))
+	if len(ciphertexts) > 0 {
+		if bulk, ok := decrypter.(BulkDecrypter); ok {
+			plaintexts, err := bulk.BulkDecrypt(ctx, ciphertexts)
+			if err != nil {
+				return nil, err
+			}
+			for k, v := range plaintexts {
+				cache[k] = v
+			}
+		} else {
+			for _, ct := range ciphertexts {
+				pt, err := decrypter.DecryptValue(ct)
+				if err != nil {
+					return nil, err
+				}
+				cache[ct] = pt
+			}
+		}
+	}
+	return cache, nil
+}
+
+// Reencrypt returns a copy of m in which every secure value, including the secure leaves of objects and lists, is
+// decrypted with oldDecrypter and encrypted again with newEncrypter. Plaintext values are copied unchanged. Values
+// that shared a ciphertext in m share a ciphertext in the result. If oldDecrypter is a BulkDecrypter, all of the
+// values are decrypted in a single operation.
+func (m Map) Reencrypt(ctx context.Context, oldDecrypter Decrypter, newEncrypter Encrypter) (Map, error) {
+	cache, err := m.decryptCiphertexts(ctx, oldDecrypter)
+	if err != nil {
+		return nil, err
+	}
+	crypter := &reencrypter{plaintexts: cache, encrypter: newEncrypter, ciphertexts: make(map[string]string)}
+
+	result := make(Map, len(m))
+	for k, v := range m {
+		if !v.secure {
+			result[k] = v
+			continue
+		}
+		if v.isExternal() {
+			result[k] = v
+			continue
+		}
+		if v.ref != "" {
+			return nil, errors.Errorf("config key %v: cannot re-encrypt the secret stored externally at %s", k, v.ref)
+		}
+		if !v.object {
+			ct, err := crypter.reencrypt(v.value)
+			if err != nil {
+				return nil, errors.Wrapf(err, "re-encrypting config key %v", k)
+			}
+			result[k] = Value{value: ct, secure: true, typ: v.typ}
+			continue
+		}
+
+		obj, err := v.ToObject()
+		if err != nil {
+			return nil, err
+		}
+		// reencryptObject "decrypts" each secure leaf with the reencrypter, which returns the leaf's new ciphertext,
+		// and then "encrypts" that with the nopCrypter, which leaves it unchanged.
+		reencrypted, err := reencryptObject(obj, crypter, nopCrypter{})
+		if err != nil {
+			return nil, errors.Wrapf(err, "re-encrypting config key %v", k)
+		}
+		bytes, err := json.Marshal(reencrypted)
+		if err != nil {
+			return nil, err
+		}
+		result[k] = NewSecureObjectValue(string(bytes))
+	}
+	return result, nil
+}
+
+// reencrypter maps old ciphertexts to new ones, encrypting each distinct ciphertext's plaintext only once.
+type reencrypter struct {
+	plaintexts  cachedDecrypter
+	encrypter   Encrypter
+	ciphertexts map[string]string
+}
+
+func (r *reencrypter) reencrypt(ciphertext string) (string, error) {
+	if ct, ok := r.ciphertexts[ciphertext]; ok {
+		return ct, nil
+	}
+	pt, err := r.plaintexts.DecryptValue(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	ct, err := r.encrypter.EncryptValue(pt)
+	if err != nil {
+		return "", err
+	}
+	r.ciphertexts[ciphertext] = ct
+	return ct, nil
+}
+
+// DecryptValue implements Decrypter by returning the new ciphertext for the given old ciphertext.
+func (r *reencrypter) DecryptValue(ciphertext string) (string, error) {
+	return r.reencrypt(ciphertext)
+}
+
 func (m Map) Copy(decrypter Decrypter, encrypter Encrypter) (Map, error) {
 	newConfig := make(Map)
 	for k, c := range m {
@@ -57,6 +192,41 @@ func (m Map) Copy(decrypter Decrypter, encrypter Encrypter) (Map, error) {
 	return newConfig, nil
 }
 
+// Clone returns a copy of m that shares no state with it, so that either map may be modified without affecting the
+// other. Values, including objects and their secure leaves, are immutable, so the copy's values keep the secure,
+// object and type metadata of the originals. The clone of a nil map is nil.
+func (m Map) Clone() Map {
+	if m == nil {
+		return nil
+	}
+	c := make(Map, len(m))
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+// MapSnapshot is a point-in-time copy of the contents of a Map, taken by Map.Snapshot.
+type MapSnapshot struct {
+	m Map
+}
+
+// Snapshot records the current contents of the map, so that they can be restored later by Restore.
+func (m Map) Snapshot() MapSnapshot {
+	return MapSnapshot{m: m.Clone()}
+}
+
+// Restore replaces the contents of the map with those recorded by snap, undoing any changes made since the snapshot
+// was taken.
+func (m Map) Restore(snap MapSnapshot) {
+	for k := range m {
+		delete(m, k)
+	}
+	for k, v := range snap.m {
+		m[k] = v
+	}
+}
+
 // HasSecureValue returns true if the config map contains a secure (encrypted) value.
 func (m Map) HasSecureValue() bool {
 	for _, v := range m {
@@ -68,6 +238,44 @@ func (m Map) HasSecureValue() bool {
 	return false
 }
 
+// Find returns the keys in the map that match pattern, in sorted order. The pattern is matched against the fully
+// qualified form of each key (`<namespace>:<name>`), and may contain any number of `*` wildcards, each of which
+// matches any sequence of characters. For example, `aws:*` matches every key in the `aws` namespace, and
+// `myproj:db.*` matches keys such as `myproj:db.host` and `myproj:db.port`.
+func (m Map) Find(pattern string) []Key {
+	var keys KeyArray
+	for k := range m {
+		if matchPattern(pattern, k.String()) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Sort(keys)
+	return keys
+}
+
+// matchPattern returns true if s matches pattern, in which `*` matches any sequence of characters.
+func matchPattern(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+
+	// The first part must be a prefix and the last part a suffix. The parts in between must appear in order.
+	first, last := parts[0], parts[len(parts)-1]
+	if !strings.HasPrefix(s, first) {
+		return false
+	}
+	s = s[len(first):]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+	return strings.HasSuffix(s, last)
+}
+
 // Get gets the value for a given key. If path is true, the key's name portion is treated as a path.
 func (m Map) Get(k Key, path bool) (Value, bool, error) {
 	// If the key isn't a path, go ahead and lookup the value.
@@ -82,50 +290,156 @@ func (m Map) Get(k Key, path bool) (Value, bool, error) {
 		return Value{}, false, err
 	}
 
+	return m.getPath(configKey, p)
+}
+
+// LookupOptions controls the behavior of Map.Lookup.
+type LookupOptions struct {
+	// Path treats the key's name portion as a path, as with Get.
+	Path bool
+	// IgnoreCase matches keys case-insensitively if there is no exact match. Only the key itself is matched this
+	// way; the elements of a path within its value must still match exactly.
+	IgnoreCase bool
+}
+
+// Lookup is like Get, but also returns the key that holds the value. If opts.IgnoreCase is set and k is not in the map,
+// Lookup falls back to a case-insensitive match, and the returned key is the one actually present in the map. It is an
+// error for more than one key to match case-insensitively. If opts.Path is set, the returned key is the key whose value
+// contains the path.
+func (m Map) Lookup(k Key, opts LookupOptions) (Key, Value, bool, error) {
+	configKey, p := k, resource.PropertyPath(nil)
+	if opts.Path {
+		var err error
+		if p, configKey, err = parseKeyPath(k); err != nil {
+			return Key{}, Value{}, false, err
+		}
+	}
+
+	if _, ok := m[configKey]; !ok && opts.IgnoreCase {
+		var matches KeyArray
+		for candidate := range m {
+			if strings.EqualFold(candidate.namespace, configKey.namespace) &&
+				strings.EqualFold(candidate.name, configKey.name) {
+				matches = append(matches, candidate)
+			}
+		}
+		sort.Sort(matches)
+		switch len(matches) {
+		case 0:
+		case 1:
+			configKey = matches[0]
+			if opts.Path {
+				p = append(resource.PropertyPath{configKey.name}, p[1:]...)
+			}
+		default:
+			return Key{}, Value{}, false, errors.Errorf("config key %v is ambiguous: it matches %v and %v",
+				configKey, matches[0], matches[1])
+		}
+	}
+
+	if !opts.Path {
+		v, ok := m[configKey]
+		return configKey, v, ok, nil
+	}
+	v, ok, err := m.getPath(configKey, p)
+	return configKey, v, ok, err
+}
+
+// GetPath gets the value at the given path within the value for key k. The path uses the same syntax as the CLI's
+// `--path` flag (e.g. `servers[0].host`), but is relative to the key rather than including its name. The value keeps
+// its type, e.g. a nested `true` is returned as a bool, and nested secure values remain secure.
+func (m Map) GetPath(k Key, path string) (Value, bool, error) {
+	p, err := parseValuePath(k, path)
+	if err != nil {
+		return Value{}, false, err
+	}
+	if len(p) == 1 {
+		v, ok := m[k]
+		return v, ok, nil
+	}
+
+	// Unlike Get, GetPath keeps the types of scalars within objects.
+	v, ok, err := m.getObjectPath(k, p)
+	if err != nil || !ok {
+		return Value{}, false, err
+	}
+	switch t := v.(type) {
+	case bool:
+		return NewBoolValue(t), true, nil
+	case float64:
+		if t == float64(int64(t)) && t >= -(1<<53) && t <= 1<<53 {
+			return Value{value: strconv.FormatInt(int64(t), 10), typ: TypeInt}, true, nil
+		}
+		return NewFloatValue(t), true, nil
+	}
+	val, err := valueFromObject(v)
+	if err != nil {
+		return Value{}, false, err
+	}
+	return val, true, nil
+}
+
+// getPath gets the value at path p, whose first segment is the name of configKey.
+func (m Map) getPath(configKey Key, p resource.PropertyPath) (Value, bool, error) {
 	// If we only have a single path segment, go ahead and lookup the value.
 	if len(p) == 1 {
 		v, ok := m[configKey]
 		return v, ok, nil
 	}
 
-	// Otherwise, lookup the current root value and save it into a temporary map.
+	v, ok, err := m.getObjectPath(configKey, p)
+	if err != nil || !ok {
+		return Value{}, false, err
+	}
+	val, err := valueFromObject(v)
+	if err != nil {
+		return Value{}, false, err
+	}
+	return val, true, nil
+}
+
+// getObjectPath gets the decoded JSON value at path p, which must have at least two segments, the first of which is
+// the name of configKey.
+func (m Map) getObjectPath(configKey Key, p resource.PropertyPath) (interface{}, bool, error) {
+	// Lookup the current root value and save it into a temporary map.
 	root := make(map[string]interface{})
 	if val, ok := m[configKey]; ok {
 		obj, err := val.ToObject()
 		if err != nil {
-			return Value{}, false, err
+			return nil, false, err
 		}
 		root[configKey.Name()] = obj
 	}
 
 	// Get the value within the object.
 	_, v, ok := getValueForPath(root, p)
-	if !ok {
-		return Value{}, false, nil
-	}
+	return v, ok, nil
+}
 
+// valueFromObject converts a value within an object into a Value.
+func valueFromObject(v interface{}) (Value, error) {
 	// If the value is a secure value, return it as one.
 	if is, s := isSecureValue(v); is {
-		return NewSecureValue(s), true, nil
+		return NewSecureValue(s), nil
 	}
 
 	// If it's a simple type, return it as a regular value.
 	switch t := v.(type) {
 	case string:
-		return NewValue(t), true, nil
+		return NewValue(t), nil
 	case bool, int, uint, int32, uint32, int64, uint64, float32, float64:
-		return NewValue(fmt.Sprintf("%v", v)), true, nil
+		return NewValue(fmt.Sprintf("%v", v)), nil
 	}
 
 	// Otherwise, return it as an object value.
 	json, err := json.Marshal(v)
 	if err != nil {
-		return Value{}, false, err
+		return Value{}, err
 	}
 	if hasSecureValue(v) {
-		return NewSecureObjectValue(string(json)), true, nil
+		return NewSecureObjectValue(string(json)), nil
 	}
-	return NewObjectValue(string(json)), true, nil
+	return NewObjectValue(string(json)), nil
 }
 
 // Remove removes the value for a given key. If path is true, the key's name portion is treated as a path.
@@ -231,6 +545,21 @@ func (m Map) Set(k Key, v Value, path bool) error {
 		return err
 	}
 
+	return m.setPath(configKey, p, v)
+}
+
+// SetPath sets the value at the given path within the value for key k, creating any intermediate objects and arrays
+// that do not yet exist. The path uses the same syntax as GetPath; an empty path replaces the value for k.
+func (m Map) SetPath(k Key, path string, v Value) error {
+	p, err := parseValuePath(k, path)
+	if err != nil {
+		return err
+	}
+	return m.setPath(k, p, v)
+}
+
+// setPath sets the value at path p, whose first segment is the name of configKey.
+func (m Map) setPath(configKey Key, p resource.PropertyPath, v Value) error {
 	// If we only have a single path segment, set the value and return.
 	if len(p) == 1 {
 		m[configKey] = v
@@ -302,9 +631,19 @@ func (m Map) Set(k Key, v Value, path bool) error {
 		cursorKey = pkey
 	}
 
-	// Adjust the value (e.g. convert "true"/"false" to booleans and integers to ints) and set it.
-	adjustedValue := adjustObjectValue(v, path)
-	if _, err = setValue(cursor, cursorKey, adjustedValue, parent, parentKey); err != nil {
+	// Adjust the value (e.g. convert "true"/"false" to booleans and integers to ints) and set it. Objects are
+	// spliced in as-is, so any secure values nested within them keep their `secure` wrappers.
+	var adjustedValue interface{}
+	if v.Object() {
+		obj, err := v.ToObject()
+		if err != nil {
+			return err
+		}
+		adjustedValue = obj
+	} else {
+		adjustedValue = adjustObjectValue(v, true)
+	}
+	if _, err := setValue(cursor, cursorKey, adjustedValue, parent, parentKey); err != nil {
 		return err
 	}
 
@@ -318,7 +657,8 @@ func (m Map) Set(k Key, v Value, path bool) error {
 	if err != nil {
 		return err
 	}
-	if v.Secure() {
+	// The object is secure if the new value is, or if it still holds any of its existing secure values.
+	if v.Secure() || hasSecureValue(root[configKey.Name()]) {
 		m[configKey] = NewSecureObjectValue(string(json))
 	} else {
 		m[configKey] = NewObjectValue(string(json))
@@ -327,6 +667,82 @@ func (m Map) Set(k Key, v Value, path bool) error {
 	return nil
 }
 
+// Redacted returns the contents of the map as plain data suitable for encoding as JSON or YAML, keyed by the fully
+// qualified form of each key. Scalars keep their types and objects keep their structure, but every secure value,
+// including those nested inside objects, is replaced by "[secret]". No decryption is required.
+func (m Map) Redacted() (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		r, err := v.redacted()
+		if err != nil {
+			return nil, errors.Wrapf(err, "redacting config key %v", k)
+		}
+		result[k.String()] = r
+	}
+	return result, nil
+}
+
+// ToInterface returns the contents of the map as plain data, keyed by the fully qualified form of each key. Each value
+// has the same form as in a stack file: plaintext scalars keep their native types, objects keep their structure, and
+// secure values (including those nested inside objects), references and binary values are written as their reserved
+// maps, e.g. `{"secure": "<ciphertext>"}`. FromInterface reverses the conversion without losing any information.
+func (m Map) ToInterface() (map[string]interface{}, error) {
+	result := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		raw, err := v.marshalValue()
+		if err != nil {
+			return nil, errors.Wrapf(err, "converting config key %v", k)
+		}
+		result[k.String()] = raw
+	}
+	return result, nil
+}
+
+// FromInterface returns the map whose plain data form, as returned by Map.ToInterface, is raw. Integers must be
+// represented by Go integer types, as float64 values are read as floats.
+func FromInterface(raw map[string]interface{}) (Map, error) {
+	m := make(Map, len(raw))
+	for name, v := range raw {
+		k, err := ParseKey(name)
+		if err != nil {
+			return nil, err
+		}
+
+		var val Value
+		switch t := v.(type) {
+		case nil:
+			val = NewValue("")
+		case string:
+			val = NewValue(t)
+		case bool:
+			val = NewBoolValue(t)
+		case int:
+			val = NewIntValue(t)
+		case int64:
+			val = Value{value: strconv.FormatInt(t, 10), typ: TypeInt}
+		case uint64:
+			val = Value{value: strconv.FormatUint(t, 10), typ: TypeInt}
+		case float64:
+			val = NewFloatValue(t)
+		default:
+			if err = val.setObject(interfaceMapToStringMap(v)); err != nil {
+				return nil, errors.Wrapf(err, "converting config key %v", k)
+			}
+		}
+		m[k] = val
+	}
+	return m, nil
+}
+
+// MarshalRedacted returns the JSON encoding of the map's redacted contents, as returned by Redacted.
+func (m Map) MarshalRedacted() ([]byte, error) {
+	r, err := m.Redacted()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(r)
+}
+
 func (m Map) MarshalJSON() ([]byte, error) {
 	rawMap := make(map[string]Value, len(m))
 	for k, v := range m {
@@ -411,6 +827,22 @@ func parseKeyPath(k Key) (resource.PropertyPath, Key, error) {
 	return p, configKey, nil
 }
 
+// parseValuePath parses a path relative to the value for key k, and returns the full path including k's name as its
+// first segment.
+func parseValuePath(k Key, path string) (resource.PropertyPath, error) {
+	if k.Name() == "" {
+		return nil, errors.New("config key is empty")
+	}
+	if path == "" {
+		return resource.PropertyPath{k.Name()}, nil
+	}
+	p, err := resource.ParsePropertyPath(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid config value path")
+	}
+	return append(resource.PropertyPath{k.Name()}, p...), nil
+}
+
 // getValueForPath returns the parent, value, and true if the value is found in source given the path segments in p.
 func getValueForPath(source interface{}, p resource.PropertyPath) (interface{}, interface{}, bool) {
 	// If the source is nil, exit early.
@@ -518,6 +950,11 @@ func adjustObjectValue(v Value, path bool) interface{} {
 		return v
 	}
 
+	// If the value has an explicit type, use its native representation.
+	if v.typ != TypeString {
+		return v.typedValue()
+	}
+
 	// If "false" or "true", return the boolean value.
 	if v.value == "false" {
 		return false
@@ -15,6 +15,7 @@
 package config
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"testing"
@@ -1170,6 +1171,297 @@ func TestSetFail(t *testing.T) {
 	}
 }
 
+func TestGetSetPath(t *testing.T) {
+	k := MustMakeKey("my", "db.config")
+	m := Map{}
+
+	// Intermediate objects and arrays are created as needed.
+	assert.NoError(t, m.SetPath(k, "servers[0].host", NewValue("example")))
+	assert.NoError(t, m.SetPath(k, "servers[0].port", NewIntValue(80)))
+	assert.NoError(t, m.SetPath(k, `["a.b"]`, NewBoolValue(true)))
+	assert.Equal(t, Map{
+		k: NewObjectValue(`{"a.b":true,"servers":[{"host":"example","port":80}]}`),
+	}, m)
+
+	v, ok, err := m.GetPath(k, "servers[0].host")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, NewValue("example"), v)
+
+	// Scalars keep their types.
+	v, ok, err = m.GetPath(k, "servers[0].port")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, NewIntValue(80), v)
+
+	v, ok, err = m.GetPath(k, `["a.b"]`)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, NewBoolValue(true), v)
+	assert.Equal(t, TypeBool, v.Type())
+
+	v, ok, err = m.GetPath(k, "servers")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, NewObjectValue(`[{"host":"example","port":80}]`), v)
+
+	_, ok, err = m.GetPath(k, "servers[1]")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	// Whole numbers too large for an int32 are not turned into floats.
+	assert.NoError(t, m.SetPath(k, "account", NewObjectValue("123456789012")))
+	v, ok, err = m.GetPath(k, "account")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, Value{value: "123456789012", typ: TypeInt}, v)
+	assert.NoError(t, m.SetPath(k, "size", NewObjectValue("3000000000")))
+	v, _, err = m.GetPath(k, "size")
+	assert.NoError(t, err)
+	assert.Equal(t, Value{value: "3000000000", typ: TypeInt}, v)
+	assert.NoError(t, m.SetPath(k, "ratio", NewObjectValue("1.5")))
+	v, _, err = m.GetPath(k, "ratio")
+	assert.NoError(t, err)
+	assert.Equal(t, NewFloatValue(1.5), v)
+
+	// Nested secure values remain secure.
+	assert.NoError(t, m.SetPath(k, "servers[0].password", NewSecureValue("ciphertext")))
+	v, ok, err = m.GetPath(k, "servers[0].password")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, NewSecureValue("ciphertext"), v)
+	assert.True(t, v.Secure())
+
+	v, ok, err = m.GetPath(k, "servers[0].port")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, NewIntValue(80), v)
+
+	// An empty path addresses the value for the key itself.
+	assert.NoError(t, m.SetPath(k, "", NewValue("plain")))
+	v, ok, err = m.GetPath(k, "")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, NewValue("plain"), v)
+
+	_, _, err = m.GetPath(k, "servers[")
+	assert.Error(t, err)
+	assert.Error(t, m.SetPath(MustMakeKey("my", ""), "a", NewValue("b")))
+}
+
+func TestFind(t *testing.T) {
+	m := Map{
+		MustMakeKey("aws", "region"):     NewValue("us-west-2"),
+		MustMakeKey("aws", "profile"):    NewValue("default"),
+		MustMakeKey("awsx", "region"):    NewValue("us-east-1"),
+		MustMakeKey("myproj", "db.host"): NewValue("localhost"),
+		MustMakeKey("myproj", "db.port"): NewValue("5432"),
+		MustMakeKey("myproj", "dbname"):  NewValue("app"),
+	}
+
+	tests := []struct {
+		Pattern  string
+		Expected []Key
+	}{
+		{"aws:*", []Key{MustMakeKey("aws", "profile"), MustMakeKey("aws", "region")}},
+		{"myproj:db.*", []Key{MustMakeKey("myproj", "db.host"), MustMakeKey("myproj", "db.port")}},
+		{"*:region", []Key{MustMakeKey("aws", "region"), MustMakeKey("awsx", "region")}},
+		{"myproj:*o*t", []Key{MustMakeKey("myproj", "db.host"), MustMakeKey("myproj", "db.port")}},
+		{"aws:region", []Key{MustMakeKey("aws", "region")}},
+		{"aws:reg", nil},
+		{"gcp:*", nil},
+	}
+	for _, test := range tests {
+		t.Run(test.Pattern, func(t *testing.T) {
+			assert.Equal(t, test.Expected, m.Find(test.Pattern))
+		})
+	}
+	assert.Len(t, m.Find("*"), len(m))
+}
+
+func TestLookup(t *testing.T) {
+	m := Map{
+		MustMakeKey("aws", "region"):     NewValue("us-west-2"),
+		MustMakeKey("my", "DatabaseURL"): NewValue("postgres://"),
+		MustMakeKey("my", "servers"):     NewObjectValue(`[{"host":"a"}]`),
+		MustMakeKey("my", "dup"):         NewValue("lower"),
+		MustMakeKey("my", "DUP"):         NewValue("upper"),
+	}
+
+	// Strict lookups only match exactly.
+	_, _, ok, err := m.Lookup(MustMakeKey("my", "databaseurl"), LookupOptions{})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	k, v, ok, err := m.Lookup(MustMakeKey("my", "databaseurl"), LookupOptions{IgnoreCase: true})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, MustMakeKey("my", "DatabaseURL"), k)
+	assert.Equal(t, NewValue("postgres://"), v)
+
+	k, v, ok, err = m.Lookup(MustMakeKey("AWS", "Region"), LookupOptions{IgnoreCase: true})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, MustMakeKey("aws", "region"), k)
+	assert.Equal(t, NewValue("us-west-2"), v)
+
+	k, v, ok, err = m.Lookup(MustMakeKey("my", "Servers[0].host"), LookupOptions{Path: true, IgnoreCase: true})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, MustMakeKey("my", "servers"), k)
+	assert.Equal(t, NewValue("a"), v)
+
+	// An exact match wins over case-insensitive ones.
+	k, v, ok, err = m.Lookup(MustMakeKey("my", "dup"), LookupOptions{IgnoreCase: true})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, MustMakeKey("my", "dup"), k)
+	assert.Equal(t, NewValue("lower"), v)
+
+	_, _, _, err = m.Lookup(MustMakeKey("my", "Dup"), LookupOptions{IgnoreCase: true})
+	assert.Error(t, err)
+}
+
+func TestMarshalRedacted(t *testing.T) {
+	m := Map{
+		MustMakeKey("my", "name"):    NewValue("value"),
+		MustMakeKey("my", "count"):   NewIntValue(3),
+		MustMakeKey("my", "enabled"): NewBoolValue(true),
+		MustMakeKey("my", "token"):   NewSecureValue("ciphertext"),
+		MustMakeKey("my", "object"): NewSecureObjectValue(
+			`{"password":{"secure":"ciphertext"},"port":5432,"hosts":["a",{"secure":"ciphertext"}]}`),
+	}
+
+	b, err := m.MarshalRedacted()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"my:name": "value",
+		"my:count": 3,
+		"my:enabled": true,
+		"my:token": "[secret]",
+		"my:object": {"password": "[secret]", "port": 5432, "hosts": ["a", "[secret]"]}
+	}`, string(b))
+	assert.NotContains(t, string(b), "ciphertext")
+}
+
+func TestClone(t *testing.T) {
+	assert.Nil(t, Map(nil).Clone())
+
+	base := Map{
+		MustMakeKey("my", "port"):   NewIntValue(80),
+		MustMakeKey("my", "secret"): NewSecureValue("securevalue"),
+		MustMakeKey("my", "object"): NewSecureObjectValue(`{"a":{"secure":"securea"},"b":"1"}`),
+	}
+	prod := base.Clone()
+	assert.Equal(t, base, prod)
+
+	// Changes to the clone, including changes within object values, are not visible in the original.
+	assert.NoError(t, prod.Set(MustMakeKey("my", "port"), NewIntValue(443), false))
+	assert.NoError(t, prod.SetPath(MustMakeKey("my", "object"), "b", NewValue("2")))
+	assert.NoError(t, prod.Remove(MustMakeKey("my", "secret"), false))
+
+	assert.Equal(t, NewIntValue(80), base[MustMakeKey("my", "port")])
+	assert.Equal(t, NewSecureObjectValue(`{"a":{"secure":"securea"},"b":"1"}`), base[MustMakeKey("my", "object")])
+	assert.True(t, base[MustMakeKey("my", "secret")].Secure())
+	assert.True(t, prod[MustMakeKey("my", "object")].Secure())
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	a, b, c := MustMakeKey("my", "a"), MustMakeKey("my", "b"), MustMakeKey("my", "c")
+	m := Map{a: NewValue("1"), b: NewObjectValue(`{"x":"y"}`)}
+
+	snap := m.Snapshot()
+	assert.NoError(t, m.Set(a, NewValue("2"), false))
+	assert.NoError(t, m.SetPath(b, "x", NewValue("z")))
+	assert.NoError(t, m.Set(c, NewValue("3"), false))
+
+	m.Restore(snap)
+	assert.Equal(t, Map{a: NewValue("1"), b: NewObjectValue(`{"x":"y"}`)}, m)
+
+	// A snapshot can be restored more than once.
+	delete(m, a)
+	m.Restore(snap)
+	assert.Equal(t, Map{a: NewValue("1"), b: NewObjectValue(`{"x":"y"}`)}, m)
+}
+
+// bulkDecrypter records the batches of ciphertexts it is asked to decrypt.
+type bulkDecrypter struct {
+	countingDecrypter
+	batches [][]string
+}
+
+func (d *bulkDecrypter) BulkDecrypt(ctx context.Context, ciphertexts []string) (map[string]string, error) {
+	d.batches = append(d.batches, ciphertexts)
+	result := make(map[string]string, len(ciphertexts))
+	for _, ct := range ciphertexts {
+		result[ct] = "plain-" + ct
+	}
+	return result, nil
+}
+
+func TestDecryptAll(t *testing.T) {
+	m := Map{
+		MustMakeKey("my", "plain"):  NewValue("value"),
+		MustMakeKey("my", "secret"): NewSecureValue("s1"),
+		MustMakeKey("my", "again"):  NewSecureValue("s1"),
+		MustMakeKey("my", "object"): NewSecureObjectValue(`{"inner":{"secure":"s2"},"list":[{"secure":"s3"}]}`),
+	}
+	expected := map[Key]string{
+		MustMakeKey("my", "plain"):  "value",
+		MustMakeKey("my", "secret"): "plain-s1",
+		MustMakeKey("my", "again"):  "plain-s1",
+		MustMakeKey("my", "object"): `{"inner":"plain-s2","list":["plain-s3"]}`,
+	}
+
+	bulk := &bulkDecrypter{}
+	r, err := m.DecryptAll(context.Background(), bulk)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, r)
+	assert.Len(t, bulk.batches, 1)
+	assert.ElementsMatch(t, []string{"s1", "s2", "s3"}, bulk.batches[0])
+	assert.Empty(t, bulk.decrypted)
+
+	single := &countingDecrypter{}
+	r, err = m.DecryptAll(context.Background(), single)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, r)
+	assert.ElementsMatch(t, []string{"s1", "s2", "s3"}, single.decrypted)
+}
+
+func TestReencrypt(t *testing.T) {
+	m := Map{
+		MustMakeKey("my", "plain"):  NewIntValue(42),
+		MustMakeKey("my", "secret"): NewSecureValue("s1"),
+		MustMakeKey("my", "again"):  NewSecureValue("s1"),
+		MustMakeKey("my", "object"): NewSecureObjectValue(`{"inner":{"secure":"s2"},"list":["a",{"secure":"s3"}]}`),
+	}
+
+	bulk := &bulkDecrypter{}
+	r, err := m.Reencrypt(context.Background(), bulk, newPrefixCrypter("new:"))
+	assert.NoError(t, err)
+	assert.Equal(t, Map{
+		MustMakeKey("my", "plain"):  NewIntValue(42),
+		MustMakeKey("my", "secret"): NewSecureValue("new:plain-s1"),
+		MustMakeKey("my", "again"):  NewSecureValue("new:plain-s1"),
+		MustMakeKey("my", "object"): NewSecureObjectValue(
+			`{"inner":{"secure":"new:plain-s2"},"list":["a",{"secure":"new:plain-s3"}]}`),
+	}, r)
+	assert.Len(t, bulk.batches, 1)
+	assert.Empty(t, bulk.decrypted)
+
+	// The original map is unchanged.
+	assert.Equal(t, NewSecureValue("s1"), m[MustMakeKey("my", "secret")])
+
+	// The nested secrets survive a round trip to the new provider.
+	decrypted, err := r.Decrypt(newPrefixCrypter("new:"))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"inner":"plain-s2","list":["a","plain-s3"]}`, decrypted[MustMakeKey("my", "object")])
+
+	external := Map{MustMakeKey("my", "blob"): NewBlobRefValue("file:blob", "hash", true)}
+	_, err = external.Reencrypt(context.Background(), bulk, newPrefixCrypter("new:"))
+	assert.Error(t, err)
+}
+
 func TestCopyMap(t *testing.T) {
 	tests := []struct {
 		Config   Map
@@ -1265,3 +1557,81 @@ func roundtripMap(m Map, marshal func(v interface{}) ([]byte, error),
 	err = unmarshal(b, &newM)
 	return newM, err
 }
+
+func TestSetPathNestedSecrets(t *testing.T) {
+	k := MustMakeKey("my", "obj")
+	m := Map{k: NewSecureObjectValue(`{"a":{"b":["x",{"secure":"ct1"}]}}`)}
+
+	// Objects set within an object keep the wrappers of their own nested secrets.
+	assert.NoError(t, m.SetPath(k, "c", NewObjectValue(`{"d":[[{"secure":"ct2"}]]}`)))
+	assert.NoError(t, m.SetPath(k, "a.e", NewObjectValue(`{"f":"plain"}`)))
+	// An object read by path can be written back by path without flattening its secrets.
+	v, ok, err := m.Get(MustMakeKey("my", "obj.a"), true)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.NoError(t, m.SetPath(k, "g", v))
+
+	assert.Equal(t, NewSecureObjectValue(
+		`{"a":{"b":["x",{"secure":"ct1"}],"e":{"f":"plain"}},"c":{"d":[[{"secure":"ct2"}]]},`+
+			`"g":{"b":["x",{"secure":"ct1"}],"e":{"f":"plain"}}}`), m[k])
+
+	decrypted, err := m.Decrypt(newPrefixCrypter("c"))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":{"b":["x","t1"],"e":{"f":"plain"}},"c":{"d":[["t2"]]},"g":{"b":["x","t1"],"e":{"f":"plain"}}}`,
+		decrypted[k])
+
+	// A plaintext object stays plaintext.
+	plain := Map{}
+	assert.NoError(t, plain.SetPath(k, "a", NewObjectValue(`{"b":1}`)))
+	assert.Equal(t, NewObjectValue(`{"a":{"b":1}}`), plain[k])
+}
+
+func TestInterfaceRoundTrip(t *testing.T) {
+	bytesValue, err := NewSecureBytesValue([]byte{0, 1, 2}, newPrefixCrypter("enc-"))
+	assert.NoError(t, err)
+
+	m := Map{
+		MustMakeKey("my", "string"):  NewValue("hello"),
+		MustMakeKey("my", "numeric"): NewValue("42"),
+		MustMakeKey("my", "int"):     NewIntValue(42),
+		MustMakeKey("my", "float"):   NewFloatValue(1.5),
+		MustMakeKey("my", "whole"):   NewFloatValue(2),
+		MustMakeKey("my", "bool"):    NewBoolValue(true),
+		MustMakeKey("my", "secret"):  NewSecureValue("ciphertext"),
+		MustMakeKey("my", "object"):  NewSecureObjectValue(`{"a":[1,{"secure":"ct"}],"b":{"c":true}}`),
+		MustMakeKey("my", "plain"):   NewObjectValue(`{"a":"b"}`),
+		MustMakeKey("my", "blob"):    NewBlobRefValue("file:blob", "hash", true),
+		MustMakeKey("my", "ref"):     NewExternalRefValue("vault://secret/token"),
+		MustMakeKey("my", "bytes"):   bytesValue,
+		MustMakeKey("my", "binary"):  NewBytesValue([]byte("data")),
+	}
+
+	raw, err := m.ToInterface()
+	assert.NoError(t, err)
+	assert.Equal(t, "42", raw["my:numeric"])
+	assert.Equal(t, int64(42), raw["my:int"])
+	assert.Equal(t, true, raw["my:bool"])
+	assert.Equal(t, map[string]string{"secure": "ciphertext"}, raw["my:secret"])
+	assert.Equal(t, map[string]interface{}{
+		"a": []interface{}{float64(1), map[string]interface{}{"secure": "ct"}},
+		"b": map[string]interface{}{"c": true},
+	}, raw["my:object"])
+
+	roundtripped, err := FromInterface(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, m, roundtripped)
+
+	// Data decoded from YAML, whose maps have interface{} keys, is accepted too.
+	fromYAML, err := FromInterface(map[string]interface{}{
+		"my:object": map[interface{}]interface{}{"inner": map[interface{}]interface{}{"secure": "ct"}},
+		"my:count":  3,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, Map{
+		MustMakeKey("my", "object"): NewSecureObjectValue(`{"inner":{"secure":"ct"}}`),
+		MustMakeKey("my", "count"):  NewIntValue(3),
+	}, fromYAML)
+
+	_, err = FromInterface(map[string]interface{}{"not-a-key": "x"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,208 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// ListStrategy determines how Merge combines two lists.
+type ListStrategy int
+
+const (
+	// ListReplace replaces the base list with the overlay list.
+	ListReplace ListStrategy = iota
+	// ListAppend appends the elements of the overlay list to the base list.
+	ListAppend
+	// ListMergeByKey deep merges elements of the two lists that are objects with the same value for the property named
+	// by MergeOptions.MergeKey. The remaining overlay elements are appended to the base list.
+	ListMergeByKey
+)
+
+// MergeOptions controls the behavior of Merge.
+type MergeOptions struct {
+	// Lists is the strategy used to combine lists.
+	Lists ListStrategy
+	// MergeKey is the name of the property that identifies list elements when Lists is ListMergeByKey.
+	MergeKey string
+}
+
+// Merge returns a new map holding the values in m overlaid with the values in overlay. Neither map is modified.
+//
+// When both maps hold an object value for the same key, the objects are merged deeply: properties present in only one
+// object are kept, and properties present in both are merged recursively. Lists are combined according to
+// opts.Lists. In all other cases the overlay value takes precedence, except that a secure value is never replaced by a
+// plaintext one: if exactly one of two conflicting scalars is secure, the secure value wins.
+func (m Map) Merge(overlay Map, opts MergeOptions) (Map, error) {
+	if opts.Lists == ListMergeByKey && opts.MergeKey == "" {
+		return nil, errors.New("a merge key is required to merge lists by key")
+	}
+
+	result := make(Map, len(m)+len(overlay))
+	for k, v := range m {
+		result[k] = v
+	}
+	for k, o := range overlay {
+		b, has := result[k]
+		if !has {
+			result[k] = o
+			continue
+		}
+		v, err := mergeValues(b, o, opts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "merging config key %v", k)
+		}
+		result[k] = v
+	}
+	return result, nil
+}
+
+func mergeValues(base, overlay Value, opts MergeOptions) (Value, error) {
+	if !base.Object() || !overlay.Object() {
+		if base.Secure() && !base.Object() && !overlay.Secure() {
+			return base, nil
+		}
+		return overlay, nil
+	}
+
+	b, err := base.ToObject()
+	if err != nil {
+		return Value{}, err
+	}
+	o, err := overlay.ToObject()
+	if err != nil {
+		return Value{}, err
+	}
+	merged, err := mergeObjects(b, o, opts)
+	if err != nil {
+		return Value{}, err
+	}
+
+	bytes, err := json.Marshal(merged)
+	if err != nil {
+		return Value{}, err
+	}
+	if hasSecureValue(merged) {
+		return NewSecureObjectValue(string(bytes)), nil
+	}
+	return NewObjectValue(string(bytes)), nil
+}
+
+func mergeObjects(base, overlay interface{}, opts MergeOptions) (interface{}, error) {
+	baseSecure, _ := isSecureValue(base)
+	overlaySecure, _ := isSecureValue(overlay)
+	if baseSecure || overlaySecure {
+		if baseSecure && !overlaySecure && isScalar(overlay) {
+			return base, nil
+		}
+		return overlay, nil
+	}
+
+	switch b := base.(type) {
+	case map[string]interface{}:
+		o, ok := overlay.(map[string]interface{})
+		if !ok {
+			return overlay, nil
+		}
+		result := make(map[string]interface{}, len(b)+len(o))
+		for k, v := range b {
+			result[k] = v
+		}
+		for k, ov := range o {
+			bv, has := result[k]
+			if !has {
+				result[k] = ov
+				continue
+			}
+			v, err := mergeObjects(bv, ov, opts)
+			if err != nil {
+				return nil, err
+			}
+			result[k] = v
+		}
+		return result, nil
+	case []interface{}:
+		o, ok := overlay.([]interface{})
+		if !ok {
+			return overlay, nil
+		}
+		return mergeLists(b, o, opts)
+	default:
+		return overlay, nil
+	}
+}
+
+func mergeLists(base, overlay []interface{}, opts MergeOptions) ([]interface{}, error) {
+	switch opts.Lists {
+	case ListReplace:
+		return overlay, nil
+	case ListAppend:
+		result := make([]interface{}, 0, len(base)+len(overlay))
+		result = append(result, base...)
+		return append(result, overlay...), nil
+	case ListMergeByKey:
+		result := make([]interface{}, len(base), len(base)+len(overlay))
+		copy(result, base)
+
+		index := make(map[interface{}]int)
+		for i, e := range base {
+			if id, ok := mergeKeyOf(e, opts.MergeKey); ok {
+				if _, dup := index[id]; dup {
+					return nil, errors.Errorf("list contains more than one element with %s %v", opts.MergeKey, id)
+				}
+				index[id] = i
+			}
+		}
+		for _, e := range overlay {
+			id, ok := mergeKeyOf(e, opts.MergeKey)
+			if i, has := index[id]; ok && has {
+				merged, err := mergeObjects(result[i], e, opts)
+				if err != nil {
+					return nil, err
+				}
+				result[i] = merged
+				continue
+			}
+			result = append(result, e)
+		}
+		return result, nil
+	default:
+		return nil, errors.Errorf("unknown list strategy %d", opts.Lists)
+	}
+}
+
+// mergeKeyOf returns the value of the merge key property of e, if e is an object with a scalar value for it.
+func mergeKeyOf(e interface{}, mergeKey string) (interface{}, bool) {
+	obj, ok := e.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	id, ok := obj[mergeKey]
+	if !ok || !isScalar(id) {
+		return nil, false
+	}
+	return id, true
+}
+
+func isScalar(v interface{}) bool {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	default:
+		return true
+	}
+}
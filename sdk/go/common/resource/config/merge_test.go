@@ -0,0 +1,88 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+	base := Map{
+		MustMakeKey("my", "name"):     NewValue("base"),
+		MustMakeKey("my", "password"): NewSecureValue("securebase"),
+		MustMakeKey("my", "token"):    NewSecureValue("securetoken"),
+		MustMakeKey("my", "only"):     NewValue("base-only"),
+		MustMakeKey("my", "object"):   NewObjectValue(`{"a":"1","nested":{"b":"2","c":"3"},"list":["x"]}`),
+		MustMakeKey("my", "servers"):  NewObjectValue(`[{"name":"a","port":80},{"name":"b","port":81}]`),
+	}
+	overlay := Map{
+		MustMakeKey("my", "name"):     NewValue("overlay"),
+		MustMakeKey("my", "password"): NewValue("plaintext"),
+		MustMakeKey("my", "token"):    NewSecureValue("securenew"),
+		MustMakeKey("my", "extra"):    NewValue("overlay-only"),
+		MustMakeKey("my", "object"):   NewSecureObjectValue(`{"nested":{"c":{"secure":"securec"}},"list":["y"]}`),
+		MustMakeKey("my", "servers"):  NewObjectValue(`[{"name":"b","port":8081},{"name":"c","port":82}]`),
+	}
+
+	tests := []struct {
+		Opts    MergeOptions
+		Object  Value
+		Servers Value
+	}{
+		{
+			Opts:    MergeOptions{Lists: ListReplace},
+			Object:  NewSecureObjectValue(`{"a":"1","list":["y"],"nested":{"b":"2","c":{"secure":"securec"}}}`),
+			Servers: NewObjectValue(`[{"name":"b","port":8081},{"name":"c","port":82}]`),
+		},
+		{
+			Opts:   MergeOptions{Lists: ListAppend},
+			Object: NewSecureObjectValue(`{"a":"1","list":["x","y"],"nested":{"b":"2","c":{"secure":"securec"}}}`),
+			Servers: NewObjectValue(
+				`[{"name":"a","port":80},{"name":"b","port":81},{"name":"b","port":8081},{"name":"c","port":82}]`),
+		},
+		{
+			Opts:    MergeOptions{Lists: ListMergeByKey, MergeKey: "name"},
+			Object:  NewSecureObjectValue(`{"a":"1","list":["x","y"],"nested":{"b":"2","c":{"secure":"securec"}}}`),
+			Servers: NewObjectValue(`[{"name":"a","port":80},{"name":"b","port":8081},{"name":"c","port":82}]`),
+		},
+	}
+
+	for _, test := range tests {
+		r, err := base.Merge(overlay, test.Opts)
+		assert.NoError(t, err)
+		assert.Equal(t, Map{
+			MustMakeKey("my", "name"):     NewValue("overlay"),
+			MustMakeKey("my", "password"): NewSecureValue("securebase"),
+			MustMakeKey("my", "token"):    NewSecureValue("securenew"),
+			MustMakeKey("my", "only"):     NewValue("base-only"),
+			MustMakeKey("my", "extra"):    NewValue("overlay-only"),
+			MustMakeKey("my", "object"):   test.Object,
+			MustMakeKey("my", "servers"):  test.Servers,
+		}, r)
+	}
+
+	// The inputs are not modified.
+	assert.Equal(t, NewValue("base"), base[MustMakeKey("my", "name")])
+	assert.Len(t, base, 6)
+
+	_, err := base.Merge(overlay, MergeOptions{Lists: ListMergeByKey})
+	assert.Error(t, err)
+
+	dups := Map{MustMakeKey("my", "servers"): NewObjectValue(`[{"name":"a"},{"name":"a"}]`)}
+	_, err = dups.Merge(overlay, MergeOptions{Lists: ListMergeByKey, MergeKey: "name"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,175 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Labels holds free-form metadata about a configuration key, such as its owner, the ticket that introduced it or the
+// date its value is next due to be rotated.
+type Labels map[string]string
+
+// Metadata holds the labels attached to configuration keys. Labels never affect the values a program sees. In a stack
+// file, they are written to a `metadata` section alongside the configuration, keyed by fully qualified key names.
+type Metadata map[Key]Labels
+
+// ParseLabel parses a label of the form `name=value`.
+func ParseLabel(s string) (string, string, error) {
+	idx := strings.Index(s, "=")
+	if idx < 0 {
+		return "", "", errors.Errorf("label %q must be of the form name=value", s)
+	}
+	name, value := s[:idx], s[idx+1:]
+	if err := validateLabelName(name); err != nil {
+		return "", "", err
+	}
+	return name, value, nil
+}
+
+func validateLabelName(name string) error {
+	if name == "" || strings.ContainsAny(name, "= \t\n") {
+		return errors.Errorf("invalid label name %q", name)
+	}
+	return nil
+}
+
+// Get returns the labels attached to k, which may be nil.
+func (md Metadata) Get(k Key) Labels {
+	return md[k]
+}
+
+// Set attaches the label name with the given value to k, replacing any existing value for the label.
+func (md Metadata) Set(k Key, name, value string) error {
+	if err := validateLabelName(name); err != nil {
+		return err
+	}
+	if md[k] == nil {
+		md[k] = make(Labels)
+	}
+	md[k][name] = value
+	return nil
+}
+
+// Remove detaches the label name from k. If name is empty, all of k's labels are removed.
+func (md Metadata) Remove(k Key, name string) {
+	if name != "" {
+		delete(md[k], name)
+	}
+	if name == "" || len(md[k]) == 0 {
+		delete(md, k)
+	}
+}
+
+// Rename moves the labels attached to from so that they are attached to to instead.
+func (md Metadata) Rename(from, to Key) {
+	if labels, ok := md[from]; ok {
+		md[to] = labels
+		delete(md, from)
+	}
+}
+
+// Prune removes the labels of every key that has no value in m.
+func (md Metadata) Prune(m Map) {
+	for k := range md {
+		if _, ok := m[k]; !ok {
+			delete(md, k)
+		}
+	}
+}
+
+// Select returns the keys whose labels include every label in selector, in sorted order.
+func (md Metadata) Select(selector Labels) []Key {
+	var keys KeyArray
+	for k, labels := range md {
+		if labels.matches(selector) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Sort(keys)
+	return keys
+}
+
+// Labeled returns the subset of m whose keys have metadata that includes every label in selector.
+func (m Map) Labeled(md Metadata, selector Labels) Map {
+	result := make(Map)
+	for k, v := range m {
+		if md[k].matches(selector) {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+func (l Labels) matches(selector Labels) bool {
+	for name, value := range selector {
+		if v, ok := l[name]; !ok || v != value {
+			return false
+		}
+	}
+	return true
+}
+
+func (md Metadata) MarshalJSON() ([]byte, error) {
+	rawMap := make(map[string]Labels, len(md))
+	for k, l := range md {
+		rawMap[k.String()] = l
+	}
+
+	return json.Marshal(rawMap)
+}
+
+func (md *Metadata) UnmarshalJSON(b []byte) error {
+	rawMap := make(map[string]Labels)
+	if err := json.Unmarshal(b, &rawMap); err != nil {
+		return errors.Wrap(err, "could not unmarshal metadata")
+	}
+	return md.fromRaw(rawMap)
+}
+
+func (md Metadata) MarshalYAML() (interface{}, error) {
+	rawMap := make(map[string]Labels, len(md))
+	for k, l := range md {
+		rawMap[k.String()] = l
+	}
+
+	return rawMap, nil
+}
+
+func (md *Metadata) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	rawMap := make(map[string]Labels)
+	if err := unmarshal(&rawMap); err != nil {
+		return errors.Wrap(err, "could not unmarshal metadata")
+	}
+	return md.fromRaw(rawMap)
+}
+
+func (md *Metadata) fromRaw(rawMap map[string]Labels) error {
+	newMetadata := make(Metadata, len(rawMap))
+	for k, l := range rawMap {
+		pk, err := ParseKey(k)
+		if err != nil {
+			return errors.Wrap(err, "could not unmarshal metadata")
+		}
+		newMetadata[pk] = l
+	}
+
+	*md = newMetadata
+	return nil
+}
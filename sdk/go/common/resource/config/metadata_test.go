@@ -0,0 +1,92 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestMetadata(t *testing.T) {
+	password := MustMakeKey("my", "password")
+	token := MustMakeKey("my", "token")
+	region := MustMakeKey("aws", "region")
+
+	md := Metadata{}
+	assert.NoError(t, md.Set(password, "owner", "team-a"))
+	assert.NoError(t, md.Set(password, "rotate", "2021-06-01"))
+	assert.NoError(t, md.Set(token, "owner", "team-b"))
+	assert.NoError(t, md.Set(region, "owner", "team-a"))
+	assert.Error(t, md.Set(region, "", "x"))
+	assert.Error(t, md.Set(region, "a=b", "x"))
+
+	assert.Equal(t, Labels{"owner": "team-a", "rotate": "2021-06-01"}, md.Get(password))
+	assert.Nil(t, md.Get(MustMakeKey("my", "missing")))
+	assert.Equal(t, []Key{region, password}, md.Select(Labels{"owner": "team-a"}))
+	assert.Equal(t, []Key{password}, md.Select(Labels{"owner": "team-a", "rotate": "2021-06-01"}))
+	assert.Empty(t, md.Select(Labels{"owner": "team-c"}))
+
+	m := Map{
+		password:                  NewSecureValue("ciphertext"),
+		token:                     NewSecureValue("ciphertext"),
+		MustMakeKey("my", "name"): NewValue("name"),
+	}
+	assert.Equal(t, Map{password: NewSecureValue("ciphertext")}, m.Labeled(md, Labels{"owner": "team-a"}))
+	assert.Equal(t, m, m.Labeled(md, nil))
+
+	// Metadata survives a round trip through a stack file.
+	b, err := yaml.Marshal(md)
+	assert.NoError(t, err)
+	var roundtripped Metadata
+	assert.NoError(t, yaml.Unmarshal(b, &roundtripped))
+	assert.Equal(t, md, roundtripped)
+	b, err = json.Marshal(md)
+	assert.NoError(t, err)
+	roundtripped = nil
+	assert.NoError(t, json.Unmarshal(b, &roundtripped))
+	assert.Equal(t, md, roundtripped)
+
+	md.Remove(password, "rotate")
+	assert.Equal(t, Labels{"owner": "team-a"}, md.Get(password))
+	md.Remove(password, "owner")
+	assert.NotContains(t, md, password)
+	md.Remove(token, "")
+	assert.NotContains(t, md, token)
+
+	md.Rename(region, MustMakeKey("my", "region"))
+	assert.Equal(t, Metadata{MustMakeKey("my", "region"): {"owner": "team-a"}}, md)
+	md.Prune(m)
+	assert.Empty(t, md)
+}
+
+func TestParseLabel(t *testing.T) {
+	name, value, err := ParseLabel("owner=team-a")
+	assert.NoError(t, err)
+	assert.Equal(t, "owner", name)
+	assert.Equal(t, "team-a", value)
+
+	name, value, err = ParseLabel("note=a=b")
+	assert.NoError(t, err)
+	assert.Equal(t, "note", name)
+	assert.Equal(t, "a=b", value)
+
+	_, _, err = ParseLabel("owner")
+	assert.Error(t, err)
+	_, _, err = ParseLabel("=value")
+	assert.Error(t, err)
+}
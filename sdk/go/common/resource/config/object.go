@@ -0,0 +1,137 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
+)
+
+// NewObjectValueWithSecrets returns an object value for v, which must be a map or a list, in which only the leaves at
+// the given paths are secret. Each of those leaves is encrypted individually using encrypter, so that when the value
+// is written to a stack file only those leaves use the `secure:` representation. Paths use the same syntax as
+// Map.GetPath (e.g. `db.password` or `hosts[1]`), and each must name a scalar within v.
+func NewObjectValueWithSecrets(v interface{}, secretPaths []string, encrypter Encrypter) (Value, error) {
+	// Round-trip v through JSON to normalize it to JSON's data model. This also gives us a copy that we are free to
+	// modify.
+	bytes, err := json.Marshal(v)
+	if err != nil {
+		return Value{}, err
+	}
+	var obj interface{}
+	if err = json.Unmarshal(bytes, &obj); err != nil {
+		return Value{}, err
+	}
+	if isScalar(obj) {
+		return Value{}, errors.New("expected a map or a list")
+	}
+
+	for _, path := range secretPaths {
+		p, err := resource.ParsePropertyPath(path)
+		if err != nil {
+			return Value{}, errors.Wrapf(err, "invalid secret path %q", path)
+		}
+		if len(p) == 0 {
+			return Value{}, errors.New("secret paths may not be empty")
+		}
+		parent, leaf, ok := getValueForPath(obj, p)
+		if !ok {
+			return Value{}, errors.Errorf("secret path %q does not exist", path)
+		}
+		if !isScalar(leaf) {
+			return Value{}, errors.Errorf("secret path %q must refer to a scalar value", path)
+		}
+
+		plaintext, ok := leaf.(string)
+		if !ok {
+			plaintext = fmt.Sprintf("%v", leaf)
+		}
+		ct, err := encrypter.EncryptValue(plaintext)
+		if err != nil {
+			return Value{}, err
+		}
+
+		secure := map[string]interface{}{"secure": ct}
+		switch t := parent.(type) {
+		case map[string]interface{}:
+			t[p[len(p)-1].(string)] = secure
+		case []interface{}:
+			t[p[len(p)-1].(int)] = secure
+		}
+	}
+
+	if bytes, err = json.Marshal(obj); err != nil {
+		return Value{}, err
+	}
+	if hasSecureValue(obj) {
+		return NewSecureObjectValue(string(bytes)), nil
+	}
+	return NewObjectValue(string(bytes)), nil
+}
+
+// SecretPaths returns the sorted paths of the secret leaves of an object value, in the syntax accepted by
+// Map.GetPath. A value that is not an object has no secret leaves.
+func (c Value) SecretPaths() ([]string, error) {
+	if !c.object || !c.secure {
+		return nil, nil
+	}
+	obj, err := c.ToObject()
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	var walk func(path string, v interface{})
+	walk = func(path string, v interface{}) {
+		if is, _ := isSecureValue(v); is {
+			paths = append(paths, path)
+			return
+		}
+		switch t := v.(type) {
+		case map[string]interface{}:
+			for k, e := range t {
+				walk(appendPathKey(path, k), e)
+			}
+		case []interface{}:
+			for i, e := range t {
+				walk(path+"["+strconv.Itoa(i)+"]", e)
+			}
+		}
+	}
+	walk("", obj)
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// appendPathKey appends a map key to a path, quoting it if it contains characters that are special in paths.
+func appendPathKey(path, key string) string {
+	if key == "" || strings.ContainsAny(key, `.[]"`) {
+		quoted, err := json.Marshal(key)
+		contract.AssertNoError(err)
+		return path + "[" + string(quoted) + "]"
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
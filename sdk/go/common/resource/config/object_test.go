@@ -0,0 +1,83 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestObjectValueWithSecrets(t *testing.T) {
+	crypter := newPrefixCrypter("enc-")
+
+	v, err := NewObjectValueWithSecrets(map[string]interface{}{
+		"db": map[string]interface{}{
+			"host":     "localhost",
+			"password": "hunter2",
+			"port":     5432,
+		},
+		"tokens":    []interface{}{"public", "private"},
+		"a.b":       "dotted",
+		"unrelated": true,
+	}, []string{"db.password", "tokens[1]", `["a.b"]`}, crypter)
+	assert.NoError(t, err)
+	assert.True(t, v.Secure())
+	assert.True(t, v.Object())
+
+	b, err := yaml.Marshal(v)
+	assert.NoError(t, err)
+	assert.Equal(t, `a.b:
+  secure: enc-dotted
+db:
+  host: localhost
+  password:
+    secure: enc-hunter2
+  port: 5432
+tokens:
+- public
+- secure: enc-private
+unrelated: true
+`, string(b))
+
+	paths, err := v.SecretPaths()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{`["a.b"]`, "db.password", "tokens[1]"}, paths)
+
+	// Every reported path can be used to look up its secret.
+	k := MustMakeKey("my", "config")
+	m := Map{k: v}
+	for _, p := range paths {
+		leaf, ok, err := m.GetPath(k, p)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.True(t, leaf.Secure(), p)
+	}
+
+	plain, err := NewObjectValueWithSecrets([]interface{}{"a", "b"}, nil, crypter)
+	assert.NoError(t, err)
+	assert.Equal(t, NewObjectValue(`["a","b"]`), plain)
+	paths, err = plain.SecretPaths()
+	assert.NoError(t, err)
+	assert.Empty(t, paths)
+
+	_, err = NewObjectValueWithSecrets(map[string]interface{}{"db": map[string]interface{}{}}, []string{"db"}, crypter)
+	assert.Error(t, err)
+	_, err = NewObjectValueWithSecrets(map[string]interface{}{}, []string{"missing"}, crypter)
+	assert.Error(t, err)
+	_, err = NewObjectValueWithSecrets("scalar", nil, crypter)
+	assert.Error(t, err)
+}
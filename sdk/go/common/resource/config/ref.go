@@ -0,0 +1,193 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// RefResolver reads values from an external store, such as a parameter store or a secrets vault.
+type RefResolver interface {
+	// ResolveRef returns the value that uri refers to.
+	ResolveRef(ctx context.Context, uri *url.URL) (string, error)
+}
+
+// RefResolvers is a registry of RefResolvers, keyed by the URI scheme that each resolves (e.g. `aws-ssm`).
+type RefResolvers map[string]RefResolver
+
+// Register registers resolver for URIs with the given scheme, replacing any resolver already registered for it.
+func (r RefResolvers) Register(scheme string, resolver RefResolver) {
+	r[scheme] = resolver
+}
+
+// NewExternalRefValue returns a value that is held in an external store at uri, e.g. `aws-ssm:///prod/db-password`.
+// In a stack file, the value is written as a map with a single `$ref` property. References are resolved by
+// Map.ResolveRefs when the configuration is loaded, and the resolved values are always secrets.
+func NewExternalRefValue(uri string) Value {
+	return Value{secure: true, ref: uri}
+}
+
+// ExternalRef returns the URI of the external store that holds the value, if the value is an external reference.
+func (c Value) ExternalRef() (string, bool) {
+	return c.ref, c.isExternal()
+}
+
+// isExternal returns true if the value is a reference to a value held in an external store.
+func (c Value) isExternal() bool {
+	return c.ref != "" && c.value == ""
+}
+
+// HasExternalRef returns true if the map contains a reference to a value held in an external store.
+func (m Map) HasExternalRef() bool {
+	for _, v := range m {
+		if v.isExternal() {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveRefs returns a copy of m in which each reference to a value held in an external store is replaced by a
+// secure value, using the resolver registered for the scheme of the reference's URI. Resolved values are never
+// encrypted: instead, the returned Decrypter serves them, and defers to decrypter for every other secure value.
+func (m Map) ResolveRefs(ctx context.Context, resolvers RefResolvers, decrypter Decrypter) (Map, Decrypter, error) {
+	result := make(Map, len(m))
+	resolved := make(cachedDecrypter)
+	for k, v := range m {
+		if !v.isExternal() {
+			result[k] = v
+			continue
+		}
+
+		uri, err := url.Parse(v.ref)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "config key %v: invalid reference %q", k, v.ref)
+		}
+		resolver, ok := resolvers[uri.Scheme]
+		if !ok {
+			return nil, nil, errors.Errorf("config key %v: no resolver for references of the form %s:", k, uri.Scheme)
+		}
+		pt, err := resolver.ResolveRef(ctx, uri)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "config key %v: resolving %s", k, v.ref)
+		}
+
+		// The reference itself stands in for the value's ciphertext.
+		resolved[v.ref] = pt
+		result[k] = NewSecureValue(v.ref)
+	}
+	if len(resolved) == 0 {
+		return result, decrypter, nil
+	}
+	return result, &refDecrypter{resolved: resolved, decrypter: decrypter}, nil
+}
+
+// refDecrypter decrypts the stand-in ciphertexts of resolved references, and defers to another decrypter for any
+// other ciphertext.
+type refDecrypter struct {
+	resolved  cachedDecrypter
+	decrypter Decrypter
+}
+
+func (r *refDecrypter) DecryptValue(ciphertext string) (string, error) {
+	if pt, ok := r.resolved[ciphertext]; ok {
+		return pt, nil
+	}
+	return r.decrypter.DecryptValue(ciphertext)
+}
+
+// BulkDecrypt implements BulkDecrypter, deferring to the underlying decrypter for any ciphertexts that are not
+// resolved references.
+func (r *refDecrypter) BulkDecrypt(ctx context.Context, ciphertexts []string) (map[string]string, error) {
+	result := make(map[string]string, len(ciphertexts))
+	var rest []string
+	for _, ct := range ciphertexts {
+		if pt, ok := r.resolved[ct]; ok {
+			result[ct] = pt
+		} else {
+			rest = append(rest, ct)
+		}
+	}
+	if len(rest) == 0 {
+		return result, nil
+	}
+
+	if bulk, ok := r.decrypter.(BulkDecrypter); ok {
+		plaintexts, err := bulk.BulkDecrypt(ctx, rest)
+		if err != nil {
+			return nil, err
+		}
+		for ct, pt := range plaintexts {
+			result[ct] = pt
+		}
+		return result, nil
+	}
+	for _, ct := range rest {
+		pt, err := r.decrypter.DecryptValue(ct)
+		if err != nil {
+			return nil, err
+		}
+		result[ct] = pt
+	}
+	return result, nil
+}
+
+// NewFileRefResolver returns a RefResolver for `file:` URIs, which refer to the content of a file. Relative paths
+// (e.g. `file:secrets/token`) are relative to dir. A single trailing newline is removed from the file's content.
+func NewFileRefResolver(dir string) RefResolver {
+	return fileRefResolver{dir: dir}
+}
+
+type fileRefResolver struct {
+	dir string
+}
+
+func (r fileRefResolver) ResolveRef(ctx context.Context, uri *url.URL) (string, error) {
+	path := uri.Path
+	if uri.Opaque != "" {
+		path = uri.Opaque
+	}
+	if path == "" {
+		return "", errors.New("a file reference must include a path")
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(r.dir, path)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(b), "\n"), nil
+}
+
+// externalRefKey is the reserved property that holds the URI of a reference to an external store in a stack file.
+const externalRefKey = "$ref"
+
+// isExternalRef returns true if v is the stack file representation of a reference to an external store, along with
+// the reference's URI.
+func isExternalRef(v interface{}) (bool, string) {
+	if m, isMap := v.(map[string]interface{}); isMap && len(m) == 1 {
+		if ref, ok := m[externalRefKey].(string); ok && ref != "" {
+			return true, ref
+		}
+	}
+	return false, ""
+}
@@ -0,0 +1,125 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	yaml "gopkg.in/yaml.v2"
+)
+
+type mapRefResolver map[string]string
+
+func (r mapRefResolver) ResolveRef(ctx context.Context, uri *url.URL) (string, error) {
+	return r[uri.Path], nil
+}
+
+func TestExternalRefs(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config-refs")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "token"), []byte("file-token\n"), 0600))
+
+	password, token := MustMakeKey("my", "password"), MustMakeKey("my", "token")
+	local, name := MustMakeKey("my", "local"), MustMakeKey("my", "name")
+
+	var m Map
+	assert.NoError(t, yaml.Unmarshal([]byte(`
+my:password:
+  $ref: aws-ssm:///prod/db-password
+my:token:
+  $ref: file:token
+my:local:
+  secure: enc-local
+my:name: web
+`), &m))
+	assert.True(t, m.HasExternalRef())
+	assert.False(t, m.HasBlobRef())
+	assert.True(t, m[password].Secure())
+	uri, ok := m[password].ExternalRef()
+	assert.True(t, ok)
+	assert.Equal(t, "aws-ssm:///prod/db-password", uri)
+
+	// References are written back unchanged, and survive copies.
+	b, err := yaml.Marshal(Map{password: m[password]})
+	assert.NoError(t, err)
+	assert.Equal(t, "my:password:\n  $ref: aws-ssm:///prod/db-password\n", string(b))
+	copied, err := m.Copy(newPrefixCrypter("enc-"), newPrefixCrypter("new-"))
+	assert.NoError(t, err)
+	assert.Equal(t, m[password], copied[password])
+
+	// Unresolved references can be blinded, but not read.
+	blinded, err := m[password].Value(NewBlindingDecrypter())
+	assert.NoError(t, err)
+	assert.Equal(t, "[secret]", blinded)
+	_, err = m[password].Value(newPrefixCrypter("enc-"))
+	assert.Error(t, err)
+
+	resolvers := RefResolvers{}
+	resolvers.Register("aws-ssm", mapRefResolver{"/prod/db-password": "hunter2"})
+	resolvers.Register("file", NewFileRefResolver(dir))
+
+	resolved, dec, err := m.ResolveRefs(context.Background(), resolvers, newPrefixCrypter("enc-"))
+	assert.NoError(t, err)
+	assert.False(t, resolved.HasExternalRef())
+	assert.True(t, resolved[password].Secure())
+
+	plaintexts, err := resolved.Decrypt(dec)
+	assert.NoError(t, err)
+	assert.Equal(t, map[Key]string{
+		password: "hunter2",
+		token:    "file-token",
+		local:    "local",
+		name:     "web",
+	}, plaintexts)
+
+	all, err := resolved.DecryptAll(context.Background(), dec)
+	assert.NoError(t, err)
+	assert.Equal(t, plaintexts, all)
+
+	_, _, err = m.ResolveRefs(context.Background(), RefResolvers{}, NopDecrypter)
+	assert.Error(t, err)
+}
+
+func TestPlainRefObjects(t *testing.T) {
+	repo := MustMakeKey("my", "repo")
+
+	// Objects with an ordinary `ref` property are not references to external stores.
+	var m Map
+	assert.NoError(t, yaml.Unmarshal([]byte("my:repo:\n  ref: main\n"), &m))
+	assert.False(t, m.HasExternalRef())
+	assert.True(t, m[repo].Object())
+	v, err := m[repo].Value(NopDecrypter)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"ref":"main"}`, v)
+
+	// The same holds for values set by path, once they have been saved and loaded again.
+	m = Map{}
+	assert.NoError(t, m.Set(MustMakeKey("my", "repo.ref"), NewValue("main"), true))
+	b, err := yaml.Marshal(m)
+	assert.NoError(t, err)
+	var loaded Map
+	assert.NoError(t, yaml.Unmarshal(b, &loaded))
+	assert.False(t, loaded.HasExternalRef())
+	v, err = loaded[repo].Value(NopDecrypter)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"ref":"main"}`, v)
+}
@@ -0,0 +1,581 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/texttheater/golang-levenshtein/levenshtein"
+
+	"github.com/pulumi/pulumi/sdk/v2/go/common/diag"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/tokens"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
+)
+
+// KeySchema declares the type and constraints of a single configuration key.
+type KeySchema struct {
+	// Description is an optional human-readable description of the key.
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	// Type is the type of the key's value. If omitted, any string value is accepted.
+	Type Type `json:"type,omitempty" yaml:"type,omitempty"`
+	// Default is an optional default value for the key, used when the stack does not set it.
+	Default string `json:"default,omitempty" yaml:"default,omitempty"`
+	// AllowedValues, if non-empty, restricts the key to the listed values.
+	AllowedValues []string `json:"allowedValues,omitempty" yaml:"allowedValues,omitempty"`
+	// Secret may be set to true to indicate that the value must be encrypted.
+	Secret bool `json:"secret,omitempty" yaml:"secret,omitempty"`
+	// Required may be set to true to indicate that every stack must set the key.
+	Required bool `json:"required,omitempty" yaml:"required,omitempty"`
+	// Deprecated, if non-empty, marks the key as deprecated and explains why.
+	Deprecated string `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+	// ReplacedBy optionally names the key that replaces a deprecated key.
+	ReplacedBy string `json:"replacedBy,omitempty" yaml:"replacedBy,omitempty"`
+	// Pattern, if non-empty, is a regular expression that the whole of a string value must match.
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	// Minimum, if non-nil, is the smallest value allowed for a numeric key.
+	Minimum *float64 `json:"minimum,omitempty" yaml:"minimum,omitempty"`
+	// Maximum, if non-nil, is the largest value allowed for a numeric key.
+	Maximum *float64 `json:"maximum,omitempty" yaml:"maximum,omitempty"`
+	// Aliases optionally lists former names of the key. Reads of an alias are forwarded to the key, and
+	// MigrateAliases renames aliases in existing configuration.
+	Aliases []string `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	// Validators are additional checks registered programmatically with Schema.AddValidator.
+	Validators []Validator `json:"-" yaml:"-"`
+}
+
+// Validator checks the plaintext of a scalar configuration value, returning an error that describes why the value is
+// invalid, or nil if it is valid.
+type Validator func(value string) error
+
+// Schema is the set of configuration keys declared by a project.
+type Schema map[Key]KeySchema
+
+// ParseSchema parses a schema whose keys are of the form `<namespace>:<name>`, or just `<name>` for keys in the given
+// default namespace (usually the project's name).
+//
+// The ReplacedBy and Aliases fields of each returned KeySchema are normalized to fully qualified key names.
+func ParseSchema(namespace string, raw map[string]KeySchema) (Schema, error) {
+	s := make(Schema, len(raw))
+	aliases := make(map[Key]Key)
+	for name, ks := range raw {
+		k, err := parseSchemaKey(namespace, name)
+		if err != nil {
+			return nil, err
+		}
+		normalized := make([]string, len(ks.Aliases))
+		for i, alias := range ks.Aliases {
+			a, err := parseSchemaKey(namespace, alias)
+			if err != nil {
+				return nil, errors.Wrapf(err, "config key %v: invalid alias", k)
+			}
+			if other, has := aliases[a]; has {
+				return nil, errors.Errorf("config keys %v and %v may not both have the alias %v", other, k, a)
+			}
+			aliases[a] = k
+			normalized[i] = a.String()
+		}
+		if len(normalized) > 0 {
+			ks.Aliases = normalized
+		}
+		if ks.ReplacedBy != "" {
+			if ks.Deprecated == "" {
+				return nil, errors.Errorf("config key %v: only deprecated keys may be replaced", k)
+			}
+			r, err := parseSchemaKey(namespace, ks.ReplacedBy)
+			if err != nil {
+				return nil, errors.Wrapf(err, "config key %v: invalid replacement", k)
+			}
+			ks.ReplacedBy = r.String()
+		}
+		if len(ks.AllowedValues) > 0 && (ks.Type == TypeList || ks.Type == TypeObject) {
+			return nil, errors.Errorf("config key %v: allowed values may not be specified for a %v", k, ks.Type)
+		}
+		if ks.Pattern != "" {
+			if ks.Type != TypeString {
+				return nil, errors.Errorf("config key %v: a pattern may not be specified for a %v", k, ks.Type)
+			}
+			if _, err := compilePattern(ks.Pattern); err != nil {
+				return nil, errors.Wrapf(err, "config key %v: invalid pattern", k)
+			}
+		}
+		if ks.Minimum != nil || ks.Maximum != nil {
+			if ks.Type != TypeInt && ks.Type != TypeFloat {
+				return nil, errors.Errorf("config key %v: a range may not be specified for a %v", k, ks.Type)
+			}
+			if ks.Minimum != nil && ks.Maximum != nil && *ks.Minimum > *ks.Maximum {
+				return nil, errors.Errorf("config key %v: minimum is greater than maximum", k)
+			}
+		}
+		if ks.Required && ks.Default != "" {
+			return nil, errors.Errorf("config key %v: a required key may not have a default value", k)
+		}
+		if ks.Default != "" {
+			if _, err := NewTypedValue(ks.Default, ks.Type); err != nil {
+				return nil, errors.Wrapf(err, "config key %v: invalid default value", k)
+			}
+		}
+		s[k] = ks
+	}
+	for a, k := range aliases {
+		if _, declared := s[a]; declared {
+			return nil, errors.Errorf("config key %v: the alias %v is declared as a key in its own right", k, a)
+		}
+	}
+	return s, nil
+}
+
+// compilePattern compiles a schema pattern so that it must match the whole of a value.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + pattern + ")$")
+}
+
+// AddValidator registers a validator to run on the values of key k, in addition to the checks declared by its schema.
+// If k is not yet declared, it is added to the schema as a string key.
+func (s Schema) AddValidator(k Key, v Validator) {
+	ks := s[k]
+	ks.Validators = append(ks.Validators, v)
+	s[k] = ks
+}
+
+// Set sets the value of k in m, as Map.Set does, but only if the resulting value of k is valid according to the
+// schema. If it is not, m is left unchanged and a *ValidationError is returned. decrypter is used as in Validate.
+func (s Schema) Set(m Map, k Key, v Value, path bool, decrypter Decrypter) error {
+	// If k is a path, the value that changes is the value of the key named by its first segment.
+	root := k
+	if path {
+		_, configKey, err := parseKeyPath(k)
+		if err != nil {
+			return err
+		}
+		root = configKey
+	}
+
+	// Apply the change to a copy of the key's current value, so that paths are validated within the whole value.
+	single := Map{}
+	if old, ok := m[root]; ok {
+		single[root] = old
+	}
+	if err := single.Set(k, v, path); err != nil {
+		return err
+	}
+	if err := NewValidationError(s.ValidateValue(root, single[root], decrypter)); err != nil {
+		return err
+	}
+	m[root] = single[root]
+	return nil
+}
+
+func parseSchemaKey(namespace, name string) (Key, error) {
+	if !strings.Contains(name, tokens.TokenDelimiter) {
+		name = namespace + tokens.TokenDelimiter + name
+	}
+	return ParseKey(name)
+}
+
+// Diagnostic describes a problem with the value of a configuration key.
+type Diagnostic struct {
+	Key      Key
+	Severity diag.Severity
+	Message  string
+	// File and Line optionally give the position of the key in the file it was loaded from. Line is 1-based, and is
+	// zero if unknown.
+	File string
+	Line int
+}
+
+func (d Diagnostic) String() string {
+	msg := fmt.Sprintf("%v: %s", d.Key, d.Message)
+	switch {
+	case d.File != "" && d.Line > 0:
+		return fmt.Sprintf("%s:%d: %s", d.File, d.Line, msg)
+	case d.File != "":
+		return fmt.Sprintf("%s: %s", d.File, msg)
+	default:
+		return msg
+	}
+}
+
+// LocateDiagnostics returns a copy of diags in which each diagnostic is positioned at the line that sets its key in
+// content, the YAML text of the stack file at path file.
+func LocateDiagnostics(diags []Diagnostic, file string, content []byte) []Diagnostic {
+	lines := strings.Split(string(content), "\n")
+	located := make([]Diagnostic, len(diags))
+	for i, d := range diags {
+		d.File = file
+		key := d.Key.String()
+		for n, line := range lines {
+			// The key may be quoted, e.g. `"proj:name": value`.
+			line = strings.TrimLeft(strings.TrimSpace(line), `"'`)
+			if rest := strings.TrimPrefix(line, key); rest != line && strings.HasPrefix(strings.TrimLeft(rest, `"'`), ":") {
+				d.Line = n + 1
+				break
+			}
+		}
+		located[i] = d
+	}
+	return located
+}
+
+// ValidationError is an error that carries the diagnostics produced while validating configuration.
+type ValidationError struct {
+	Diagnostics []Diagnostic
+}
+
+// NewValidationError returns a *ValidationError for the error diagnostics in diags, or nil if there are none.
+func NewValidationError(diags []Diagnostic) error {
+	var errs []Diagnostic
+	for _, d := range diags {
+		if d.Severity == diag.Error {
+			errs = append(errs, d)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Diagnostics: errs}
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		msgs[i] = d.String()
+	}
+	return "invalid configuration:\n  " + strings.Join(msgs, "\n  ")
+}
+
+// Defaults returns a layer holding the default values declared by the schema.
+func (s Schema) Defaults() Layer {
+	m := make(Map)
+	for k, ks := range s {
+		if ks.Default == "" {
+			continue
+		}
+		v, err := NewTypedValue(ks.Default, ks.Type)
+		if err != nil {
+			// ParseSchema rejects invalid defaults, so this is only reachable for schemas built by hand.
+			continue
+		}
+		m[k] = v
+	}
+	return Layer{Name: ProjectDefaultsLayer, Config: m}
+}
+
+// Validate checks every value in m against the schema, returning diagnostics sorted by key. Undeclared keys are
+// ignored. decrypter is used to check the contents of secure values; if it is nil, only their secret-ness is checked.
+func (s Schema) Validate(m Map, decrypter Decrypter) []Diagnostic {
+	keys := make(KeyArray, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Sort(keys)
+
+	var diags []Diagnostic
+	for _, k := range keys {
+		diags = append(diags, s.ValidateValue(k, m[k], decrypter)...)
+	}
+	return diags
+}
+
+// Missing reports an error for each required key that m does not set, either directly or through an alias, returning
+// diagnostics sorted by key. Unlike reading each key in turn, this reports every missing key at once.
+func (s Schema) Missing(m Map) []Diagnostic {
+	var diags []Diagnostic
+	for _, k := range s.sortedKeys() {
+		ks := s[k]
+		if !ks.Required || hasKeyOrAlias(m, k, ks) {
+			continue
+		}
+
+		msg := "required config key is not set"
+		if ks.Description != "" {
+			msg += fmt.Sprintf(" (%s)", ks.Description)
+		}
+		diags = append(diags, Diagnostic{Key: k, Severity: diag.Error, Message: msg})
+	}
+	return diags
+}
+
+func hasKeyOrAlias(m Map, k Key, ks KeySchema) bool {
+	if _, ok := m[k]; ok {
+		return true
+	}
+	for _, alias := range ks.Aliases {
+		a, err := ParseKey(alias)
+		contract.AssertNoError(err)
+		if _, ok := m[a]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateKeys reports an error for each key in m that the schema declares neither as a key nor as an alias,
+// returning diagnostics sorted by key. Where a declared key is a likely match for an undeclared one, e.g. because of a
+// typo, the error suggests it.
+func (s Schema) ValidateKeys(m Map) []Diagnostic {
+	keys := make(KeyArray, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Sort(keys)
+
+	var diags []Diagnostic
+	for _, k := range keys {
+		if _, ok := s[k]; ok {
+			continue
+		}
+		if _, ok := s.AliasOf(k); ok {
+			continue
+		}
+
+		msg := "config key is not declared by the project's config schema"
+		if suggestion, ok := s.closestKey(k); ok {
+			msg += fmt.Sprintf("; did you mean %v?", suggestion)
+		}
+		diags = append(diags, Diagnostic{Key: k, Severity: diag.Error, Message: msg})
+	}
+	return diags
+}
+
+// closestKey returns the declared key whose name is closest to k's, if there is one within a small edit distance.
+func (s Schema) closestKey(k Key) (Key, bool) {
+	const maxDistance = 2
+
+	var closest Key
+	best := maxDistance + 1
+	for _, candidate := range s.sortedKeys() {
+		distance := levenshtein.DistanceForStrings([]rune(k.String()), []rune(candidate.String()),
+			levenshtein.DefaultOptions)
+		if distance < best {
+			closest, best = candidate, distance
+		}
+	}
+	return closest, best <= maxDistance
+}
+
+// ValidateValue checks a single value against the schema for key k. If k is not declared, no diagnostics are
+// returned. decrypter is used as in Validate.
+func (s Schema) ValidateValue(k Key, v Value, decrypter Decrypter) []Diagnostic {
+	ks, ok := s[k]
+	if !ok {
+		return nil
+	}
+
+	var diags []Diagnostic
+	report := func(format string, args ...interface{}) {
+		diags = append(diags, Diagnostic{Key: k, Severity: diag.Error, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if d, ok := s.Deprecation(k); ok {
+		diags = append(diags, d)
+	}
+
+	if ks.Secret && !v.Secure() {
+		report("value must be secret")
+	}
+
+	switch ks.Type {
+	case TypeList, TypeObject:
+		if v.Type() != ks.Type {
+			report("expected a value of type %v", ks.Type)
+		}
+		return diags
+	}
+	if v.Object() {
+		report("expected a value of type %v, not a %v", ks.Type, v.Type())
+		return diags
+	}
+	if v.Secure() && decrypter == nil {
+		return diags
+	}
+
+	raw, err := v.Value(decrypter)
+	if err != nil {
+		report("could not decrypt value: %v", err)
+		return diags
+	}
+	if _, err := NewTypedValue(raw, ks.Type); err != nil {
+		report("expected a value of type %v", ks.Type)
+		return diags
+	}
+	if ks.Pattern != "" {
+		if re, err := compilePattern(ks.Pattern); err != nil {
+			report("invalid pattern: %v", err)
+		} else if !re.MatchString(raw) {
+			report("value must match the pattern %s", ks.Pattern)
+		}
+	}
+	if ks.Minimum != nil || ks.Maximum != nil {
+		if n, err := strconv.ParseFloat(raw, 64); err != nil {
+			report("expected a number")
+		} else if ks.Minimum != nil && n < *ks.Minimum {
+			report("value must be at least %v", *ks.Minimum)
+		} else if ks.Maximum != nil && n > *ks.Maximum {
+			report("value must be at most %v", *ks.Maximum)
+		}
+	}
+	for _, validate := range ks.Validators {
+		if err := validate(raw); err != nil {
+			report("%v", err)
+		}
+	}
+	if len(ks.AllowedValues) > 0 {
+		allowed := false
+		for _, a := range ks.AllowedValues {
+			if a == raw {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			report("value must be one of %s", strings.Join(ks.AllowedValues, ", "))
+		}
+	}
+	return diags
+}
+
+// Deprecation returns a warning diagnostic if key k is declared as deprecated.
+func (s Schema) Deprecation(k Key) (Diagnostic, bool) {
+	ks, ok := s[k]
+	if !ok || ks.Deprecated == "" {
+		return Diagnostic{}, false
+	}
+	msg := "config key is deprecated: " + ks.Deprecated
+	if ks.ReplacedBy != "" {
+		msg += fmt.Sprintf("; use %v instead", ks.ReplacedBy)
+	}
+	return Diagnostic{Key: k, Severity: diag.Warning, Message: msg}, true
+}
+
+// MigrateDeprecated rewrites m so that the values of deprecated keys are moved to their replacements, returning the
+// keys that were migrated in sorted order. It is an error for both a deprecated key and its replacement to be set.
+func (s Schema) MigrateDeprecated(m Map) ([]Key, error) {
+	var migrated KeyArray
+	targets := make(map[Key]Key)
+	for k := range m {
+		ks, ok := s[k]
+		if !ok || ks.ReplacedBy == "" {
+			continue
+		}
+		r, err := ParseKey(ks.ReplacedBy)
+		if err != nil {
+			return nil, err
+		}
+		if _, has := m[r]; has {
+			return nil, errors.Errorf("cannot migrate deprecated config key %v: %v is already set", k, r)
+		}
+		if other, has := targets[r]; has {
+			return nil, errors.Errorf("cannot migrate deprecated config keys %v and %v: both are replaced by %v",
+				other, k, r)
+		}
+		targets[r] = k
+		migrated = append(migrated, k)
+	}
+	sort.Sort(migrated)
+
+	for _, k := range migrated {
+		r, err := ParseKey(s[k].ReplacedBy)
+		contract.AssertNoError(err)
+		m[r] = m[k]
+		delete(m, k)
+	}
+	return migrated, nil
+}
+
+// AliasOf returns the key that k is an alias of, if any.
+func (s Schema) AliasOf(k Key) (Key, bool) {
+	for target, ks := range s {
+		for _, alias := range ks.Aliases {
+			if alias == k.String() {
+				return target, true
+			}
+		}
+	}
+	return Key{}, false
+}
+
+// ForwardAliases returns a copy of m in which each alias and the key that it names have the same value, so that reads
+// of either see the key's value. A value that is set only under an alias is forwarded to the key with a warning that
+// the configuration should be migrated; if both are set, the key's value wins.
+func (s Schema) ForwardAliases(m Map) (Map, []Diagnostic) {
+	result := m.Clone()
+	if result == nil {
+		result = Map{}
+	}
+
+	var diags []Diagnostic
+	for _, k := range s.sortedKeys() {
+		for _, alias := range s[k].Aliases {
+			a, err := ParseKey(alias)
+			contract.AssertNoError(err)
+
+			v, hasAlias := m[a]
+			if _, hasKey := result[k]; !hasKey && hasAlias {
+				result[k] = v
+				diags = append(diags, Diagnostic{Key: a, Severity: diag.Warning,
+					Message: fmt.Sprintf("config key has been renamed to %v; run `pulumi config migrate` to rename it", k)})
+			}
+			if kv, ok := result[k]; ok {
+				result[a] = kv
+			}
+		}
+	}
+	return result, diags
+}
+
+// MigrateAliases rewrites m so that values set under an alias are moved to the key that it names, returning the
+// aliases that were migrated in sorted order. It is an error for both an alias and its key to be set.
+func (s Schema) MigrateAliases(m Map) ([]Key, error) {
+	renames := make(map[Key]Key)
+	var migrated KeyArray
+	for _, k := range s.sortedKeys() {
+		for _, alias := range s[k].Aliases {
+			a, err := ParseKey(alias)
+			contract.AssertNoError(err)
+			if _, has := m[a]; !has {
+				continue
+			}
+			if _, has := m[k]; has {
+				return nil, errors.Errorf("cannot migrate config key %v: %v is already set", a, k)
+			}
+			if other, has := renames[k]; has {
+				return nil, errors.Errorf("cannot migrate config keys %v and %v: both are aliases of %v", other, a, k)
+			}
+			renames[k] = a
+			migrated = append(migrated, a)
+		}
+	}
+	sort.Sort(migrated)
+
+	for k, a := range renames {
+		m[k] = m[a]
+		delete(m, a)
+	}
+	return migrated, nil
+}
+
+func (s Schema) sortedKeys() KeyArray {
+	keys := make(KeyArray, 0, len(s))
+	for k := range s {
+		keys = append(keys, k)
+	}
+	sort.Sort(keys)
+	return keys
+}
@@ -0,0 +1,339 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/pulumi/pulumi/sdk/v2/go/common/diag"
+)
+
+func TestParseSchema(t *testing.T) {
+	var raw map[string]KeySchema
+	err := yaml.Unmarshal([]byte(`
+replicas:
+  type: int
+  default: "3"
+  description: The number of replicas.
+aws:region:
+  allowedValues: [us-east-1, us-west-2]
+password:
+  secret: true
+`), &raw)
+	assert.NoError(t, err)
+
+	s, err := ParseSchema("my", raw)
+	assert.NoError(t, err)
+	assert.Equal(t, Schema{
+		MustMakeKey("my", "replicas"): {Type: TypeInt, Default: "3", Description: "The number of replicas."},
+		MustMakeKey("aws", "region"):  {AllowedValues: []string{"us-east-1", "us-west-2"}},
+		MustMakeKey("my", "password"): {Secret: true},
+	}, s)
+
+	assert.Equal(t, Layer{
+		Name:   ProjectDefaultsLayer,
+		Config: Map{MustMakeKey("my", "replicas"): NewIntValue(3)},
+	}, s.Defaults())
+
+	_, err = ParseSchema("my", map[string]KeySchema{"count": {Type: TypeInt, Default: "many"}})
+	assert.Error(t, err)
+	_, err = ParseSchema("my", map[string]KeySchema{"tags": {Type: TypeList, AllowedValues: []string{"a"}}})
+	assert.Error(t, err)
+
+	err = yaml.Unmarshal([]byte("count:\n  type: integer\n"), &raw)
+	assert.Error(t, err)
+}
+
+func TestValidate(t *testing.T) {
+	s := Schema{
+		MustMakeKey("my", "replicas"): {Type: TypeInt},
+		MustMakeKey("my", "tags"):     {Type: TypeList},
+		MustMakeKey("my", "size"):     {AllowedValues: []string{"small", "large"}},
+		MustMakeKey("my", "password"): {Secret: true, Type: TypeInt},
+	}
+
+	diags := s.Validate(Map{
+		MustMakeKey("my", "replicas"):   NewValue("three"),
+		MustMakeKey("my", "tags"):       NewObjectValue(`{"a":"b"}`),
+		MustMakeKey("my", "size"):       NewValue("medium"),
+		MustMakeKey("my", "password"):   NewValue("1234"),
+		MustMakeKey("my", "undeclared"): NewValue("anything"),
+	}, nil)
+	assert.Equal(t, []Diagnostic{
+		{Key: MustMakeKey("my", "password"), Severity: diag.Error, Message: "value must be secret"},
+		{Key: MustMakeKey("my", "replicas"), Severity: diag.Error, Message: "expected a value of type int"},
+		{Key: MustMakeKey("my", "size"), Severity: diag.Error, Message: "value must be one of small, large"},
+		{Key: MustMakeKey("my", "tags"), Severity: diag.Error, Message: "expected a value of type list"},
+	}, diags)
+
+	diags = s.Validate(Map{
+		MustMakeKey("my", "replicas"): NewIntValue(3),
+		MustMakeKey("my", "tags"):     NewObjectValue(`["a"]`),
+		MustMakeKey("my", "size"):     NewValue("small"),
+		MustMakeKey("my", "password"): NewSecureValue("1234"),
+	}, passThroughDecrypter{})
+	assert.Empty(t, diags)
+	assert.NoError(t, NewValidationError(diags))
+
+	// Secure values are only checked when a decrypter is supplied.
+	assert.Empty(t, s.ValidateValue(MustMakeKey("my", "password"), NewSecureValue("abc"), nil))
+	diags = s.ValidateValue(MustMakeKey("my", "password"), NewSecureValue("abc"), passThroughDecrypter{})
+	assert.Len(t, diags, 1)
+	assert.EqualError(t, NewValidationError(diags), "invalid configuration:\n  my:password: expected a value of type int")
+}
+
+func TestDeprecatedKeys(t *testing.T) {
+	s, err := ParseSchema("my", map[string]KeySchema{
+		"oldName": {Deprecated: "names are now global", ReplacedBy: "global:name"},
+		"legacy":  {Deprecated: "no longer used"},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "global:name", s[MustMakeKey("my", "oldName")].ReplacedBy)
+
+	d, ok := s.Deprecation(MustMakeKey("my", "oldName"))
+	assert.True(t, ok)
+	assert.Equal(t, Diagnostic{
+		Key:      MustMakeKey("my", "oldName"),
+		Severity: diag.Warning,
+		Message:  "config key is deprecated: names are now global; use global:name instead",
+	}, d)
+
+	// Deprecation warnings are reported by validation, but are not errors.
+	m := Map{
+		MustMakeKey("my", "oldName"): NewValue("example"),
+		MustMakeKey("my", "legacy"):  NewValue("true"),
+	}
+	diags := s.Validate(m, nil)
+	assert.Len(t, diags, 2)
+	assert.NoError(t, NewValidationError(diags))
+
+	migrated, err := s.MigrateDeprecated(m)
+	assert.NoError(t, err)
+	assert.Equal(t, []Key{MustMakeKey("my", "oldName")}, migrated)
+	assert.Equal(t, Map{
+		MustMakeKey("global", "name"): NewValue("example"),
+		MustMakeKey("my", "legacy"):   NewValue("true"),
+	}, m)
+
+	// Migration fails rather than overwriting an existing value.
+	m[MustMakeKey("my", "oldName")] = NewValue("other")
+	_, err = s.MigrateDeprecated(m)
+	assert.Error(t, err)
+
+	_, err = ParseSchema("my", map[string]KeySchema{"a": {ReplacedBy: "b"}})
+	assert.Error(t, err)
+}
+
+func TestValidationHooks(t *testing.T) {
+	var raw map[string]KeySchema
+	err := yaml.Unmarshal([]byte(`
+name:
+  pattern: "[a-z][a-z0-9-]*"
+port:
+  type: int
+  minimum: 1
+  maximum: 65535
+ratio:
+  type: float
+  maximum: 1
+size:
+  allowedValues: [small, large]
+`), &raw)
+	assert.NoError(t, err)
+	s, err := ParseSchema("my", raw)
+	assert.NoError(t, err)
+
+	name, port, ratio, size := MustMakeKey("my", "name"), MustMakeKey("my", "port"), MustMakeKey("my", "ratio"),
+		MustMakeKey("my", "size")
+	s.AddValidator(name, func(v string) error {
+		if strings.HasSuffix(v, "-") {
+			return errors.New("value may not end with a hyphen")
+		}
+		return nil
+	})
+
+	assert.Empty(t, s.Validate(Map{
+		name:  NewValue("web-1"),
+		port:  NewValue("8080"),
+		ratio: NewValue("0.5"),
+		size:  NewValue("small"),
+	}, nil))
+
+	diags := s.Validate(Map{
+		name:  NewValue("Web"),
+		port:  NewValue("0"),
+		ratio: NewValue("1.5"),
+		size:  NewValue("medium"),
+	}, nil)
+	assert.Equal(t, []string{
+		"my:name: value must match the pattern [a-z][a-z0-9-]*",
+		"my:port: value must be at least 1",
+		"my:ratio: value must be at most 1",
+		"my:size: value must be one of small, large",
+	}, diagnosticStrings(diags))
+	assert.Equal(t, []string{"my:name: value may not end with a hyphen"},
+		diagnosticStrings(s.ValidateValue(name, NewValue("web-"), nil)))
+
+	// Set only applies valid values.
+	m := Map{port: NewValue("80")}
+	assert.NoError(t, s.Set(m, port, NewValue("443"), false, nil))
+	assert.Equal(t, NewValue("443"), m[port])
+	err = s.Set(m, port, NewValue("70000"), false, nil)
+	assert.IsType(t, &ValidationError{}, err)
+	assert.Equal(t, NewValue("443"), m[port])
+
+	// Paths are set within the value of the key named by their first segment.
+	outer := MustMakeKey("my", "outer")
+	m = Map{outer: NewObjectValue(`{"keep":"me","list":["a","b"]}`)}
+	assert.NoError(t, Schema{}.Set(m, MustMakeKey("my", "outer.inner"), NewValue("x"), true, nil))
+	assert.NoError(t, Schema{}.Set(m, MustMakeKey("my", "outer.list[1]"), NewValue("c"), true, nil))
+	assert.Equal(t, Map{outer: NewObjectValue(`{"inner":"x","keep":"me","list":["a","c"]}`)}, m)
+	assert.NoError(t, s.Set(m, MustMakeKey("my", "ports[0]"), NewValue("80"), true, nil))
+	assert.Equal(t, NewObjectValue(`[80]`), m[MustMakeKey("my", "ports")])
+	s[MustMakeKey("my", "servers")] = KeySchema{Type: TypeList}
+	err = s.Set(m, MustMakeKey("my", "servers.name"), NewValue("web"), true, nil)
+	assert.IsType(t, &ValidationError{}, err)
+	assert.NotContains(t, m, MustMakeKey("my", "servers"))
+
+	// Diagnostics can be positioned at the keys in a stack file.
+	located := LocateDiagnostics(diags[:2], "Pulumi.dev.yaml", []byte(`config:
+  my:name: Web
+  "my:port": "0"
+`))
+	assert.Equal(t, []string{
+		"Pulumi.dev.yaml:2: my:name: value must match the pattern [a-z][a-z0-9-]*",
+		"Pulumi.dev.yaml:3: my:port: value must be at least 1",
+	}, diagnosticStrings(located))
+
+	for _, bad := range []map[string]KeySchema{
+		{"count": {Type: TypeInt, Pattern: "[0-9]+"}},
+		{"name": {Pattern: "("}},
+		{"name": {Minimum: &[]float64{1}[0]}},
+		{"count": {Type: TypeInt, Minimum: &[]float64{2}[0], Maximum: &[]float64{1}[0]}},
+	} {
+		_, err = ParseSchema("my", bad)
+		assert.Error(t, err)
+	}
+}
+
+func diagnosticStrings(diags []Diagnostic) []string {
+	var result []string
+	for _, d := range diags {
+		result = append(result, d.String())
+	}
+	return result
+}
+
+func TestKeyAliases(t *testing.T) {
+	s, err := ParseSchema("my", map[string]KeySchema{
+		"dbHost": {Aliases: []string{"databaseHost", "legacy:host"}},
+		"region": {},
+	})
+	assert.NoError(t, err)
+	dbHost, databaseHost, legacyHost := MustMakeKey("my", "dbHost"), MustMakeKey("my", "databaseHost"),
+		MustMakeKey("legacy", "host")
+	assert.Equal(t, []string{"my:databaseHost", "legacy:host"}, s[dbHost].Aliases)
+
+	k, ok := s.AliasOf(legacyHost)
+	assert.True(t, ok)
+	assert.Equal(t, dbHost, k)
+	_, ok = s.AliasOf(dbHost)
+	assert.False(t, ok)
+
+	// A value set under an alias is forwarded to the key, and to its other aliases.
+	m := Map{databaseHost: NewValue("db.internal")}
+	forwarded, diags := s.ForwardAliases(m)
+	assert.Equal(t, Map{
+		dbHost:       NewValue("db.internal"),
+		databaseHost: NewValue("db.internal"),
+		legacyHost:   NewValue("db.internal"),
+	}, forwarded)
+	assert.Len(t, diags, 1)
+	assert.Equal(t, databaseHost, diags[0].Key)
+	assert.Equal(t, diag.Warning, diags[0].Severity)
+	assert.Len(t, m, 1)
+
+	// The key's own value wins.
+	forwarded, diags = s.ForwardAliases(Map{dbHost: NewValue("new"), legacyHost: NewValue("old")})
+	assert.Empty(t, diags)
+	assert.Equal(t, NewValue("new"), forwarded[legacyHost])
+
+	migrated, err := s.MigrateAliases(m)
+	assert.NoError(t, err)
+	assert.Equal(t, []Key{databaseHost}, migrated)
+	assert.Equal(t, Map{dbHost: NewValue("db.internal")}, m)
+
+	_, err = s.MigrateAliases(Map{dbHost: NewValue("a"), legacyHost: NewValue("b")})
+	assert.Error(t, err)
+	_, err = s.MigrateAliases(Map{databaseHost: NewValue("a"), legacyHost: NewValue("b")})
+	assert.Error(t, err)
+
+	_, err = ParseSchema("my", map[string]KeySchema{"a": {Aliases: []string{"old"}}, "b": {Aliases: []string{"old"}}})
+	assert.Error(t, err)
+	_, err = ParseSchema("my", map[string]KeySchema{"a": {Aliases: []string{"b"}}, "b": {}})
+	assert.Error(t, err)
+}
+
+func TestValidateKeys(t *testing.T) {
+	s, err := ParseSchema("my", map[string]KeySchema{
+		"dbHost":     {Aliases: []string{"databaseHost"}},
+		"aws:region": {},
+	})
+	assert.NoError(t, err)
+
+	m := Map{
+		MustMakeKey("my", "dbHost"):       NewValue("db"),
+		MustMakeKey("my", "databaseHost"): NewValue("db"),
+		MustMakeKey("aws", "regoin"):      NewValue("us-west-2"),
+		MustMakeKey("my", "unrelated"):    NewValue("x"),
+	}
+	assert.Equal(t, []string{
+		"aws:regoin: config key is not declared by the project's config schema; did you mean aws:region?",
+		"my:unrelated: config key is not declared by the project's config schema",
+	}, diagnosticStrings(s.ValidateKeys(m)))
+	assert.Empty(t, s.ValidateKeys(Map{MustMakeKey("aws", "region"): NewValue("us-west-2")}))
+}
+
+func TestMissing(t *testing.T) {
+	s, err := ParseSchema("my", map[string]KeySchema{
+		"dbHost":     {Required: true, Aliases: []string{"databaseHost"}},
+		"password":   {Required: true, Secret: true, Description: "the database password"},
+		"aws:region": {Required: true},
+		"optional":   {},
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"aws:region: required config key is not set",
+		"my:dbHost: required config key is not set",
+		"my:password: required config key is not set (the database password)",
+	}, diagnosticStrings(s.Missing(Map{})))
+
+	// A key set only under an alias is not missing.
+	assert.Equal(t, []string{
+		"my:password: required config key is not set (the database password)",
+	}, diagnosticStrings(s.Missing(Map{
+		MustMakeKey("aws", "region"):      NewValue("us-west-2"),
+		MustMakeKey("my", "databaseHost"): NewValue("db"),
+	})))
+
+	_, err = ParseSchema("my", map[string]KeySchema{"count": {Required: true, Default: "1"}})
+	assert.Error(t, err)
+}
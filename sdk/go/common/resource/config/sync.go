@@ -0,0 +1,272 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// SyncMap is a configuration map that is safe for concurrent use by multiple goroutines. It offers the operations of
+// Map, each of which holds a lock for its duration: reads may proceed in parallel, while updates are exclusive.
+//
+// A plain Map is not safe for concurrent use if any goroutine modifies it, so programs that share configuration
+// between goroutines (e.g. Automation API programs that run several operations at once) should use a SyncMap instead.
+//
+// Listeners registered with Subscribe are notified of every change to the map's contents.
+type SyncMap struct {
+	mu        sync.RWMutex
+	m         Map
+	listeners map[int]func(ChangeEvent)
+	nextID    int
+
+	// notifyMu serializes the delivery of change events, so that listeners observe changes in the order in which they
+	// were made.
+	notifyMu sync.Mutex
+}
+
+// ChangeEvent describes a change to the value of a single key in a SyncMap.
+type ChangeEvent struct {
+	// Key is the key whose value changed.
+	Key Key
+	// Old is the key's previous value, or nil if the key was added.
+	Old *Value
+	// New is the key's new value, or nil if the key was removed.
+	New *Value
+	// Secure is true if either value is secure. Listeners that display changes should not display such values.
+	Secure bool
+}
+
+// Subscribe registers listener to be called with an event for each key whose value changes, after the change has been
+// made. Events for a single update are delivered in key order, and updates are delivered in the order in which they
+// were made. Listeners are called without the map's lock held, so they may read the map, but they must not modify it.
+// The returned function unregisters the listener.
+func (s *SyncMap) Subscribe(listener func(ChangeEvent)) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.listeners == nil {
+		s.listeners = make(map[int]func(ChangeEvent))
+	}
+	id := s.nextID
+	s.nextID++
+	s.listeners[id] = listener
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.listeners, id)
+	}
+}
+
+// mutate runs mutate, which modifies s.m, with the lock held, and then notifies any listeners of the changes it made.
+func (s *SyncMap) mutate(mutate func() error) error {
+	s.mu.Lock()
+	if len(s.listeners) == 0 {
+		defer s.mu.Unlock()
+		return mutate()
+	}
+
+	old := s.m.Clone()
+	if err := mutate(); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	events := changeEvents(old, s.m)
+	ids := make([]int, 0, len(s.listeners))
+	for id := range s.listeners {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	listeners := make([]func(ChangeEvent), len(ids))
+	for i, id := range ids {
+		listeners[i] = s.listeners[id]
+	}
+
+	// Take the notification lock before releasing the map's lock, so that the next update's events cannot be
+	// delivered before these.
+	s.notifyMu.Lock()
+	s.mu.Unlock()
+	defer s.notifyMu.Unlock()
+
+	for _, e := range events {
+		for _, listener := range listeners {
+			listener(e)
+		}
+	}
+	return nil
+}
+
+// changeEvents returns the events that describe the changes from before to after, in key order.
+func changeEvents(before, after Map) []ChangeEvent {
+	diff := before.Diff(after)
+
+	keys := make(KeyArray, 0, len(diff.Added)+len(diff.Removed)+len(diff.Changed))
+	keys = append(keys, diff.Added...)
+	keys = append(keys, diff.Removed...)
+	keys = append(keys, diff.Changed...)
+	sort.Sort(keys)
+
+	events := make([]ChangeEvent, len(keys))
+	for i, k := range keys {
+		e := ChangeEvent{Key: k}
+		if v, ok := before[k]; ok {
+			e.Old, e.Secure = &v, v.Secure()
+		}
+		if v, ok := after[k]; ok {
+			e.New, e.Secure = &v, e.Secure || v.Secure()
+		}
+		events[i] = e
+	}
+	return events
+}
+
+// NewSyncMap returns a SyncMap that holds a copy of m.
+func NewSyncMap(m Map) *SyncMap {
+	c := m.Clone()
+	if c == nil {
+		c = Map{}
+	}
+	return &SyncMap{m: c}
+}
+
+// Map returns a copy of the map's current contents.
+func (s *SyncMap) Map() Map {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.Clone()
+}
+
+// Freeze returns an immutable copy of the map's current contents.
+func (s *SyncMap) Freeze() FrozenMap {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.Freeze()
+}
+
+// Len returns the number of keys in the map.
+func (s *SyncMap) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.m)
+}
+
+// Keys returns the keys in the map, in sorted order.
+func (s *SyncMap) Keys() []Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	keys := make(KeyArray, 0, len(s.m))
+	for k := range s.m {
+		keys = append(keys, k)
+	}
+	sort.Sort(keys)
+	return keys
+}
+
+// Find is the concurrency-safe equivalent of Map.Find.
+func (s *SyncMap) Find(pattern string) []Key {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.Find(pattern)
+}
+
+// Get is the concurrency-safe equivalent of Map.Get.
+func (s *SyncMap) Get(k Key, path bool) (Value, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.Get(k, path)
+}
+
+// Lookup is the concurrency-safe equivalent of Map.Lookup.
+func (s *SyncMap) Lookup(k Key, opts LookupOptions) (Key, Value, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.Lookup(k, opts)
+}
+
+// GetPath is the concurrency-safe equivalent of Map.GetPath.
+func (s *SyncMap) GetPath(k Key, path string) (Value, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.GetPath(k, path)
+}
+
+// HasSecureValue is the concurrency-safe equivalent of Map.HasSecureValue.
+func (s *SyncMap) HasSecureValue() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.HasSecureValue()
+}
+
+// Decrypt is the concurrency-safe equivalent of Map.Decrypt. The lock is not held while values are decrypted, so a
+// slow decrypter does not block updates; the result reflects the map's contents when Decrypt was called.
+func (s *SyncMap) Decrypt(decrypter Decrypter) (map[Key]string, error) {
+	return s.Map().Decrypt(decrypter)
+}
+
+// DecryptAll is the concurrency-safe equivalent of Map.DecryptAll. As with Decrypt, the lock is not held while values
+// are decrypted.
+func (s *SyncMap) DecryptAll(ctx context.Context, decrypter Decrypter) (map[Key]string, error) {
+	return s.Map().DecryptAll(ctx, decrypter)
+}
+
+// Set is the concurrency-safe equivalent of Map.Set.
+func (s *SyncMap) Set(k Key, v Value, path bool) error {
+	return s.mutate(func() error {
+		return s.m.Set(k, v, path)
+	})
+}
+
+// SetPath is the concurrency-safe equivalent of Map.SetPath.
+func (s *SyncMap) SetPath(k Key, path string, v Value) error {
+	return s.mutate(func() error {
+		return s.m.SetPath(k, path, v)
+	})
+}
+
+// Remove is the concurrency-safe equivalent of Map.Remove.
+func (s *SyncMap) Remove(k Key, path bool) error {
+	return s.mutate(func() error {
+		return s.m.Remove(k, path)
+	})
+}
+
+// Update applies several changes to the map as a single atomic operation. update is called with a copy of the map's
+// contents, which it may modify freely; if it returns nil, the copy replaces the map's contents, and otherwise the
+// map is left unchanged and update's error is returned. Other goroutines observe either none or all of the changes.
+// update must not call methods of the SyncMap itself.
+func (s *SyncMap) Update(update func(m Map) error) error {
+	return s.mutate(func() error {
+		m := s.m.Clone()
+		if err := update(m); err != nil {
+			return err
+		}
+		s.m = m
+		return nil
+	})
+}
+
+func (s *SyncMap) MarshalJSON() ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.MarshalJSON()
+}
+
+func (s *SyncMap) MarshalYAML() (interface{}, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.m.MarshalYAML()
+}
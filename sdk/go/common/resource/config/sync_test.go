@@ -0,0 +1,144 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncMap(t *testing.T) {
+	count := MustMakeKey("my", "count")
+	other := MustMakeKey("my", "other")
+
+	base := Map{count: NewValue("0")}
+	s := NewSyncMap(base)
+
+	// The SyncMap holds its own copy of the map.
+	base[count] = NewValue("changed")
+	v, ok, err := s.Get(count, false)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, NewValue("0"), v)
+
+	// Readers run concurrently with a writer. Run with -race to check for data races.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_, _, err := s.Get(count, false)
+				assert.NoError(t, err)
+				_, err = s.Decrypt(NopDecrypter)
+				assert.NoError(t, err)
+				_ = s.Keys()
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 1; j <= 100; j++ {
+			assert.NoError(t, s.Set(count, NewValue(strconv.Itoa(j)), false))
+		}
+	}()
+	wg.Wait()
+
+	v, _, err = s.Get(count, false)
+	assert.NoError(t, err)
+	assert.Equal(t, NewValue("100"), v)
+
+	// A failed update leaves the map unchanged.
+	err = s.Update(func(m Map) error {
+		m[other] = NewValue("x")
+		delete(m, count)
+		return errors.New("failed")
+	})
+	assert.Error(t, err)
+	assert.Equal(t, Map{count: NewValue("100")}, s.Map())
+
+	assert.NoError(t, s.Update(func(m Map) error {
+		m[other] = NewValue("x")
+		return nil
+	}))
+	assert.Equal(t, 2, s.Len())
+	assert.Equal(t, []Key{count, other}, s.Keys())
+}
+
+func TestSyncMapSubscribe(t *testing.T) {
+	name := MustMakeKey("my", "name")
+	password := MustMakeKey("my", "password")
+	obj := MustMakeKey("my", "obj")
+
+	s := NewSyncMap(Map{name: NewValue("a")})
+
+	var events []ChangeEvent
+	unsubscribe := s.Subscribe(func(e ChangeEvent) {
+		// Listeners may read the map, which already holds the new value.
+		v, ok, err := s.Get(e.Key, false)
+		assert.NoError(t, err)
+		assert.Equal(t, e.New != nil, ok)
+		if ok {
+			assert.Equal(t, *e.New, v)
+		}
+		events = append(events, e)
+	})
+
+	old, updated := NewValue("a"), NewValue("b")
+	assert.NoError(t, s.Set(name, updated, false))
+	assert.Equal(t, []ChangeEvent{{Key: name, Old: &old, New: &updated}}, events)
+
+	// Setting a key to its current value is not a change.
+	events = nil
+	assert.NoError(t, s.Set(name, updated, false))
+	assert.Empty(t, events)
+
+	// An update reports each changed key in order.
+	events = nil
+	secret := NewSecureValue("ciphertext")
+	assert.NoError(t, s.Update(func(m Map) error {
+		m[password] = secret
+		delete(m, name)
+		return nil
+	}))
+	assert.Equal(t, []ChangeEvent{
+		{Key: name, Old: &updated},
+		{Key: password, New: &secret, Secure: true},
+	}, events)
+
+	// Failed changes are not reported.
+	events = nil
+	assert.Error(t, s.Update(func(m Map) error { return errors.New("failed") }))
+	assert.Error(t, s.Set(MustMakeKey("my", "name[0"), NewValue("x"), true))
+	assert.Empty(t, events)
+
+	// Changes by path are reported against the key that holds the path.
+	assert.NoError(t, s.SetPath(obj, "a.b", NewValue("c")))
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, obj, events[0].Key)
+		assert.Nil(t, events[0].Old)
+		assert.Equal(t, NewObjectValue(`{"a":{"b":"c"}}`), *events[0].New)
+	}
+
+	events = nil
+	unsubscribe()
+	assert.NoError(t, s.Remove(password, false))
+	assert.Empty(t, events)
+}
@@ -0,0 +1,119 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// byteSizeUnits maps each byte size suffix accepted by ParseByteSize to its multiplier. Both the decimal (`KB`, `MB`,
+// ...) and binary (`Ki`, `KiB`, `Mi`, `MiB`, ...) conventions are accepted; the suffix is case-sensitive.
+var byteSizeUnits = map[string]float64{
+	"":  1,
+	"B": 1,
+	"K": 1e3, "KB": 1e3, "Ki": 1 << 10, "KiB": 1 << 10,
+	"M": 1e6, "MB": 1e6, "Mi": 1 << 20, "MiB": 1 << 20,
+	"G": 1e9, "GB": 1e9, "Gi": 1 << 30, "GiB": 1 << 30,
+	"T": 1e12, "TB": 1e12, "Ti": 1 << 40, "TiB": 1 << 40,
+	"P": 1e15, "PB": 1e15, "Pi": 1 << 50, "PiB": 1 << 50,
+}
+
+// ParseByteSize parses a size in bytes such as `512Mi`, `10GB` or `1.5K`. A size without a suffix is a number of
+// bytes. Fractional sizes are allowed so long as they amount to a whole number of bytes.
+func ParseByteSize(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i == -1 {
+		i = len(s)
+	}
+	number, unit := s[:i], strings.TrimSpace(s[i:])
+
+	multiplier, ok := byteSizeUnits[unit]
+	if !ok || number == "" {
+		return 0, errors.Errorf("%q is not a valid byte size", s)
+	}
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, errors.Errorf("%q is not a valid byte size", s)
+	}
+	size := n * multiplier
+	if size != math.Trunc(size) {
+		return 0, errors.Errorf("%q is not a whole number of bytes", s)
+	}
+	if size >= math.MaxUint64 {
+		return 0, errors.Errorf("%q is too large", s)
+	}
+	return uint64(size), nil
+}
+
+// ParseURL parses an absolute URL, such as `https://example.com/path`. Unlike url.Parse, it rejects relative
+// references.
+func ParseURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, errors.Errorf("%q is not a valid URL", s)
+	}
+	if !u.IsAbs() {
+		return nil, errors.Errorf("%q is not an absolute URL", s)
+	}
+	return u, nil
+}
+
+// AsDuration returns the value as a time.Duration, using decrypter to decrypt it if necessary. Durations use the
+// syntax of time.ParseDuration, e.g. `30s` or `1h15m`.
+func (c Value) AsDuration(decrypter Decrypter) (time.Duration, error) {
+	v, err := c.scalar(decrypter)
+	if err != nil {
+		return 0, err
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, errors.Errorf("config value %q is not a valid duration", v)
+	}
+	return d, nil
+}
+
+// AsByteSize returns the value as a number of bytes, using decrypter to decrypt it if necessary. Sizes use the syntax
+// of ParseByteSize, e.g. `512Mi` or `10GB`.
+func (c Value) AsByteSize(decrypter Decrypter) (uint64, error) {
+	v, err := c.scalar(decrypter)
+	if err != nil {
+		return 0, err
+	}
+	size, err := ParseByteSize(v)
+	if err != nil {
+		return 0, errors.Wrap(err, "invalid config value")
+	}
+	return size, nil
+}
+
+// AsURL returns the value as an absolute URL, using decrypter to decrypt it if necessary.
+func (c Value) AsURL(decrypter Decrypter) (*url.URL, error) {
+	v, err := c.scalar(decrypter)
+	if err != nil {
+		return nil, err
+	}
+	u, err := ParseURL(v)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid config value")
+	}
+	return u, nil
+}
@@ -0,0 +1,76 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseByteSize(t *testing.T) {
+	tests := map[string]uint64{
+		"0":       0,
+		"100":     100,
+		"100B":    100,
+		"1K":      1000,
+		"10KB":    10000,
+		"512Mi":   512 << 20,
+		"2GiB":    2 << 30,
+		"1.5K":    1500,
+		"0.5Ki":   512,
+		" 3 TB ":  3e12,
+		"1Pi":     1 << 50,
+		"0.25MiB": 256 << 10,
+	}
+	for s, expected := range tests {
+		size, err := ParseByteSize(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, expected, size, s)
+	}
+
+	for _, s := range []string{"", "Mi", "12mb", "1.2.3K", "-1", "1.5", "0.1Ki", "1XB", "100000000000EB"} {
+		_, err := ParseByteSize(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestUnitAccessors(t *testing.T) {
+	d, err := NewValue("1h15m").AsDuration(NopDecrypter)
+	assert.NoError(t, err)
+	assert.Equal(t, 75*time.Minute, d)
+	d, err = NewSecureValue("enc-30s").AsDuration(newPrefixCrypter("enc-"))
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Second, d)
+	_, err = NewValue("30").AsDuration(NopDecrypter)
+	assert.Error(t, err)
+
+	size, err := NewValue("512Mi").AsByteSize(NopDecrypter)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(512<<20), size)
+	_, err = NewObjectValue(`{"a":"b"}`).AsByteSize(NopDecrypter)
+	assert.Error(t, err)
+
+	u, err := NewValue("https://example.com:8443/path?q=1").AsURL(NopDecrypter)
+	assert.NoError(t, err)
+	assert.Equal(t, "https", u.Scheme)
+	assert.Equal(t, "example.com:8443", u.Host)
+	assert.Equal(t, "/path", u.Path)
+	_, err = NewValue("example.com/path").AsURL(NopDecrypter)
+	assert.Error(t, err)
+	_, err = NewValue("http://[::1").AsURL(NopDecrypter)
+	assert.Error(t, err)
+}
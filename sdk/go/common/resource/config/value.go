@@ -15,17 +15,93 @@
 package config
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 
 	"github.com/pkg/errors"
 )
 
+// Type is the type of a config value.
+type Type int
+
+const (
+	// TypeString is a plain string value. This is the default for values that were not created with an explicit type.
+	TypeString Type = iota
+	// TypeInt is an integer value.
+	TypeInt
+	// TypeFloat is a floating point value.
+	TypeFloat
+	// TypeBool is a boolean value.
+	TypeBool
+	// TypeList is a list value, stored as a JSON array.
+	TypeList
+	// TypeObject is an object value, stored as a JSON object.
+	TypeObject
+	// TypeBytes is a binary value, stored as base64 text. See NewBytesValue.
+	TypeBytes
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeString:
+		return "string"
+	case TypeInt:
+		return "int"
+	case TypeFloat:
+		return "float"
+	case TypeBool:
+		return "bool"
+	case TypeList:
+		return "list"
+	case TypeObject:
+		return "object"
+	case TypeBytes:
+		return "bytes"
+	}
+	return fmt.Sprintf("Type(%d)", int(t))
+}
+
+// ParseType parses the name of a config value type, as returned by Type.String.
+func ParseType(s string) (Type, error) {
+	for t := TypeString; t <= TypeBytes; t++ {
+		if t.String() == s {
+			return t, nil
+		}
+	}
+	return TypeString, errors.Errorf("unknown config value type %q", s)
+}
+
+func (t Type) MarshalText() ([]byte, error) {
+	return []byte(t.String()), nil
+}
+
+func (t *Type) UnmarshalText(b []byte) error {
+	pt, err := ParseType(string(b))
+	if err != nil {
+		return err
+	}
+	*t = pt
+	return nil
+}
+
 // Value is a single config value.
 type Value struct {
 	value  string
 	secure bool
 	object bool
+	// typ records the type of a plaintext scalar value, or TypeBytes for a secure binary value. It is otherwise
+	// ignored for secure and object values.
+	typ Type
+	// ref, if non-empty, is the URI of an external blob that holds the value's content, in which case value holds the
+	// hex-encoded SHA-256 hash of that content (see NewBlobValue), or the URI of a value held in an external store, in
+	// which case value is empty (see NewExternalRefValue).
+	ref string
+	// unquotedBool records that a plaintext string was written in a stack file as a bare YAML 1.1 boolean literal,
+	// e.g. `yes` rather than `"yes"`, so that it may be read as a bool under BoolSyntaxYAML11 (see Map.NormalizeBools).
+	unquotedBool bool
 }
 
 func NewSecureValue(v string) Value {
@@ -44,9 +120,104 @@ func NewObjectValue(v string) Value {
 	return Value{value: v, secure: false, object: true}
 }
 
+// NewIntValue returns a plaintext value of type TypeInt.
+func NewIntValue(v int) Value {
+	return Value{value: strconv.Itoa(v), typ: TypeInt}
+}
+
+// NewFloatValue returns a plaintext value of type TypeFloat.
+func NewFloatValue(v float64) Value {
+	return Value{value: strconv.FormatFloat(v, 'g', -1, 64), typ: TypeFloat}
+}
+
+// NewBoolValue returns a plaintext value of type TypeBool.
+func NewBoolValue(v bool) Value {
+	return Value{value: strconv.FormatBool(v), typ: TypeBool}
+}
+
+// NewTypedValue parses v as a value of the given scalar type, returning an error if v is not a valid literal for that
+// type. TypeList and TypeObject values must be valid JSON of the corresponding shape.
+func NewTypedValue(v string, t Type) (Value, error) {
+	switch t {
+	case TypeString:
+		return NewValue(v), nil
+	case TypeInt:
+		i, err := strconv.Atoi(v)
+		if err != nil {
+			return Value{}, errors.Errorf("%q is not a valid int", v)
+		}
+		return NewIntValue(i), nil
+	case TypeFloat:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return Value{}, errors.Errorf("%q is not a valid float", v)
+		}
+		return NewFloatValue(f), nil
+	case TypeBool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return Value{}, errors.Errorf("%q is not a valid bool", v)
+		}
+		return NewBoolValue(b), nil
+	case TypeBytes:
+		b, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return Value{}, errors.Errorf("%q is not valid base64", v)
+		}
+		return NewBytesValue(b), nil
+	case TypeList, TypeObject:
+		var obj interface{}
+		if err := json.Unmarshal([]byte(v), &obj); err != nil {
+			return Value{}, errors.Wrapf(err, "%q is not a valid %v", v, t)
+		}
+		switch obj.(type) {
+		case []interface{}:
+			if t != TypeList {
+				return Value{}, errors.Errorf("%q is not a valid %v", v, t)
+			}
+		case map[string]interface{}:
+			if t != TypeObject {
+				return Value{}, errors.Errorf("%q is not a valid %v", v, t)
+			}
+		default:
+			return Value{}, errors.Errorf("%q is not a valid %v", v, t)
+		}
+		if hasSecureValue(obj) {
+			return NewSecureObjectValue(v), nil
+		}
+		return NewObjectValue(v), nil
+	}
+	return Value{}, errors.Errorf("unknown config value type %v", t)
+}
+
+// NewSecureListValue returns a list value whose elements are the given plaintexts, each encrypted individually using
+// encrypter. In a stack file, each element is written using the `secure:` representation.
+func NewSecureListValue(elements []string, encrypter Encrypter) (Value, error) {
+	list := make([]interface{}, len(elements))
+	for i, e := range elements {
+		ct, err := encrypter.EncryptValue(e)
+		if err != nil {
+			return Value{}, err
+		}
+		list[i] = map[string]interface{}{"secure": ct}
+	}
+	bytes, err := json.Marshal(list)
+	if err != nil {
+		return Value{}, err
+	}
+	return NewSecureObjectValue(string(bytes)), nil
+}
+
 // Value fetches the value of this configuration entry, using decrypter to decrypt if necessary.  If the value
 // is a secret and decrypter is nil, or if decryption fails for any reason, a non-nil error is returned.
 func (c Value) Value(decrypter Decrypter) (string, error) {
+	if c.ref != "" {
+		// Blinding a secret never requires its content, so even an unresolved reference to one can be blinded.
+		if _, blind := decrypter.(blindingCrypter); blind && c.secure {
+			return decrypter.DecryptValue(c.value)
+		}
+		return "", errors.Errorf("value is stored externally at %s and must be resolved before it is read", c.ref)
+	}
 	if !c.secure {
 		return c.value, nil
 	}
@@ -73,6 +244,15 @@ func (c Value) Value(decrypter Decrypter) (string, error) {
 }
 
 func (c Value) Copy(decrypter Decrypter, encrypter Encrypter) (Value, error) {
+	if c.ref != "" {
+		// The content of a reference is stored externally, so a plaintext blob reference or a reference to an external
+		// store can be shared as-is. A secure blob's content cannot be re-encrypted in place.
+		if c.secure && c.isBlob() {
+			return Value{}, errors.Errorf("cannot re-encrypt the secret stored externally at %s", c.ref)
+		}
+		return c, nil
+	}
+
 	var val Value
 	raw, err := c.Value(decrypter)
 	if err != nil {
@@ -100,12 +280,13 @@ func (c Value) Copy(decrypter Decrypter, encrypter Encrypter) (Value, error) {
 				return Value{}, eerr
 			}
 			val = NewSecureValue(enc)
+			val.typ = c.typ
 		}
 	} else {
 		if c.Object() {
 			val = NewObjectValue(raw)
 		} else {
-			val = NewValue(raw)
+			val = Value{value: raw, typ: c.typ}
 		}
 	}
 
@@ -120,6 +301,66 @@ func (c Value) SecureValues(decrypter Decrypter) ([]string, error) {
 	return d.SecureValues(), nil
 }
 
+// ciphertexts returns the ciphertexts of every secure value within this value, in no particular order.
+func (c Value) ciphertexts() ([]string, error) {
+	if !c.secure || c.ref != "" {
+		return nil, nil
+	}
+	if !c.object {
+		return []string{c.value}, nil
+	}
+
+	var obj interface{}
+	if err := json.Unmarshal([]byte(c.value), &obj); err != nil {
+		return nil, err
+	}
+	var result []string
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		if is, ct := isSecureValue(v); is {
+			result = append(result, ct)
+			return
+		}
+		switch t := v.(type) {
+		case map[string]interface{}:
+			for _, e := range t {
+				walk(e)
+			}
+		case []interface{}:
+			for _, e := range t {
+				walk(e)
+			}
+		}
+	}
+	walk(obj)
+	return result, nil
+}
+
+// redacted returns the value as plain data with every secure value replaced by "[secret]".
+func (c Value) redacted() (interface{}, error) {
+	if c.ref != "" {
+		if c.secure {
+			return blindingCrypter{}.DecryptValue(c.value)
+		}
+		return c.marshalValue()
+	}
+	if !c.object {
+		if c.secure {
+			return blindingCrypter{}.DecryptValue(c.value)
+		}
+		return c.typedValue(), nil
+	}
+
+	obj, err := c.ToObject()
+	if err != nil {
+		return nil, err
+	}
+	if is, ct := isSecureValue(obj); is {
+		return blindingCrypter{}.DecryptValue(ct)
+	}
+	return decryptObject(obj, blindingCrypter{})
+}
+
 func (c Value) Secure() bool {
 	return c.secure
 }
@@ -128,6 +369,104 @@ func (c Value) Object() bool {
 	return c.object
 }
 
+// Type returns the type of this value. Secure scalar values are always reported as TypeString, since their type is
+// not recorded alongside the ciphertext.
+func (c Value) Type() Type {
+	if c.object {
+		if strings.HasPrefix(strings.TrimSpace(c.value), "[") {
+			return TypeList
+		}
+		return TypeObject
+	}
+	if c.secure && c.typ != TypeBytes {
+		return TypeString
+	}
+	return c.typ
+}
+
+// AsInt returns the value as an int, using decrypter to decrypt it if necessary.
+func (c Value) AsInt(decrypter Decrypter) (int, error) {
+	v, err := c.scalar(decrypter)
+	if err != nil {
+		return 0, err
+	}
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, errors.Errorf("config value %q is not a valid int", v)
+	}
+	return i, nil
+}
+
+// AsFloat returns the value as a float64, using decrypter to decrypt it if necessary.
+func (c Value) AsFloat(decrypter Decrypter) (float64, error) {
+	v, err := c.scalar(decrypter)
+	if err != nil {
+		return 0, err
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, errors.Errorf("config value %q is not a valid float", v)
+	}
+	return f, nil
+}
+
+// AsBool returns the value as a bool, using decrypter to decrypt it if necessary.
+func (c Value) AsBool(decrypter Decrypter) (bool, error) {
+	v, err := c.scalar(decrypter)
+	if err != nil {
+		return false, err
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, errors.Errorf("config value %q is not a valid bool", v)
+	}
+	return b, nil
+}
+
+// Elements returns the elements of a list value without decrypting them. Secure elements are returned as secure
+// values, and elements that are themselves lists or objects are returned as object values.
+func (c Value) Elements() ([]Value, error) {
+	if c.Type() != TypeList {
+		return nil, errors.Errorf("expected a list, not a %v", c.Type())
+	}
+	obj, err := c.ToObject()
+	if err != nil {
+		return nil, err
+	}
+	list := obj.([]interface{})
+	elements := make([]Value, len(list))
+	for i, e := range list {
+		if elements[i], err = valueFromObject(e); err != nil {
+			return nil, err
+		}
+	}
+	return elements, nil
+}
+
+// AsList returns the plaintext of each element of a list value, using decrypter to decrypt secure elements. Elements
+// that are themselves lists or objects are returned as JSON text.
+func (c Value) AsList(decrypter Decrypter) ([]string, error) {
+	elements, err := c.Elements()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]string, len(elements))
+	for i, e := range elements {
+		if result[i], err = e.Value(decrypter); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// scalar returns the (decrypted) text of a scalar value, or an error if the value is an object.
+func (c Value) scalar(decrypter Decrypter) (string, error) {
+	if c.object {
+		return "", errors.Errorf("config value is a %v, not a scalar", c.Type())
+	}
+	return c.Value(decrypter)
+}
+
 // ToObject returns the string value (if not an object), or the unmarshalled JSON object (if an object).
 func (c Value) ToObject() (interface{}, error) {
 	if !c.object {
@@ -172,11 +511,19 @@ func (c *Value) UnmarshalYAML(unmarshal func(interface{}) error) error {
 }
 
 func (c *Value) unmarshalValue(unmarshal func(interface{}) error, fix func(interface{}) interface{}) error {
-	// First, try to unmarshal as a string.
+	// First, try to unmarshal as a string, recording the scalar's native type if it round-trips exactly.
 	err := unmarshal(&c.value)
 	if err == nil {
 		c.secure = false
 		c.object = false
+		c.typ = TypeString
+		c.unquotedBool = false
+		var native interface{}
+		if unmarshal(&native) == nil {
+			c.typ = scalarType(c.value, native)
+			_, isBool := native.(bool)
+			c.unquotedBool = isBool && c.typ == TypeString
+		}
 		return nil
 	}
 
@@ -189,6 +536,29 @@ func (c *Value) unmarshalValue(unmarshal func(interface{}) error, fix func(inter
 	// Fix-up the object (e.g. convert `map[interface{}]interface{}` to `map[string]interface{}`).
 	obj = fix(obj)
 
+	// Native scalars that could not be read as strings (e.g. JSON numbers and booleans) become typed values.
+	switch t := obj.(type) {
+	case bool:
+		*c = NewBoolValue(t)
+		return nil
+	case float64:
+		if t == float64(int64(t)) && t >= -(1<<53) && t <= 1<<53 {
+			*c = Value{value: strconv.FormatInt(int64(t), 10), typ: TypeInt}
+		} else {
+			*c = NewFloatValue(t)
+		}
+		return nil
+	}
+
+	return c.setObject(obj)
+}
+
+// setObject sets c from obj, the decoded form of a non-scalar value in a stack file: a reserved map such as a secure
+// value or a reference, or else a plain object.
+//
+// Reserved maps other than secure values are marked by a property whose name starts with `$`, such as `$ref`, so that
+// a plain object that happens to have a property of the same name without the prefix is not mistaken for one.
+func (c *Value) setObject(obj interface{}) error {
 	if is, val := isSecureValue(obj); is {
 		c.value = val
 		c.secure = true
@@ -196,6 +566,21 @@ func (c *Value) unmarshalValue(unmarshal func(interface{}) error, fix func(inter
 		return nil
 	}
 
+	if is, ref, hash, secure := isBlobRef(obj); is {
+		*c = NewBlobRefValue(ref, hash, secure)
+		return nil
+	}
+
+	if is, ref := isExternalRef(obj); is {
+		*c = NewExternalRefValue(ref)
+		return nil
+	}
+
+	if is, val, secure := isBinaryValue(obj); is {
+		*c = Value{value: val, secure: secure, typ: TypeBytes}
+		return nil
+	}
+
 	json, err := json.Marshal(obj)
 	if err != nil {
 		return errors.Wrapf(err, "marshalling obj")
@@ -207,14 +592,29 @@ func (c *Value) unmarshalValue(unmarshal func(interface{}) error, fix func(inter
 }
 
 func (c Value) marshalValue() (interface{}, error) {
+	if c.isExternal() {
+		return map[string]interface{}{externalRefKey: c.ref}, nil
+	}
+	if c.ref != "" {
+		m := map[string]interface{}{blobRefKey: c.ref, "sha256": c.value}
+		if c.secure {
+			m["secure"] = true
+		}
+		return m, nil
+	}
+
 	if c.object {
 		var obj interface{}
 		err := json.Unmarshal([]byte(c.value), &obj)
 		return obj, err
 	}
 
+	if c.typ == TypeBytes {
+		return c.marshalBinaryValue(), nil
+	}
+
 	if !c.secure {
-		return c.value, nil
+		return c.typedValue(), nil
 	}
 
 	m := make(map[string]string)
@@ -223,10 +623,60 @@ func (c Value) marshalValue() (interface{}, error) {
 	return m, nil
 }
 
+// typedValue returns the native Go representation of a plaintext scalar value, falling back to the raw string if the
+// value cannot be parsed as its declared type.
+func (c Value) typedValue() interface{} {
+	switch c.typ {
+	case TypeInt:
+		if i, err := strconv.ParseInt(c.value, 10, 64); err == nil {
+			return i
+		}
+	case TypeFloat:
+		if f, err := strconv.ParseFloat(c.value, 64); err == nil {
+			return f
+		}
+	case TypeBool:
+		if b, err := strconv.ParseBool(c.value); err == nil {
+			return b
+		}
+	}
+	return c.value
+}
+
+// scalarType returns the type of a scalar that was decoded both as the string raw and as the native value native. A
+// typed result is only returned when formatting native reproduces raw exactly, so literals such as `yes`, `0x10` or
+// `1.10` remain strings and are written back unchanged.
+func scalarType(raw string, native interface{}) Type {
+	switch t := native.(type) {
+	case bool:
+		if raw == strconv.FormatBool(t) {
+			return TypeBool
+		}
+	case int:
+		if raw == strconv.Itoa(t) {
+			return TypeInt
+		}
+	case int64:
+		if raw == strconv.FormatInt(t, 10) {
+			return TypeInt
+		}
+	case uint64:
+		if raw == strconv.FormatUint(t, 10) {
+			return TypeInt
+		}
+	case float64:
+		if raw == strconv.FormatFloat(t, 'g', -1, 64) && strings.ContainsAny(raw, ".eE") {
+			return TypeFloat
+		}
+	}
+	return TypeString
+}
+
 // The unserialized value from YAML needs to be serializable as JSON, but YAML will unmarshal maps as
 // `map[interface{}]interface{}` (because it supports bools as keys), which isn't supported by the JSON
 // marshaller. To address, when unserializing YAML, we convert `map[interface{}]interface{}` to
-// `map[string]interface{}`.
+// `map[string]interface{}`. Maps built in Go, such as the `map[string]string` form of a secure value, are normalized
+// in the same way.
 func interfaceMapToStringMap(v interface{}) interface{} {
 	switch t := v.(type) {
 	case map[interface{}]interface{}:
@@ -235,6 +685,18 @@ func interfaceMapToStringMap(v interface{}) interface{} {
 			m[fmt.Sprintf("%v", key)] = interfaceMapToStringMap(val)
 		}
 		return m
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for key, val := range t {
+			m[key] = interfaceMapToStringMap(val)
+		}
+		return m
+	case map[string]string:
+		m := make(map[string]interface{}, len(t))
+		for key, val := range t {
+			m[key] = val
+		}
+		return m
 	case []interface{}:
 		a := make([]interface{}, len(t))
 		for i, val := range t {
@@ -268,6 +730,32 @@ func hasSecureValue(v interface{}) bool {
 	return false
 }
 
+// blobRefKey is the reserved property that holds the URI of an external blob in a stack file.
+const blobRefKey = "$blob"
+
+// isBlobRef returns true if v is the serialized form of a reference to an external blob, i.e. a map holding a string
+// `$blob`, a string `sha256` and, optionally, a boolean `secure`.
+func isBlobRef(v interface{}) (bool, string, string, bool) {
+	m, isMap := v.(map[string]interface{})
+	if !isMap {
+		return false, "", "", false
+	}
+	ref, refOK := m[blobRefKey].(string)
+	hash, hashOK := m["sha256"].(string)
+	if !refOK || !hashOK {
+		return false, "", "", false
+	}
+	switch len(m) {
+	case 2:
+		return true, ref, hash, false
+	case 3:
+		secure, ok := m["secure"].(bool)
+		return ok, ref, hash, secure
+	default:
+		return false, "", "", false
+	}
+}
+
 // isSecureValue returns true if the object is a `map[string]string` of length one with a "secure" key.
 func isSecureValue(v interface{}) (bool, string) {
 	if m, isMap := v.(map[string]interface{}); isMap && len(m) == 1 {
@@ -282,6 +282,140 @@ func TestCopyValue(t *testing.T) {
 	}
 }
 
+func TestTypedValues(t *testing.T) {
+	tests := []struct {
+		Value Value
+		Type  Type
+		YAML  string
+		JSON  string
+	}{
+		{Value: NewValue("3"), Type: TypeString, YAML: "\"3\"\n", JSON: `"3"`},
+		{Value: NewIntValue(3), Type: TypeInt, YAML: "3\n", JSON: `3`},
+		{Value: NewFloatValue(1.5), Type: TypeFloat, YAML: "1.5\n", JSON: `1.5`},
+		{Value: NewBoolValue(true), Type: TypeBool, YAML: "true\n", JSON: `true`},
+		{Value: NewObjectValue(`["a"]`), Type: TypeList, YAML: "- a\n", JSON: `["a"]`},
+		{Value: NewObjectValue(`{"a":1}`), Type: TypeObject, YAML: "a: 1\n", JSON: `{"a":1}`},
+		{Value: NewSecureValue("3"), Type: TypeString, YAML: "secure: \"3\"\n", JSON: `{"secure":"3"}`},
+	}
+
+	for _, test := range tests {
+		t.Run(fmt.Sprintf("%v", test.Value), func(t *testing.T) {
+			assert.Equal(t, test.Type, test.Value.Type())
+
+			b, err := yaml.Marshal(test.Value)
+			assert.NoError(t, err)
+			assert.Equal(t, test.YAML, string(b))
+			newV, err := roundtripValueYAML(test.Value)
+			assert.NoError(t, err)
+			assert.Equal(t, test.Value, newV)
+
+			b, err = json.Marshal(test.Value)
+			assert.NoError(t, err)
+			assert.Equal(t, test.JSON, string(b))
+			newV, err = roundtripValueJSON(test.Value)
+			assert.NoError(t, err)
+			assert.Equal(t, test.Value, newV)
+		})
+	}
+}
+
+func TestUntypedYAMLScalarsRemainStrings(t *testing.T) {
+	for _, s := range []string{"yes", "0x10", "1.10", "010"} {
+		var v Value
+		err := yaml.Unmarshal([]byte(s), &v)
+		assert.NoError(t, err)
+		assert.Equal(t, TypeString, v.Type())
+		raw, err := v.Value(NopDecrypter)
+		assert.NoError(t, err)
+		assert.Equal(t, s, raw)
+		assert.True(t, v.Equal(NewValue(s)))
+	}
+}
+
+func TestNewTypedValue(t *testing.T) {
+	v, err := NewTypedValue("42", TypeInt)
+	assert.NoError(t, err)
+	assert.Equal(t, NewIntValue(42), v)
+
+	v, err = NewTypedValue(`[1,2]`, TypeList)
+	assert.NoError(t, err)
+	assert.Equal(t, NewObjectValue(`[1,2]`), v)
+
+	_, err = NewTypedValue("forty-two", TypeInt)
+	assert.Error(t, err)
+	_, err = NewTypedValue(`{"a":1}`, TypeList)
+	assert.Error(t, err)
+}
+
+func TestTypedAccessors(t *testing.T) {
+	i, err := NewIntValue(7).AsInt(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 7, i)
+
+	f, err := NewValue("2.5").AsFloat(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2.5, f)
+
+	b, err := NewSecureValue("pretrue").AsBool(newPrefixCrypter("pre"))
+	assert.NoError(t, err)
+	assert.True(t, b)
+
+	_, err = NewValue("nope").AsBool(nil)
+	assert.Error(t, err)
+	_, err = NewObjectValue(`{"a":1}`).AsInt(nil)
+	assert.Error(t, err)
+}
+
+func TestSecureLists(t *testing.T) {
+	crypter := newPrefixCrypter("enc-")
+
+	all, err := NewSecureListValue([]string{"a", "b"}, crypter)
+	assert.NoError(t, err)
+	assert.True(t, all.Secure())
+	assert.Equal(t, TypeList, all.Type())
+
+	b, err := yaml.Marshal(all)
+	assert.NoError(t, err)
+	assert.Equal(t, "- secure: enc-a\n- secure: enc-b\n", string(b))
+
+	var mixed Value
+	err = yaml.Unmarshal([]byte("- secure: enc-a\n- plain\n- 3\n- [nested]\n"), &mixed)
+	assert.NoError(t, err)
+	assert.True(t, mixed.Secure())
+	assert.Equal(t, TypeList, mixed.Type())
+
+	for _, v := range []Value{all, mixed} {
+		newV, err := roundtripValueYAML(v)
+		assert.NoError(t, err)
+		assert.Equal(t, v, newV)
+		newV, err = roundtripValueJSON(v)
+		assert.NoError(t, err)
+		assert.Equal(t, v, newV)
+	}
+
+	elements, err := mixed.Elements()
+	assert.NoError(t, err)
+	assert.Equal(t, []Value{
+		NewSecureValue("enc-a"),
+		NewValue("plain"),
+		NewValue("3"),
+		NewObjectValue(`["nested"]`),
+	}, elements)
+
+	plaintexts, err := mixed.AsList(crypter)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "plain", "3", `["nested"]`}, plaintexts)
+
+	s, err := all.Value(crypter)
+	assert.NoError(t, err)
+	assert.Equal(t, `["a","b"]`, s)
+
+	_, err = NewObjectValue(`{"a":"b"}`).Elements()
+	assert.Error(t, err)
+	_, err = NewValue("a").AsList(crypter)
+	assert.Error(t, err)
+}
+
 func roundtripValueYAML(v Value) (Value, error) {
 	return roundtripValue(v, yaml.Marshal, yaml.Unmarshal)
 }
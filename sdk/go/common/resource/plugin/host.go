@@ -365,6 +365,8 @@ func (host *defaultHost) EnsurePlugins(plugins []workspace.PluginInfo, kinds Fla
 						errors.Wrapf(err, "failed to load resource plugin %s", plugin.Name))
 				}
 			}
+		case workspace.SecretsPlugin:
+			// Secrets plugins are launched on demand for each operation, so there is nothing to load.
 		default:
 			contract.Failf("unexpected plugin kind: %s", plugin.Kind)
 		}
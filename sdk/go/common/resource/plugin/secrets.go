@@ -0,0 +1,36 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"context"
+	"io"
+
+	"github.com/pulumi/pulumi/sdk/v2/go/common/workspace"
+)
+
+// SecretsProvider provides a pluggable interface for encrypting and decrypting the data keys that protect a stack's
+// secrets. A secrets provider plugin serves all of the secrets provider URLs of a single scheme; each call names the
+// key to use with the full URL.
+type SecretsProvider interface {
+	// Closer closes any underlying OS resources associated with this provider (like processes, RPC channels, etc).
+	io.Closer
+	// Encrypt encrypts plaintext with the key named by url.
+	Encrypt(ctx context.Context, url string, plaintext []byte) ([]byte, error)
+	// Decrypt decrypts a ciphertext that Encrypt returned for the same url.
+	Decrypt(ctx context.Context, url string, ciphertext []byte) ([]byte, error)
+	// GetPluginInfo returns this plugin's information.
+	GetPluginInfo() (workspace.PluginInfo, error)
+}
@@ -0,0 +1,134 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/blang/semver"
+	pbempty "github.com/golang/protobuf/ptypes/empty"
+
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/logging"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/rpcutil/rpcerror"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/workspace"
+	pulumirpc "github.com/pulumi/pulumi/sdk/v2/proto/go"
+)
+
+// secretsProvider reflects a secrets provider plugin, loaded dynamically for the URLs of a single scheme.
+type secretsProvider struct {
+	scheme string
+	plug   *plugin
+	client pulumirpc.SecretsProviderClient
+}
+
+var _ SecretsProvider = (*secretsProvider)(nil)
+
+// NewSecretsProvider binds to the secrets provider plugin for the given URL scheme, `pulumi-secrets-<scheme>`, and
+// creates a gRPC connection to it. If the plugin could not be found on the PATH or in the plugin cache, or an error
+// occurs while creating the child process, an error is returned.
+func NewSecretsProvider(ctx *Context, scheme string) (SecretsProvider, error) {
+	_, path, err := workspace.GetPluginPath(workspace.SecretsPlugin, scheme, nil)
+	if err != nil {
+		return nil, rpcerror.Convert(err)
+	} else if path == "" {
+		return nil, workspace.NewMissingError(workspace.PluginInfo{
+			Kind: workspace.SecretsPlugin,
+			Name: scheme,
+		})
+	}
+
+	plug, err := newPlugin(ctx, ctx.Pwd, path, fmt.Sprintf("%v (secrets)", scheme), nil /*args*/, nil /*env*/)
+	if err != nil {
+		return nil, err
+	}
+	contract.Assertf(plug != nil, "unexpected nil secrets plugin for %s", scheme)
+
+	return &secretsProvider{
+		scheme: scheme,
+		plug:   plug,
+		client: pulumirpc.NewSecretsProviderClient(plug.Conn),
+	}, nil
+}
+
+func (p *secretsProvider) label() string {
+	return fmt.Sprintf("SecretsProvider[%s]", p.scheme)
+}
+
+// Encrypt encrypts plaintext with the key named by url.
+func (p *secretsProvider) Encrypt(ctx context.Context, url string, plaintext []byte) ([]byte, error) {
+	label := fmt.Sprintf("%s.Encrypt(%s)", p.label(), url)
+	logging.V(7).Infof("%s executing", label)
+	resp, err := p.client.Encrypt(ctx, &pulumirpc.SecretsEncryptRequest{Url: url, Plaintext: plaintext})
+	if err != nil {
+		rpcError := rpcerror.Convert(err)
+		logging.V(7).Infof("%s failed: err=%v", label, rpcError)
+		return nil, rpcError
+	}
+	return resp.GetCiphertext(), nil
+}
+
+// Decrypt decrypts a ciphertext that Encrypt returned for the same url.
+func (p *secretsProvider) Decrypt(ctx context.Context, url string, ciphertext []byte) ([]byte, error) {
+	label := fmt.Sprintf("%s.Decrypt(%s)", p.label(), url)
+	logging.V(7).Infof("%s executing", label)
+	resp, err := p.client.Decrypt(ctx, &pulumirpc.SecretsDecryptRequest{Url: url, Ciphertext: ciphertext})
+	if err != nil {
+		rpcError := rpcerror.Convert(err)
+		logging.V(7).Infof("%s failed: err=%v", label, rpcError)
+		return nil, rpcError
+	}
+	return resp.GetPlaintext(), nil
+}
+
+// GetPluginInfo returns this plugin's information.
+func (p *secretsProvider) GetPluginInfo() (workspace.PluginInfo, error) {
+	label := fmt.Sprintf("%s.GetPluginInfo()", p.label())
+	logging.V(7).Infof("%s executing", label)
+	resp, err := p.client.GetPluginInfo(context.Background(), &pbempty.Empty{})
+	if err != nil {
+		rpcError := rpcerror.Convert(err)
+		logging.V(7).Infof("%s failed: err=%v", label, rpcError)
+		return workspace.PluginInfo{}, rpcError
+	}
+
+	var version *semver.Version
+	if v := resp.Version; v != "" {
+		sv, err := semver.ParseTolerant(v)
+		if err != nil {
+			return workspace.PluginInfo{}, err
+		}
+		version = &sv
+	}
+
+	info := workspace.PluginInfo{
+		Name:    p.scheme,
+		Kind:    workspace.SecretsPlugin,
+		Version: version,
+	}
+	if p.plug != nil {
+		info.Path = p.plug.Bin
+	}
+	return info, nil
+}
+
+// Close tears down the underlying plugin RPC connection and process.
+func (p *secretsProvider) Close() error {
+	if p.plug == nil {
+		return nil
+	}
+	return p.plug.Close()
+}
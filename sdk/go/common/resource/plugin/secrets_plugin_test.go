@@ -0,0 +1,97 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/blang/semver"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+
+	"github.com/pulumi/pulumi/sdk/v2/go/common/util/rpcutil"
+	"github.com/pulumi/pulumi/sdk/v2/go/common/workspace"
+	pulumirpc "github.com/pulumi/pulumi/sdk/v2/proto/go"
+)
+
+// xorSecretsProvider "encrypts" by XORing each byte of a plaintext with the length of the URL.
+type xorSecretsProvider struct{}
+
+func (xorSecretsProvider) Close() error { return nil }
+
+func (xorSecretsProvider) Encrypt(ctx context.Context, url string, plaintext []byte) ([]byte, error) {
+	if url == "xor://broken" {
+		return nil, errors.New("the key is broken")
+	}
+	return xor(url, plaintext), nil
+}
+
+func (xorSecretsProvider) Decrypt(ctx context.Context, url string, ciphertext []byte) ([]byte, error) {
+	return xor(url, ciphertext), nil
+}
+
+func (xorSecretsProvider) GetPluginInfo() (workspace.PluginInfo, error) {
+	version := semver.MustParse("1.2.3")
+	return workspace.PluginInfo{Version: &version}, nil
+}
+
+func xor(url string, b []byte) []byte {
+	result := make([]byte, len(b))
+	for i := range b {
+		result[i] = b[i] ^ byte(len(url))
+	}
+	return result
+}
+
+func TestSecretsProviderRPC(t *testing.T) {
+	cancel := make(chan bool)
+	defer close(cancel)
+	port, _, err := rpcutil.Serve(0, cancel, []func(*grpc.Server) error{
+		func(srv *grpc.Server) error {
+			pulumirpc.RegisterSecretsProviderServer(srv, NewSecretsProviderServer(xorSecretsProvider{}))
+			return nil
+		},
+	}, nil)
+	assert.NoError(t, err)
+
+	conn, err := grpc.Dial("127.0.0.1:"+strconv.Itoa(port), grpc.WithInsecure(), rpcutil.GrpcChannelOptions())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	p := &secretsProvider{scheme: "xor", client: pulumirpc.NewSecretsProviderClient(conn)}
+	ctx := context.Background()
+
+	ciphertext, err := p.Encrypt(ctx, "xor://key", []byte("data key"))
+	assert.NoError(t, err)
+	assert.False(t, bytes.Equal([]byte("data key"), ciphertext))
+	plaintext, err := p.Decrypt(ctx, "xor://key", ciphertext)
+	assert.NoError(t, err)
+	assert.Equal(t, "data key", string(plaintext))
+
+	_, err = p.Encrypt(ctx, "xor://broken", []byte("data key"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "the key is broken")
+
+	info, err := p.GetPluginInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, workspace.SecretsPlugin, info.Kind)
+	assert.Equal(t, "xor", info.Name)
+	assert.Equal(t, "1.2.3", info.Version.String())
+	assert.NoError(t, p.Close())
+}
@@ -0,0 +1,65 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plugin
+
+import (
+	"context"
+
+	pbempty "github.com/golang/protobuf/ptypes/empty"
+
+	pulumirpc "github.com/pulumi/pulumi/sdk/v2/proto/go"
+)
+
+type secretsProviderServer struct {
+	provider SecretsProvider
+}
+
+// NewSecretsProviderServer returns a gRPC server for provider. Secrets provider plugins serve it with rpcutil.Serve
+// and print the port that it listens on to stdout, like any other plugin.
+func NewSecretsProviderServer(provider SecretsProvider) pulumirpc.SecretsProviderServer {
+	return &secretsProviderServer{provider: provider}
+}
+
+func (s *secretsProviderServer) GetPluginInfo(ctx context.Context, req *pbempty.Empty) (*pulumirpc.PluginInfo, error) {
+	info, err := s.provider.GetPluginInfo()
+	if err != nil {
+		return nil, err
+	}
+	var version string
+	if info.Version != nil {
+		version = info.Version.String()
+	}
+	return &pulumirpc.PluginInfo{Version: version}, nil
+}
+
+func (s *secretsProviderServer) Encrypt(ctx context.Context,
+	req *pulumirpc.SecretsEncryptRequest) (*pulumirpc.SecretsEncryptResponse, error) {
+
+	ciphertext, err := s.provider.Encrypt(ctx, req.GetUrl(), req.GetPlaintext())
+	if err != nil {
+		return nil, err
+	}
+	return &pulumirpc.SecretsEncryptResponse{Ciphertext: ciphertext}, nil
+}
+
+func (s *secretsProviderServer) Decrypt(ctx context.Context,
+	req *pulumirpc.SecretsDecryptRequest) (*pulumirpc.SecretsDecryptResponse, error) {
+
+	plaintext, err := s.provider.Decrypt(ctx, req.GetUrl(), req.GetCiphertext())
+	if err != nil {
+		return nil, err
+	}
+	return &pulumirpc.SecretsDecryptResponse{Plaintext: plaintext}, nil
+}
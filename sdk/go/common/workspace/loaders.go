@@ -17,8 +17,11 @@ package workspace
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"sync"
 
+	"github.com/pkg/errors"
+
 	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
 	"github.com/pulumi/pulumi/sdk/v2/go/common/util/contract"
 )
@@ -239,3 +242,41 @@ func LoadPolicyPack(path string) (*PolicyPackProject, error) {
 
 	return policyPackProjectSingleton.load(path)
 }
+
+// LoadInheritedConfig reads the configuration documents that the project at projPath inherits values from, returning
+// them as layers ordered from lowest to highest precedence. Inherited documents are shared by many stacks, so they
+// may not contain values encrypted with a stack's secrets provider.
+func LoadInheritedConfig(proj *Project, projPath string) ([]config.Layer, error) {
+	contract.Require(proj != nil, "proj")
+
+	if proj.ConfigInherits == nil {
+		return nil, nil
+	}
+
+	var layers []config.Layer
+	for _, doc := range []struct{ layer, path string }{
+		{config.OrganizationLayer, proj.ConfigInherits.Organization},
+		{config.ProjectLayer, proj.ConfigInherits.Project},
+	} {
+		if doc.path == "" {
+			continue
+		}
+
+		path := doc.path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(projPath), path)
+		}
+		ps, err := LoadProjectStack(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "loading %s configuration", doc.layer)
+		}
+		for k, v := range ps.Config {
+			if _, external := v.ExternalRef(); v.Secure() && !external {
+				return nil, errors.Errorf("%s: config key %v is a secret; secrets may only be set in a stack's "+
+					"configuration", path, k)
+			}
+		}
+		layers = append(layers, config.Layer{Name: doc.layer, Source: path, Config: ps.Config})
+	}
+	return layers, nil
+}
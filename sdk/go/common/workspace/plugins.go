@@ -439,12 +439,14 @@ const (
 	LanguagePlugin PluginKind = "language"
 	// ResourcePlugin is a plugin that can be used as a resource provider for custom CRUD operations.
 	ResourcePlugin PluginKind = "resource"
+	// SecretsPlugin is a plugin that can be used as a secrets provider for the URLs of a single scheme.
+	SecretsPlugin PluginKind = "secrets"
 )
 
 // IsPluginKind returns true if k is a valid plugin kind, and false otherwise.
 func IsPluginKind(k string) bool {
 	switch PluginKind(k) {
-	case AnalyzerPlugin, LanguagePlugin, ResourcePlugin:
+	case AnalyzerPlugin, LanguagePlugin, ResourcePlugin, SecretsPlugin:
 		return true
 	default:
 		return false
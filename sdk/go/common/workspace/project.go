@@ -85,6 +85,32 @@ type Project struct {
 	// Config indicates where to store the Pulumi.<stack-name>.yaml files, combined with the folder Pulumi.yaml is in.
 	Config string `json:"config,omitempty" yaml:"config,omitempty"`
 
+	// ConfigSchema optionally declares the configuration keys used by this project. Keys without a namespace are in
+	// the project's namespace.
+	ConfigSchema map[string]config.KeySchema `json:"configSchema,omitempty" yaml:"configSchema,omitempty"`
+
+	// ConfigBooleans selects which literals in the project's configuration are booleans: `yaml1.2` (the default) for
+	// just `true` and `false`, or `yaml1.1` to also accept `yes`, `on`, `no`, `off` and so on. Under `yaml1.1`,
+	// unquoted top-level values that are boolean literals are passed to programs as `true` or `false`.
+	ConfigBooleans config.BoolSyntax `json:"configBooleans,omitempty" yaml:"configBooleans,omitempty"`
+
+	// ConfigStrict rejects stack configuration that sets keys the project's ConfigSchema does not declare. It has no
+	// effect if the project does not declare a schema.
+	ConfigStrict bool `json:"configStrict,omitempty" yaml:"configStrict,omitempty"`
+
+	// ConfigInterpolation allows the project's configuration values to reference other keys, as
+	// `${<namespace>:<name>}`. It is off by default, so that values written before interpolation existed (e.g. scripts
+	// that contain `${HOME}`) are passed to programs as they are written.
+	ConfigInterpolation bool `json:"configInterpolation,omitempty" yaml:"configInterpolation,omitempty"`
+
+	// ConfigEnvInterpolation additionally allows the project's configuration values to reference environment
+	// variables, as `${env:<NAME>}`, and implies ConfigInterpolation. It is off by default, so that a value's meaning
+	// does not depend on where it is read.
+	ConfigEnvInterpolation bool `json:"configEnvInterpolation,omitempty" yaml:"configEnvInterpolation,omitempty"`
+
+	// ConfigInherits optionally names configuration documents whose values every stack of the project inherits.
+	ConfigInherits *ConfigInheritance `json:"configInherits,omitempty" yaml:"configInherits,omitempty"`
+
 	// Template is an optional template manifest, if this project is a template.
 	Template *ProjectTemplate `json:"template,omitempty" yaml:"template,omitempty"`
 
@@ -92,6 +118,16 @@ type Project struct {
 	Backend *ProjectBackend `json:"backend,omitempty" yaml:"backend,omitempty"`
 }
 
+// ConfigInheritance names the configuration documents a project's stacks inherit values from. Each document has the
+// same format as a stack's configuration file, and paths are relative to the folder Pulumi.yaml is in. A stack's own
+// configuration overrides the project document, which in turn overrides the organization document.
+type ConfigInheritance struct {
+	// Organization is the path of a document shared by every project in an organization.
+	Organization string `json:"organization,omitempty" yaml:"organization,omitempty"`
+	// Project is the path of a document shared by every stack of the project.
+	Project string `json:"project,omitempty" yaml:"project,omitempty"`
+}
+
 func (proj *Project) Validate() error {
 	if proj.Name == "" {
 		return errors.New("project is missing a 'name' attribute")
@@ -99,10 +135,18 @@ func (proj *Project) Validate() error {
 	if proj.Runtime.Name() == "" {
 		return errors.New("project is missing a 'runtime' attribute")
 	}
+	if _, err := proj.ParseConfigSchema(); err != nil {
+		return errors.Wrap(err, "project has an invalid 'configSchema' attribute")
+	}
 
 	return nil
 }
 
+// ParseConfigSchema returns the project's declared configuration schema.
+func (proj *Project) ParseConfigSchema() (config.Schema, error) {
+	return config.ParseSchema(string(proj.Name), proj.ConfigSchema)
+}
+
 // TrustResourceDependencies returns whether or not this project's runtime can be trusted to accurately report
 // dependencies. All languages supported by Pulumi today do this correctly. This option remains useful when bringing
 // up new Pulumi languages.
@@ -177,8 +221,36 @@ type ProjectStack struct {
 	// EncryptionSalt is this stack's base64 encoded encryption salt.  Only used for
 	// passphrase-based secrets providers.
 	EncryptionSalt string `json:"encryptionsalt,omitempty" yaml:"encryptionsalt,omitempty"`
+	// KeyProviders optionally overrides the secrets provider used for individual config keys, which are identified by
+	// their fully qualified names.
+	KeyProviders map[string]KeySecretsProvider `json:"keyproviders,omitempty" yaml:"keyproviders,omitempty"`
+	// FallbackProviders optionally lists cloud secrets providers that are tried in order when a secret cannot
+	// be decrypted by the stack's secrets provider. Secrets are always encrypted by the stack's own provider. A
+	// fallback without an encrypted key is given a copy of the stack's data key, encrypted by the fallback's KMS, by
+	// the next command that changes the stack's configuration (`pulumi config set` or `pulumi stack rotate-secrets`).
+	FallbackProviders []KeySecretsProvider `json:"fallbackproviders,omitempty" yaml:"fallbackproviders,omitempty"`
+	// SecretsRotation records when the stack's data keys were last rotated.
+	SecretsRotation *SecretsRotation `json:"secretsrotation,omitempty" yaml:"secretsrotation,omitempty"`
 	// Config is an optional config bag.
 	Config config.Map `json:"config,omitempty" yaml:"config,omitempty"`
+	// Metadata optionally attaches labels, such as an owner or a rotation date, to config keys.
+	Metadata config.Metadata `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+}
+
+// KeySecretsProvider describes the secrets provider used to encrypt the value of a single config key.
+type KeySecretsProvider struct {
+	// SecretsProvider is the URL of the key's secrets provider.
+	SecretsProvider string `json:"secretsprovider" yaml:"secretsprovider"`
+	// EncryptedKey is the KMS-encrypted ciphertext for the data key used to encrypt the key's value.
+	EncryptedKey string `json:"encryptedkey,omitempty" yaml:"encryptedkey,omitempty"`
+}
+
+// SecretsRotation describes the rotations of a stack's data keys.
+type SecretsRotation struct {
+	// Time is when the data keys were last rotated, in RFC 3339 format.
+	Time string `json:"time" yaml:"time"`
+	// Count is the number of times the data keys have been rotated.
+	Count int `json:"count" yaml:"count"`
 }
 
 // Save writes a project definition to a file.
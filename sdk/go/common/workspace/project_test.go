@@ -2,10 +2,15 @@ package workspace
 
 import (
 	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"gopkg.in/yaml.v2"
+
+	"github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
 )
 
 func TestProjectRuntimeInfoRoundtripYAML(t *testing.T) {
@@ -36,3 +41,110 @@ func TestProjectRuntimeInfoRoundtripYAML(t *testing.T) {
 	doTest(yaml.Marshal, yaml.Unmarshal)
 	doTest(json.Marshal, json.Unmarshal)
 }
+
+func TestProjectConfigSchema(t *testing.T) {
+	var proj Project
+	err := yaml.Unmarshal([]byte(`
+name: my-project
+runtime: go
+configSchema:
+  replicas:
+    type: int
+    default: "1"
+  aws:region:
+    allowedValues: [us-west-2]
+`), &proj)
+	assert.NoError(t, err)
+	assert.NoError(t, proj.Validate())
+
+	schema, err := proj.ParseConfigSchema()
+	assert.NoError(t, err)
+	assert.Equal(t, config.Schema{
+		config.MustMakeKey("my-project", "replicas"): {Type: config.TypeInt, Default: "1"},
+		config.MustMakeKey("aws", "region"):          {AllowedValues: []string{"us-west-2"}},
+	}, schema)
+
+	proj.ConfigSchema["count"] = config.KeySchema{Type: config.TypeInt, Default: "lots"}
+	assert.Error(t, proj.Validate())
+}
+
+func TestProjectConfigBooleans(t *testing.T) {
+	var proj Project
+	assert.NoError(t, yaml.Unmarshal([]byte("name: my-project\nruntime: go\n"), &proj))
+	assert.Equal(t, config.BoolSyntaxYAML12, proj.ConfigBooleans)
+
+	assert.NoError(t, yaml.Unmarshal([]byte("name: my-project\nruntime: go\nconfigBooleans: yaml1.1\n"), &proj))
+	assert.Equal(t, config.BoolSyntaxYAML11, proj.ConfigBooleans)
+	b, err := yaml.Marshal(proj)
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "configBooleans: yaml1.1")
+
+	assert.Error(t, yaml.Unmarshal([]byte("name: my-project\nruntime: go\nconfigBooleans: yaml2\n"), &proj))
+}
+
+func TestLoadInheritedConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "inherited-config")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	write := func(name, content string) {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+	write("org.yaml", "config:\n  aws:region: us-east-1\n")
+	write("shared.yaml", "config:\n  aws:region: us-west-2\n  my-project:token:\n    $ref: vault://token\n")
+	write("secret.yaml", "config:\n  my-project:password:\n    secure: ciphertext\n")
+
+	projPath := filepath.Join(dir, "Pulumi.yaml")
+	proj := &Project{Name: "my-project"}
+	layers, err := LoadInheritedConfig(proj, projPath)
+	assert.NoError(t, err)
+	assert.Empty(t, layers)
+
+	proj.ConfigInherits = &ConfigInheritance{
+		Organization: filepath.Join(dir, "org.yaml"),
+		Project:      "shared.yaml",
+	}
+	layers, err = LoadInheritedConfig(proj, projPath)
+	assert.NoError(t, err)
+	assert.Equal(t, []config.Layer{
+		{
+			Name:   config.OrganizationLayer,
+			Source: filepath.Join(dir, "org.yaml"),
+			Config: config.Map{config.MustMakeKey("aws", "region"): config.NewValue("us-east-1")},
+		},
+		{
+			Name:   config.ProjectLayer,
+			Source: filepath.Join(dir, "shared.yaml"),
+			Config: config.Map{
+				config.MustMakeKey("aws", "region"):       config.NewValue("us-west-2"),
+				config.MustMakeKey("my-project", "token"): config.NewExternalRefValue("vault://token"),
+			},
+		},
+	}, layers)
+
+	proj.ConfigInherits.Project = "secret.yaml"
+	_, err = LoadInheritedConfig(proj, projPath)
+	assert.Error(t, err)
+}
+
+func TestProjectConfigStrict(t *testing.T) {
+	var proj Project
+	assert.NoError(t, yaml.Unmarshal([]byte("name: my-project\nruntime: go\n"), &proj))
+	assert.False(t, proj.ConfigStrict)
+
+	assert.NoError(t, yaml.Unmarshal([]byte("name: my-project\nruntime: go\nconfigStrict: true\n"), &proj))
+	assert.True(t, proj.ConfigStrict)
+}
+
+func TestProjectConfigEnvInterpolation(t *testing.T) {
+	var proj Project
+	assert.NoError(t, yaml.Unmarshal([]byte("name: my-project\nruntime: go\n"), &proj))
+	assert.False(t, proj.ConfigInterpolation)
+	assert.False(t, proj.ConfigEnvInterpolation)
+
+	assert.NoError(t, yaml.Unmarshal([]byte("name: my-project\nruntime: go\nconfigInterpolation: true\n"), &proj))
+	assert.True(t, proj.ConfigInterpolation)
+
+	assert.NoError(t, yaml.Unmarshal([]byte("name: my-project\nruntime: go\nconfigEnvInterpolation: true\n"), &proj))
+	assert.True(t, proj.ConfigEnvInterpolation)
+}
@@ -15,6 +15,9 @@
 package config
 
 import (
+	"net/url"
+	"time"
+
 	"github.com/pulumi/pulumi/sdk/v2/go/pulumi"
 )
 
@@ -40,6 +43,15 @@ func (c *Config) fullKey(key string) string {
 	return c.namespace + ":" + key
 }
 
+// fullKeys returns the fully resolved form of each of keys.
+func (c *Config) fullKeys(keys []string) []string {
+	full := make([]string, len(keys))
+	for i, key := range keys {
+		full[i] = c.fullKey(key)
+	}
+	return full
+}
+
 // Get loads an optional configuration value by its key, or returns "" if it doesn't exist.
 func (c *Config) Get(key string) string {
 	return Get(c.ctx, c.fullKey(key))
@@ -66,6 +78,11 @@ func (c *Config) GetInt(key string) int {
 	return GetInt(c.ctx, c.fullKey(key))
 }
 
+// RequireAll panics if any of the given keys is not set, listing every missing key.
+func (c *Config) RequireAll(keys ...string) {
+	RequireAll(c.ctx, c.fullKeys(keys)...)
+}
+
 // Require loads a configuration value by its key, or panics if it doesn't exist.
 func (c *Config) Require(key string) string {
 	return Require(c.ctx, c.fullKey(key))
@@ -92,6 +109,33 @@ func (c *Config) RequireInt(key string) int {
 	return RequireInt(c.ctx, c.fullKey(key))
 }
 
+// RequireBytes loads a binary configuration value by its key, or panics if it doesn't exist or is not valid base64.
+func (c *Config) RequireBytes(key string) []byte {
+	return RequireBytes(c.ctx, c.fullKey(key))
+}
+
+// RequireDuration loads a time.Duration configuration value by its key, or panics if it doesn't exist or is not a
+// valid duration.
+func (c *Config) RequireDuration(key string) time.Duration {
+	return RequireDuration(c.ctx, c.fullKey(key))
+}
+
+// RequireByteSize loads a byte size configuration value by its key, or panics if it doesn't exist or is not a valid
+// size.
+func (c *Config) RequireByteSize(key string) uint64 {
+	return RequireByteSize(c.ctx, c.fullKey(key))
+}
+
+// RequireURL loads a URL configuration value by its key, or panics if it doesn't exist or is not a valid URL.
+func (c *Config) RequireURL(key string) *url.URL {
+	return RequireURL(c.ctx, c.fullKey(key))
+}
+
+// TryAll returns a non-nil error that lists every one of the given keys that is not set.
+func (c *Config) TryAll(keys ...string) error {
+	return TryAll(c.ctx, c.fullKeys(keys)...)
+}
+
 // Try loads a configuration value by its key, returning a non-nil error if it doesn't exist.
 func (c *Config) Try(key string) (string, error) {
 	return Try(c.ctx, c.fullKey(key))
@@ -118,6 +162,35 @@ func (c *Config) TryInt(key string) (int, error) {
 	return TryInt(c.ctx, c.fullKey(key))
 }
 
+// TryBytes loads an optional binary configuration value by its key, or returns an error if it doesn't exist or is not
+// valid base64.
+func (c *Config) TryBytes(key string) ([]byte, error) {
+	return TryBytes(c.ctx, c.fullKey(key))
+}
+
+// TryDuration loads an optional time.Duration configuration value by its key, or returns an error if it doesn't exist
+// or is not a valid duration.
+func (c *Config) TryDuration(key string) (time.Duration, error) {
+	return TryDuration(c.ctx, c.fullKey(key))
+}
+
+// TryByteSize loads an optional byte size configuration value by its key, or returns an error if it doesn't exist or
+// is not a valid size.
+func (c *Config) TryByteSize(key string) (uint64, error) {
+	return TryByteSize(c.ctx, c.fullKey(key))
+}
+
+// TryURL loads an optional URL configuration value by its key, or returns an error if it doesn't exist or is not a
+// valid URL.
+func (c *Config) TryURL(key string) (*url.URL, error) {
+	return TryURL(c.ctx, c.fullKey(key))
+}
+
+// GetBytes loads an optional binary configuration value by its key, or returns nil if it doesn't exist.
+func (c *Config) GetBytes(key string) []byte {
+	return GetBytes(c.ctx, c.fullKey(key))
+}
+
 // GetSecret loads an optional configuration value by its key
 // or "" if it doesn't exist, and returns it wrapped in a secret Output.
 func (c *Config) GetSecret(key string) pulumi.StringOutput {
@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
@@ -312,3 +313,97 @@ func TestSecretConfig(t *testing.T) {
 		}
 	}
 }
+
+func TestUnitConfig(t *testing.T) {
+	ctx, err := pulumi.NewContext(context.Background(), pulumi.RunInfo{
+		Config: map[string]string{
+			"testpkg:timeout":  "1m30s",
+			"testpkg:memory":   "512Mi",
+			"testpkg:endpoint": "https://example.com/api",
+			"testpkg:bad":      "not a unit",
+		},
+	})
+	assert.Nil(t, err)
+
+	cfg := New(ctx, "testpkg")
+
+	d, err := cfg.TryDuration("timeout")
+	assert.Nil(t, err)
+	assert.Equal(t, 90*time.Second, d)
+	size, err := cfg.TryByteSize("memory")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(512<<20), size)
+	u, err := cfg.TryURL("endpoint")
+	assert.Nil(t, err)
+	assert.Equal(t, "example.com", u.Host)
+
+	_, err = cfg.TryDuration("bad")
+	assert.NotNil(t, err)
+	_, err = cfg.TryByteSize("bad")
+	assert.NotNil(t, err)
+	_, err = cfg.TryURL("bad")
+	assert.NotNil(t, err)
+	_, err = cfg.TryDuration("missing")
+	assert.NotNil(t, err)
+
+	assert.Equal(t, 90*time.Second, cfg.RequireDuration("timeout"))
+	assert.Equal(t, uint64(512<<20), cfg.RequireByteSize("memory"))
+	assert.Equal(t, "/api", cfg.RequireURL("endpoint").Path)
+	assert.Panics(t, func() { cfg.RequireDuration("bad") })
+	assert.Panics(t, func() { cfg.RequireByteSize("missing") })
+	assert.Panics(t, func() { cfg.RequireURL("bad") })
+}
+
+func TestBytesConfig(t *testing.T) {
+	ctx, err := pulumi.NewContext(context.Background(), pulumi.RunInfo{
+		Config: map[string]string{
+			"testpkg:cert": "MIIBCgD/",
+			"testpkg:bad":  "not base64!",
+		},
+	})
+	assert.Nil(t, err)
+
+	cfg := New(ctx, "testpkg")
+	der := []byte{0x30, 0x82, 0x01, 0x0a, 0x00, 0xff}
+
+	assert.Equal(t, der, cfg.GetBytes("cert"))
+	assert.Nil(t, cfg.GetBytes("bad"))
+	assert.Nil(t, cfg.GetBytes("missing"))
+
+	b, err := cfg.TryBytes("cert")
+	assert.Nil(t, err)
+	assert.Equal(t, der, b)
+	_, err = cfg.TryBytes("bad")
+	assert.NotNil(t, err)
+
+	assert.Equal(t, der, cfg.RequireBytes("cert"))
+	assert.Panics(t, func() { cfg.RequireBytes("missing") })
+}
+
+func TestTryAll(t *testing.T) {
+	ctx, err := pulumi.NewContext(context.Background(), pulumi.RunInfo{
+		Config: map[string]string{
+			"testpkg:a": "1",
+			"testpkg:b": "2",
+		},
+	})
+	assert.Nil(t, err)
+
+	cfg := New(ctx, "testpkg")
+	assert.Nil(t, cfg.TryAll("a", "b"))
+	assert.Nil(t, TryAll(ctx, "testpkg:a"))
+
+	err = cfg.TryAll("a", "c")
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "missing required configuration variable 'testpkg:c'; run `pulumi config` to set",
+			err.Error())
+	}
+	err = cfg.TryAll("c", "b", "d")
+	if assert.NotNil(t, err) {
+		assert.Equal(t, "missing required configuration variables 'testpkg:c', 'testpkg:d'; "+
+			"run `pulumi config` to set", err.Error())
+	}
+
+	assert.NotPanics(t, func() { cfg.RequireAll("a", "b") })
+	assert.Panics(t, func() { cfg.RequireAll("c", "d") })
+}
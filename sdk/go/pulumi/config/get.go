@@ -15,6 +15,7 @@
 package config
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"strings"
 
@@ -74,6 +75,18 @@ func GetInt(ctx *pulumi.Context, key string) int {
 	return 0
 }
 
+// GetBytes loads an optional configuration value by its key, as the bytes of a base64-encoded binary value, or returns
+// nil if it doesn't exist or is not valid base64.
+func GetBytes(ctx *pulumi.Context, key string) []byte {
+	key = ensureKey(ctx, key)
+	if v, ok := ctx.GetConfig(key); ok {
+		if b, err := base64.StdEncoding.DecodeString(v); err == nil {
+			return b
+		}
+	}
+	return nil
+}
+
 // GetSecret loads an optional configuration value by its key, or "" if it does not exist, into a secret Output.
 func GetSecret(ctx *pulumi.Context, key string) pulumi.StringOutput {
 	key = ensureKey(ctx, key)
@@ -16,6 +16,8 @@ package config
 
 import (
 	"encoding/json"
+	"net/url"
+	"time"
 
 	"github.com/spf13/cast"
 
@@ -23,6 +25,13 @@ import (
 	"github.com/pulumi/pulumi/sdk/v2/go/pulumi"
 )
 
+// RequireAll panics if any of the given keys is not set, listing every missing key.
+func RequireAll(ctx *pulumi.Context, keys ...string) {
+	if err := TryAll(ctx, keys...); err != nil {
+		contract.Failf("%s", err.Error())
+	}
+}
+
 // Require loads a configuration value by its key, or panics if it doesn't exist.
 func Require(ctx *pulumi.Context, key string) string {
 	key = ensureKey(ctx, key)
@@ -64,6 +73,46 @@ func RequireInt(ctx *pulumi.Context, key string) int {
 	return cast.ToInt(v)
 }
 
+// RequireDuration loads a configuration value by its key, as a time.Duration (e.g. "30s"), or panics if it doesn't
+// exist or is not a valid duration.
+func RequireDuration(ctx *pulumi.Context, key string) time.Duration {
+	d, err := TryDuration(ctx, key)
+	if err != nil {
+		contract.Failf("%s", err.Error())
+	}
+	return d
+}
+
+// RequireByteSize loads a configuration value by its key, as a number of bytes (e.g. "512Mi"), or panics if it
+// doesn't exist or is not a valid size.
+func RequireByteSize(ctx *pulumi.Context, key string) uint64 {
+	size, err := TryByteSize(ctx, key)
+	if err != nil {
+		contract.Failf("%s", err.Error())
+	}
+	return size
+}
+
+// RequireURL loads a configuration value by its key, as an absolute URL, or panics if it doesn't exist or is not a
+// valid URL.
+func RequireURL(ctx *pulumi.Context, key string) *url.URL {
+	u, err := TryURL(ctx, key)
+	if err != nil {
+		contract.Failf("%s", err.Error())
+	}
+	return u
+}
+
+// RequireBytes loads a configuration value by its key, as the bytes of a base64-encoded binary value, or panics if it
+// doesn't exist or is not valid base64.
+func RequireBytes(ctx *pulumi.Context, key string) []byte {
+	b, err := TryBytes(ctx, key)
+	if err != nil {
+		contract.Failf("%s", err.Error())
+	}
+	return b
+}
+
 // RequireSecret loads a configuration value by its key returning it wrapped in a secret Output,
 // or panics if it doesn't exist.
 func RequireSecret(ctx *pulumi.Context, key string) pulumi.StringOutput {
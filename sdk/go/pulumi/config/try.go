@@ -15,14 +15,40 @@
 package config
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"net/url"
+	"strings"
+	"time"
 
 	"github.com/spf13/cast"
 
+	resconfig "github.com/pulumi/pulumi/sdk/v2/go/common/resource/config"
 	"github.com/pulumi/pulumi/sdk/v2/go/pulumi"
 )
 
+// TryAll returns a non-nil error that lists every one of the given keys that is not set. Checking all of a program's
+// required keys up front reports every missing value at once, rather than one at a time as each Try fails.
+func TryAll(ctx *pulumi.Context, keys ...string) error {
+	var missing []string
+	for _, key := range keys {
+		key = ensureKey(ctx, key)
+		if _, ok := ctx.GetConfig(key); !ok {
+			missing = append(missing, fmt.Sprintf("'%s'", key))
+		}
+	}
+	switch len(missing) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("missing required configuration variable %s; run `pulumi config` to set", missing[0])
+	default:
+		return fmt.Errorf("missing required configuration variables %s; run `pulumi config` to set",
+			strings.Join(missing, ", "))
+	}
+}
+
 // Try loads a configuration value by its key, returning a non-nil error if it doesn't exist.
 func Try(ctx *pulumi.Context, key string) (string, error) {
 	key = ensureKey(ctx, key)
@@ -75,6 +101,66 @@ func TryInt(ctx *pulumi.Context, key string) (int, error) {
 	return cast.ToInt(v), nil
 }
 
+// TryDuration loads an optional configuration value by its key, as a time.Duration (e.g. "30s"), or returns an error
+// if it doesn't exist or is not a valid duration.
+func TryDuration(ctx *pulumi.Context, key string) (time.Duration, error) {
+	key = ensureKey(ctx, key)
+	v, err := Try(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("configuration variable '%s' is not a valid duration: %q", key, v)
+	}
+	return d, nil
+}
+
+// TryByteSize loads an optional configuration value by its key, as a number of bytes (e.g. "512Mi"), or returns an
+// error if it doesn't exist or is not a valid size.
+func TryByteSize(ctx *pulumi.Context, key string) (uint64, error) {
+	key = ensureKey(ctx, key)
+	v, err := Try(ctx, key)
+	if err != nil {
+		return 0, err
+	}
+	size, err := resconfig.ParseByteSize(v)
+	if err != nil {
+		return 0, fmt.Errorf("configuration variable '%s' is not a valid byte size: %v", key, err)
+	}
+	return size, nil
+}
+
+// TryURL loads an optional configuration value by its key, as an absolute URL, or returns an error if it doesn't
+// exist or is not a valid URL.
+func TryURL(ctx *pulumi.Context, key string) (*url.URL, error) {
+	key = ensureKey(ctx, key)
+	v, err := Try(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	u, err := resconfig.ParseURL(v)
+	if err != nil {
+		return nil, fmt.Errorf("configuration variable '%s' is not a valid URL: %v", key, err)
+	}
+	return u, nil
+}
+
+// TryBytes loads an optional configuration value by its key, as the bytes of a base64-encoded binary value, or
+// returns an error if it doesn't exist or is not valid base64.
+func TryBytes(ctx *pulumi.Context, key string) ([]byte, error) {
+	key = ensureKey(ctx, key)
+	v, err := Try(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	b, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return nil, fmt.Errorf("configuration variable '%s' is not valid base64", key)
+	}
+	return b, nil
+}
+
 // TrySecret loads a configuration value by its key, returning a non-nil error if it doesn't exist.
 func TrySecret(ctx *pulumi.Context, key string) (pulumi.StringOutput, error) {
 	key = ensureKey(ctx, key)
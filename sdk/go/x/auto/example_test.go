@@ -1197,3 +1197,20 @@ func ExampleLocalWorkspace_ImportStack() {
 	// import our edited deployment state back to our stack
 	_ = w.ImportStack(ctx, stackName, dep)
 }
+
+func ExampleLocalWorkspace_RotateSecrets() {
+	ctx := context.Background()
+	// create a workspace from a local project
+	w, _ := NewLocalWorkspace(ctx, WorkDir(filepath.Join(".", "program")))
+	stackName := FullyQualifiedStackName("org", "proj", "existing_stack")
+	// re-encrypt the stack's config and state with new data keys
+	_ = w.RotateSecrets(ctx, stackName)
+}
+
+func ExampleStack_RotateSecrets() {
+	ctx := context.Background()
+	stackName := FullyQualifiedStackName("org", "project", "stack")
+	stack, _ := SelectStackLocalSource(ctx, stackName, filepath.Join(".", "program"))
+	// re-encrypt the stack's config and state with new data keys
+	_ = stack.RotateSecrets(ctx)
+}
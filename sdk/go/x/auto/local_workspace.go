@@ -250,6 +250,26 @@ func (l *LocalWorkspace) RefreshConfig(ctx context.Context, stackName string) (C
 	return cfg, nil
 }
 
+// DiffConfig returns the differences between the config map used with the last Update for Stack matching stack name
+// and the config map in the Pulumi.<stack>.yaml file in Workspace.WorkDir().
+func (l *LocalWorkspace) DiffConfig(ctx context.Context, stackName string) (ConfigDiff, error) {
+	var diff ConfigDiff
+	err := l.SelectStack(ctx, stackName)
+	if err != nil {
+		return diff, errors.Wrapf(err, "could not diff config, unable to select stack %s", stackName)
+	}
+
+	stdout, stderr, errCode, err := l.runPulumiCmdSync(ctx, "config", "diff", "--json")
+	if err != nil {
+		return diff, newAutoError(errors.Wrap(err, "could not diff config"), stdout, stderr, errCode)
+	}
+	err = json.Unmarshal([]byte(stdout), &diff)
+	if err != nil {
+		return diff, errors.Wrap(err, "unable to unmarshal config diff")
+	}
+	return diff, nil
+}
+
 // GetEnvVars returns the environment values scoped to the current workspace.
 func (l *LocalWorkspace) GetEnvVars() map[string]string {
 	if l.envvars == nil {
@@ -479,6 +499,17 @@ func (l *LocalWorkspace) ImportStack(ctx context.Context, stackName string, stat
 	return nil
 }
 
+// RotateSecrets generates new data keys for the secrets providers of the stack matching the given name, and
+// re-encrypts the secrets in its configuration and latest deployment state with them.
+// LocalWorkspace records the rotation in the matching Pulumi.<stack>.yaml file in Workspace.WorkDir().
+func (l *LocalWorkspace) RotateSecrets(ctx context.Context, stackName string) error {
+	stdout, stderr, errCode, err := l.runPulumiCmdSync(ctx, "stack", "rotate-secrets", "--stack", stackName)
+	if err != nil {
+		return newAutoError(errors.Wrap(err, "could not rotate secrets"), stdout, stderr, errCode)
+	}
+	return nil
+}
+
 func (l *LocalWorkspace) runPulumiCmdSync(
 	ctx context.Context,
 	args ...string,
@@ -645,6 +645,11 @@ func (s *Stack) RefreshConfig(ctx context.Context) (ConfigMap, error) {
 	return s.Workspace().RefreshConfig(ctx, s.Name())
 }
 
+// DiffConfig returns the differences between the config map used with the last Update and the current config map.
+func (s *Stack) DiffConfig(ctx context.Context) (ConfigDiff, error) {
+	return s.Workspace().DiffConfig(ctx, s.Name())
+}
+
 // Info returns a summary of the Stack including its URL.
 func (s *Stack) Info(ctx context.Context) (StackSummary, error) {
 	var info StackSummary
@@ -695,6 +700,12 @@ func (s *Stack) Import(ctx context.Context, state apitype.UntypedDeployment) err
 	return s.Workspace().ImportStack(ctx, s.Name(), state)
 }
 
+// RotateSecrets generates new data keys for the stack's secrets providers, and re-encrypts the secrets in its
+// configuration and deployment state with them.
+func (s *Stack) RotateSecrets(ctx context.Context) error {
+	return s.Workspace().RotateSecrets(ctx, s.Name())
+}
+
 // UpdateSummary provides a summary of a Stack lifecycle operation (up/preview/refresh/destroy).
 type UpdateSummary struct {
 	Version     int               `json:"version"`
@@ -57,6 +57,9 @@ type Workspace interface {
 	RemoveAllConfig(context.Context, string, []string) error
 	// RefreshConfig gets and sets the config map used with the last Update for Stack matching stack name.
 	RefreshConfig(context.Context, string) (ConfigMap, error)
+	// DiffConfig returns the differences between the config map used with the last Update for Stack matching
+	// stack name and the current config map.
+	DiffConfig(context.Context, string) (ConfigDiff, error)
 	// GetEnvVars returns the environment values scoped to the current workspace.
 	GetEnvVars() map[string]string
 	// SetEnvVars sets the specified map of environment values scoped to the current workspace.
@@ -103,6 +106,9 @@ type Workspace interface {
 	// ImportStack imports the specified deployment state into a pre-existing stack.
 	// This can be combined with ExportStack to edit a stack's state (such as recovery from failed deployments).
 	ImportStack(context.Context, string, apitype.UntypedDeployment) error
+	// RotateSecrets generates new data keys for the secrets providers of the stack matching the given name, and
+	// re-encrypts the secrets in its configuration and latest deployment state with them.
+	RotateSecrets(context.Context, string) error
 }
 
 // ConfigValue is a configuration value used by a Pulumi program.
@@ -116,6 +122,30 @@ type ConfigValue struct {
 // Allows differentiating between secret and plaintext values.
 type ConfigMap map[string]ConfigValue
 
+// Clone returns a copy of the config map, so that variants of a base configuration (e.g. one per environment) can be
+// derived from it without modifying the base or each other.
+func (c ConfigMap) Clone() ConfigMap {
+	if c == nil {
+		return nil
+	}
+	clone := make(ConfigMap, len(c))
+	for k, v := range c {
+		clone[k] = v
+	}
+	return clone
+}
+
+// ConfigDiff describes how a stack's config map differs from the config map used with its last Update.
+// Secret values are compared by their encrypted values.
+type ConfigDiff struct {
+	// Added holds the keys that were not set for the last Update.
+	Added []string `json:"added"`
+	// Removed holds the keys that were set for the last Update but are no longer set.
+	Removed []string `json:"removed"`
+	// Changed holds the keys whose values differ from those used with the last Update.
+	Changed []string `json:"changed"`
+}
+
 // StackSummary is a description of a stack and its current status.
 type StackSummary struct {
 	Name             string `json:"name"`
@@ -0,0 +1,39 @@
+// Copyright 2016-2021, Pulumi Corporation.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package auto
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigMapClone(t *testing.T) {
+	assert.Nil(t, ConfigMap(nil).Clone())
+
+	base := ConfigMap{
+		"region":   ConfigValue{Value: "us-west-2"},
+		"password": ConfigValue{Value: "hunter2", Secret: true},
+	}
+	prod := base.Clone()
+	prod["region"] = ConfigValue{Value: "us-east-1"}
+	prod["replicas"] = ConfigValue{Value: "3"}
+
+	assert.Equal(t, ConfigMap{
+		"region":   ConfigValue{Value: "us-west-2"},
+		"password": ConfigValue{Value: "hunter2", Secret: true},
+	}, base)
+	assert.Equal(t, ConfigValue{Value: "hunter2", Secret: true}, prod["password"])
+}
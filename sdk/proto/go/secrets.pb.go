@@ -0,0 +1,389 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: secrets.proto
+
+package pulumirpc
+
+import (
+	context "context"
+	fmt "fmt"
+	proto "github.com/golang/protobuf/proto"
+	empty "github.com/golang/protobuf/ptypes/empty"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+	math "math"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
+
+type SecretsEncryptRequest struct {
+	Url                  string   `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	Plaintext            []byte   `protobuf:"bytes,2,opt,name=plaintext,proto3" json:"plaintext,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SecretsEncryptRequest) Reset()         { *m = SecretsEncryptRequest{} }
+func (m *SecretsEncryptRequest) String() string { return proto.CompactTextString(m) }
+func (*SecretsEncryptRequest) ProtoMessage()    {}
+func (*SecretsEncryptRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d4bc6c625e214507, []int{0}
+}
+
+func (m *SecretsEncryptRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SecretsEncryptRequest.Unmarshal(m, b)
+}
+func (m *SecretsEncryptRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SecretsEncryptRequest.Marshal(b, m, deterministic)
+}
+func (m *SecretsEncryptRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SecretsEncryptRequest.Merge(m, src)
+}
+func (m *SecretsEncryptRequest) XXX_Size() int {
+	return xxx_messageInfo_SecretsEncryptRequest.Size(m)
+}
+func (m *SecretsEncryptRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SecretsEncryptRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SecretsEncryptRequest proto.InternalMessageInfo
+
+func (m *SecretsEncryptRequest) GetUrl() string {
+	if m != nil {
+		return m.Url
+	}
+	return ""
+}
+
+func (m *SecretsEncryptRequest) GetPlaintext() []byte {
+	if m != nil {
+		return m.Plaintext
+	}
+	return nil
+}
+
+type SecretsEncryptResponse struct {
+	Ciphertext           []byte   `protobuf:"bytes,1,opt,name=ciphertext,proto3" json:"ciphertext,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SecretsEncryptResponse) Reset()         { *m = SecretsEncryptResponse{} }
+func (m *SecretsEncryptResponse) String() string { return proto.CompactTextString(m) }
+func (*SecretsEncryptResponse) ProtoMessage()    {}
+func (*SecretsEncryptResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d4bc6c625e214507, []int{1}
+}
+
+func (m *SecretsEncryptResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SecretsEncryptResponse.Unmarshal(m, b)
+}
+func (m *SecretsEncryptResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SecretsEncryptResponse.Marshal(b, m, deterministic)
+}
+func (m *SecretsEncryptResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SecretsEncryptResponse.Merge(m, src)
+}
+func (m *SecretsEncryptResponse) XXX_Size() int {
+	return xxx_messageInfo_SecretsEncryptResponse.Size(m)
+}
+func (m *SecretsEncryptResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SecretsEncryptResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SecretsEncryptResponse proto.InternalMessageInfo
+
+func (m *SecretsEncryptResponse) GetCiphertext() []byte {
+	if m != nil {
+		return m.Ciphertext
+	}
+	return nil
+}
+
+type SecretsDecryptRequest struct {
+	Url                  string   `protobuf:"bytes,1,opt,name=url,proto3" json:"url,omitempty"`
+	Ciphertext           []byte   `protobuf:"bytes,2,opt,name=ciphertext,proto3" json:"ciphertext,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SecretsDecryptRequest) Reset()         { *m = SecretsDecryptRequest{} }
+func (m *SecretsDecryptRequest) String() string { return proto.CompactTextString(m) }
+func (*SecretsDecryptRequest) ProtoMessage()    {}
+func (*SecretsDecryptRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d4bc6c625e214507, []int{2}
+}
+
+func (m *SecretsDecryptRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SecretsDecryptRequest.Unmarshal(m, b)
+}
+func (m *SecretsDecryptRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SecretsDecryptRequest.Marshal(b, m, deterministic)
+}
+func (m *SecretsDecryptRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SecretsDecryptRequest.Merge(m, src)
+}
+func (m *SecretsDecryptRequest) XXX_Size() int {
+	return xxx_messageInfo_SecretsDecryptRequest.Size(m)
+}
+func (m *SecretsDecryptRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_SecretsDecryptRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SecretsDecryptRequest proto.InternalMessageInfo
+
+func (m *SecretsDecryptRequest) GetUrl() string {
+	if m != nil {
+		return m.Url
+	}
+	return ""
+}
+
+func (m *SecretsDecryptRequest) GetCiphertext() []byte {
+	if m != nil {
+		return m.Ciphertext
+	}
+	return nil
+}
+
+type SecretsDecryptResponse struct {
+	Plaintext            []byte   `protobuf:"bytes,1,opt,name=plaintext,proto3" json:"plaintext,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SecretsDecryptResponse) Reset()         { *m = SecretsDecryptResponse{} }
+func (m *SecretsDecryptResponse) String() string { return proto.CompactTextString(m) }
+func (*SecretsDecryptResponse) ProtoMessage()    {}
+func (*SecretsDecryptResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_d4bc6c625e214507, []int{3}
+}
+
+func (m *SecretsDecryptResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SecretsDecryptResponse.Unmarshal(m, b)
+}
+func (m *SecretsDecryptResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SecretsDecryptResponse.Marshal(b, m, deterministic)
+}
+func (m *SecretsDecryptResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SecretsDecryptResponse.Merge(m, src)
+}
+func (m *SecretsDecryptResponse) XXX_Size() int {
+	return xxx_messageInfo_SecretsDecryptResponse.Size(m)
+}
+func (m *SecretsDecryptResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_SecretsDecryptResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SecretsDecryptResponse proto.InternalMessageInfo
+
+func (m *SecretsDecryptResponse) GetPlaintext() []byte {
+	if m != nil {
+		return m.Plaintext
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*SecretsEncryptRequest)(nil), "pulumirpc.SecretsEncryptRequest")
+	proto.RegisterType((*SecretsEncryptResponse)(nil), "pulumirpc.SecretsEncryptResponse")
+	proto.RegisterType((*SecretsDecryptRequest)(nil), "pulumirpc.SecretsDecryptRequest")
+	proto.RegisterType((*SecretsDecryptResponse)(nil), "pulumirpc.SecretsDecryptResponse")
+}
+
+func init() {
+	proto.RegisterFile("secrets.proto", fileDescriptor_d4bc6c625e214507)
+}
+
+var fileDescriptor_d4bc6c625e214507 = []byte{
+	// 278 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x90, 0x5b, 0x4b, 0xc3, 0x30,
+	0x14, 0xc7, 0x97, 0x09, 0xca, 0x0e, 0x1b, 0x4a, 0x60, 0x65, 0x54, 0x91, 0xda, 0xa7, 0x3d, 0x65,
+	0xa0, 0x20, 0x3e, 0xfa, 0xd0, 0x31, 0xf6, 0x56, 0xea, 0x27, 0x70, 0xf5, 0xac, 0x16, 0xb2, 0x24,
+	0xe6, 0x22, 0xee, 0x93, 0xfb, 0x2a, 0x4b, 0xea, 0x2e, 0xf5, 0xb2, 0xb7, 0xe4, 0x5c, 0x7e, 0xfc,
+	0xcf, 0x0f, 0x06, 0x06, 0x4b, 0x8d, 0xd6, 0x30, 0xa5, 0xa5, 0x95, 0xb4, 0xa7, 0x1c, 0x77, 0xab,
+	0x5a, 0xab, 0x32, 0xee, 0x2b, 0xee, 0xaa, 0x5a, 0x84, 0x46, 0x7c, 0x59, 0x49, 0x59, 0x71, 0x9c,
+	0xf8, 0xdf, 0xc2, 0x2d, 0x27, 0xb8, 0x52, 0x76, 0x1d, 0x9a, 0xe9, 0x0c, 0x86, 0x4f, 0x01, 0x33,
+	0x15, 0xa5, 0x5e, 0x2b, 0x5b, 0xe0, 0x9b, 0x43, 0x63, 0xe9, 0x05, 0x9c, 0x38, 0xcd, 0x47, 0x24,
+	0x21, 0xe3, 0x5e, 0xb1, 0x79, 0xd2, 0x2b, 0xe8, 0x29, 0xfe, 0x5c, 0x0b, 0x8b, 0x1f, 0x76, 0xd4,
+	0x4d, 0xc8, 0xb8, 0x5f, 0xec, 0x0a, 0xe9, 0x03, 0x44, 0x6d, 0x90, 0x51, 0x52, 0x18, 0xa4, 0xd7,
+	0x00, 0x65, 0xad, 0x5e, 0x51, 0xfb, 0x45, 0xe2, 0x17, 0xf7, 0x2a, 0xe9, 0x7c, 0x1b, 0x21, 0xc3,
+	0x23, 0x11, 0x0e, 0x51, 0xdd, 0x1f, 0xa8, 0x7b, 0x88, 0xda, 0xa8, 0x26, 0xc4, 0x41, 0x78, 0xd2,
+	0x0a, 0x7f, 0xfb, 0x49, 0xe0, 0xbc, 0x59, 0xcc, 0xb5, 0x7c, 0xaf, 0x5f, 0x50, 0xd3, 0x47, 0x18,
+	0xcc, 0xd0, 0xe6, 0xde, 0xe4, 0x5c, 0x2c, 0x25, 0x8d, 0x58, 0x10, 0xc9, 0xbe, 0x45, 0xb2, 0xe9,
+	0x46, 0x64, 0x3c, 0x64, 0x5b, 0xf3, 0x6c, 0x37, 0x9e, 0x76, 0x68, 0x0e, 0x67, 0x8d, 0x0b, 0x9a,
+	0xec, 0xcd, 0xfc, 0xea, 0x3b, 0xbe, 0xf9, 0x67, 0x22, 0xdc, 0x10, 0x88, 0x19, 0xfe, 0x49, 0xcc,
+	0xf0, 0x18, 0xb1, 0x65, 0x25, 0xed, 0x2c, 0x4e, 0xfd, 0x31, 0x77, 0x5f, 0x03, 0x00, 0x96, 0x4d,
+	0x70, 0xe6, 0x4d, 0x02, 0x00, 0x00,
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConnInterface
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion6
+
+// SecretsProviderClient is the client API for SecretsProvider service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type SecretsProviderClient interface {
+	// GetPluginInfo returns generic information about this plugin, like its version.
+	GetPluginInfo(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*PluginInfo, error)
+	// Encrypt encrypts a plaintext with the key named by a secrets provider URL.
+	Encrypt(ctx context.Context, in *SecretsEncryptRequest, opts ...grpc.CallOption) (*SecretsEncryptResponse, error)
+	// Decrypt decrypts a ciphertext that Encrypt returned for the same secrets provider URL.
+	Decrypt(ctx context.Context, in *SecretsDecryptRequest, opts ...grpc.CallOption) (*SecretsDecryptResponse, error)
+}
+
+type secretsProviderClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSecretsProviderClient(cc grpc.ClientConnInterface) SecretsProviderClient {
+	return &secretsProviderClient{cc}
+}
+
+func (c *secretsProviderClient) GetPluginInfo(ctx context.Context, in *empty.Empty, opts ...grpc.CallOption) (*PluginInfo, error) {
+	out := new(PluginInfo)
+	err := c.cc.Invoke(ctx, "/pulumirpc.SecretsProvider/GetPluginInfo", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *secretsProviderClient) Encrypt(ctx context.Context, in *SecretsEncryptRequest, opts ...grpc.CallOption) (*SecretsEncryptResponse, error) {
+	out := new(SecretsEncryptResponse)
+	err := c.cc.Invoke(ctx, "/pulumirpc.SecretsProvider/Encrypt", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *secretsProviderClient) Decrypt(ctx context.Context, in *SecretsDecryptRequest, opts ...grpc.CallOption) (*SecretsDecryptResponse, error) {
+	out := new(SecretsDecryptResponse)
+	err := c.cc.Invoke(ctx, "/pulumirpc.SecretsProvider/Decrypt", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SecretsProviderServer is the server API for SecretsProvider service.
+type SecretsProviderServer interface {
+	// GetPluginInfo returns generic information about this plugin, like its version.
+	GetPluginInfo(context.Context, *empty.Empty) (*PluginInfo, error)
+	// Encrypt encrypts a plaintext with the key named by a secrets provider URL.
+	Encrypt(context.Context, *SecretsEncryptRequest) (*SecretsEncryptResponse, error)
+	// Decrypt decrypts a ciphertext that Encrypt returned for the same secrets provider URL.
+	Decrypt(context.Context, *SecretsDecryptRequest) (*SecretsDecryptResponse, error)
+}
+
+// UnimplementedSecretsProviderServer can be embedded to have forward compatible implementations.
+type UnimplementedSecretsProviderServer struct {
+}
+
+func (*UnimplementedSecretsProviderServer) GetPluginInfo(ctx context.Context, req *empty.Empty) (*PluginInfo, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPluginInfo not implemented")
+}
+func (*UnimplementedSecretsProviderServer) Encrypt(ctx context.Context, req *SecretsEncryptRequest) (*SecretsEncryptResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Encrypt not implemented")
+}
+func (*UnimplementedSecretsProviderServer) Decrypt(ctx context.Context, req *SecretsDecryptRequest) (*SecretsDecryptResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Decrypt not implemented")
+}
+
+func RegisterSecretsProviderServer(s *grpc.Server, srv SecretsProviderServer) {
+	s.RegisterService(&_SecretsProvider_serviceDesc, srv)
+}
+
+func _SecretsProvider_GetPluginInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(empty.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecretsProviderServer).GetPluginInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pulumirpc.SecretsProvider/GetPluginInfo",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecretsProviderServer).GetPluginInfo(ctx, req.(*empty.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SecretsProvider_Encrypt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SecretsEncryptRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecretsProviderServer).Encrypt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pulumirpc.SecretsProvider/Encrypt",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecretsProviderServer).Encrypt(ctx, req.(*SecretsEncryptRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SecretsProvider_Decrypt_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SecretsDecryptRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SecretsProviderServer).Decrypt(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pulumirpc.SecretsProvider/Decrypt",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SecretsProviderServer).Decrypt(ctx, req.(*SecretsDecryptRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _SecretsProvider_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "pulumirpc.SecretsProvider",
+	HandlerType: (*SecretsProviderServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetPluginInfo",
+			Handler:    _SecretsProvider_GetPluginInfo_Handler,
+		},
+		{
+			MethodName: "Encrypt",
+			Handler:    _SecretsProvider_Encrypt_Handler,
+		},
+		{
+			MethodName: "Decrypt",
+			Handler:    _SecretsProvider_Decrypt_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "secrets.proto",
+}